@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"os"
 	"os/user"
 	"path"
 
+	"github.com/matt-steen/todo-tracker/pkg/cli"
 	"github.com/matt-steen/todo-tracker/pkg/controller"
 	"github.com/matt-steen/todo-tracker/pkg/db"
 	"github.com/rs/zerolog"
@@ -50,6 +52,25 @@ func main() {
 		panic(err)
 	}
 
+	if limit, ok := os.LookupEnv("TT_MAX_CLOSED_TODOS"); ok {
+		if err = db.OverrideMaxClosedTodos(limit); err != nil {
+			panic(err)
+		}
+	}
+
+	if _, ok := os.LookupEnv("TT_CHECK_INTEGRITY"); ok {
+		checkIntegrity(ctx, db)
+	}
+
+	// any arguments run a single command instead of the interactive UI
+	if len(os.Args) > 1 {
+		code := cli.Run(ctx, db, os.Args[1:], os.Stdout, os.Stderr)
+
+		db.Close()
+		logFile.Close()
+		os.Exit(code)
+	}
+
 	controller, err := controller.NewController(ctx, db)
 	if err != nil {
 		panic(err)
@@ -57,3 +78,21 @@ func main() {
 
 	controller.Go()
 }
+
+// checkIntegrity logs any problems that CheckIntegrity finds and points to tt doctor, without stopping the app.
+func checkIntegrity(ctx context.Context, database *db.Database) {
+	problems, err := database.CheckIntegrity(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error checking integrity")
+
+		return
+	}
+
+	for _, problem := range problems {
+		log.Warn().Msg(problem)
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "tt: found %d integrity problem(s); run tt doctor for details\n", len(problems))
+	}
+}
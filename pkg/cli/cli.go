@@ -0,0 +1,835 @@
+// Package cli implements non-interactive subcommands so that todos can be managed from scripts as well as the TUI.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+	"github.com/matt-steen/todo-tracker/pkg/importer"
+	"github.com/matt-steen/todo-tracker/pkg/server"
+)
+
+// These constants are the exit codes returned from Run.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+const usage = `usage: tt [command] [arguments]
+
+With no command, tt opens the interactive UI.
+
+commands:
+  add <title> [-d description] [-l label]...   add a todo to the open list
+  ls [status]                                  list the todos with a status (default closed)
+  mv <id> <status>                             move a todo to another status
+  done <id>                                    move a todo to done
+  rm <id>                                      delete a todo
+  snooze <id> <when>                           put a todo on hold until e.g. 2d, tomorrow or next monday
+  block <id> <blocker-id>                      keep a todo from being done until the blocker is done
+  unblock <id> <blocker-id>                    remove a blocker from a todo
+  label add <id> <label>                       add a label to a todo
+  label rm <id> <label>                        remove a label from a todo
+  rank <id> top|bottom|up|down                 move a todo within its status
+  config [setting [value]]                     show or change settings
+  export [--format json|csv|md] [--status s]   write todos to stdout, optionally only those with the given statuses
+  import [--format f] [--map m] [--dry-run] <file>
+                                               add todos from a json export, csv or todo.txt file
+  serve [--addr host:port] [--token t]         serve a JSON API over HTTP until interrupted (default 127.0.0.1:7070)
+  status ls                                    list statuses with their keys, limits and transitions
+  status add <name> <key> [-c color] [-w n]    add a status with a key in the UI and an optional limit
+  status allow|deny <from> <to>                allow or forbid moving todos between statuses
+  status limit <name> <n>                      limit the number of todos in a status (0 for no limit)
+  doctor [--fix]                               check the database for problems; --fix renumbers broken ranks
+  help                                         show this message
+
+statuses: closed, open, on_hold, done, abandoned, and any added with status add
+settings: max_closed_todos
+`
+
+// snoozeTimeFormat is used to show when snoozed todos wake up.
+const snoozeTimeFormat = "Mon 2006-01-02 15:04"
+
+var (
+	// errUsage is wrapped by errors caused by invalid arguments, which are reported along with the usage message.
+	errUsage = errors.New("invalid arguments")
+	// errIntegrity is returned from doctor when the database has problems.
+	errIntegrity = errors.New("the database has integrity problems")
+)
+
+// runner holds the state shared by the subcommands.
+type runner struct {
+	ctx    context.Context
+	db     *db.Database
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes the subcommand given by args, which excludes the program name, writing output to stdout and errors
+// to stderr. It returns the exit code for the process.
+func Run(ctx context.Context, database *db.Database, args []string, stdout, stderr io.Writer) int {
+	r := &runner{ctx: ctx, db: database, stdout: stdout, stderr: stderr}
+
+	commands := map[string]func([]string) error{
+		"add":     r.add,
+		"ls":      r.list,
+		"mv":      r.move,
+		"done":    r.done,
+		"rm":      r.remove,
+		"snooze":  r.snooze,
+		"block":   r.block,
+		"unblock": r.unblock,
+		"label":   r.label,
+		"rank":    r.rank,
+		"config":  r.config,
+		"export":  r.export,
+		"import":  r.importTodos,
+		"serve":   r.serve,
+		"status":  r.status,
+		"doctor":  r.doctor,
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+
+		return ExitOK
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		return r.fail(fmt.Errorf("%w: unknown command '%s'", errUsage, args[0]))
+	}
+
+	if err := command(args[1:]); err != nil {
+		return r.fail(err)
+	}
+
+	return ExitOK
+}
+
+// fail reports an error and returns the matching exit code.
+func (r *runner) fail(err error) int {
+	fmt.Fprintf(r.stderr, "tt: %s\n", err)
+
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(r.stderr, usage)
+
+		return ExitUsage
+	}
+
+	return ExitError
+}
+
+// stringList is a flag.Value that collects every use of a repeated flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+
+	return nil
+}
+
+// newFlagSet returns a FlagSet that leaves reporting parse errors to Run.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	return flags
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional arguments, which the flag
+// package doesn't support on its own, and returns the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// expectArgs checks the number of positional arguments for a command.
+func expectArgs(command string, args []string, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("%w: %s expects %d argument(s) but got %d", errUsage, command, count, len(args))
+	}
+
+	return nil
+}
+
+func (r *runner) findTodo(idText string) (*db.Todo, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(idText, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid todo id '%s'", errUsage, idText)
+	}
+
+	return r.db.TodoByID(r.ctx, id)
+}
+
+func (r *runner) printTodo(todo *db.Todo) {
+	names := make([]string, 0, len(todo.Labels))
+	for _, label := range todo.Labels {
+		names = append(names, label.Name)
+	}
+
+	line := fmt.Sprintf("#%-4d %-10s %d. %s", todo.ID(), todo.Status.Name, todo.Rank+1, todo.Title)
+	if len(names) > 0 {
+		line += fmt.Sprintf(" [%s]", strings.Join(names, ", "))
+	}
+
+	if blockers := todo.OpenBlockers(); len(blockers) > 0 {
+		ids := make([]string, 0, len(blockers))
+		for _, blocker := range blockers {
+			ids = append(ids, fmt.Sprintf("#%d", blocker.ID()))
+		}
+
+		line += fmt.Sprintf(" (blocked by %s)", strings.Join(ids, ", "))
+	}
+
+	fmt.Fprintln(r.stdout, line)
+}
+
+// printChanged prints the todo as it is after a change: the todo itself is a copy from before it.
+func (r *runner) printChanged(todo *db.Todo) error {
+	current, err := r.db.TodoByID(r.ctx, todo.ID())
+	if err != nil {
+		return err
+	}
+
+	r.printTodo(current)
+
+	return nil
+}
+
+func (r *runner) add(args []string) error {
+	flags := newFlagSet("add")
+	description := flags.String("d", "", "description of the todo")
+
+	var labelNames stringList
+
+	flags.Var(&labelNames, "l", "label to add to the todo; may be repeated")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("add", positional, 1); err != nil {
+		return err
+	}
+
+	// look up all labels before creating the todo so that a typo doesn't leave a half-labelled todo behind
+	labels := make([]*db.Label, 0, len(labelNames))
+
+	for _, name := range labelNames {
+		label, err := r.db.LabelByName(r.ctx, name)
+		if err != nil {
+			return err
+		}
+
+		labels = append(labels, label)
+	}
+
+	todo, err := r.db.NewTodo(r.ctx, positional[0], *description)
+	if err != nil {
+		return fmt.Errorf("error adding todo: %w", err)
+	}
+
+	for _, label := range labels {
+		if err = r.db.AddTodoLabel(r.ctx, todo, label); err != nil {
+			return fmt.Errorf("error adding label: %w", err)
+		}
+	}
+
+	return r.printChanged(todo)
+}
+
+func (r *runner) list(args []string) error {
+	if len(args) > 1 {
+		return expectArgs("ls", args, 1)
+	}
+
+	name := db.StatusClosed
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	status, err := r.db.StatusByName(r.ctx, name)
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range status.Todos {
+		r.printTodo(todo)
+	}
+
+	return nil
+}
+
+func (r *runner) changeStatus(idText, statusName string) error {
+	todo, err := r.findTodo(idText)
+	if err != nil {
+		return err
+	}
+
+	status, err := r.db.StatusByName(r.ctx, statusName)
+	if err != nil {
+		return err
+	}
+
+	if err = r.db.ChangeStatus(r.ctx, todo, todo.Status, status); err != nil {
+		return fmt.Errorf("error moving todo #%d: %w", todo.ID(), err)
+	}
+
+	return r.printChanged(todo)
+}
+
+func (r *runner) move(args []string) error {
+	if err := expectArgs("mv", args, 2); err != nil {
+		return err
+	}
+
+	return r.changeStatus(args[0], args[1])
+}
+
+func (r *runner) done(args []string) error {
+	if err := expectArgs("done", args, 1); err != nil {
+		return err
+	}
+
+	return r.changeStatus(args[0], db.StatusDone)
+}
+
+func (r *runner) remove(args []string) error {
+	if err := expectArgs("rm", args, 1); err != nil {
+		return err
+	}
+
+	todo, err := r.findTodo(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err = r.db.DeleteTodo(r.ctx, todo); err != nil {
+		return fmt.Errorf("error deleting todo #%d: %w", todo.ID(), err)
+	}
+
+	fmt.Fprintf(r.stdout, "deleted #%d %s\n", todo.ID(), todo.Title)
+
+	return nil
+}
+
+func (r *runner) snooze(args []string) error {
+	if len(args) < 2 {
+		return expectArgs("snooze", args, 2)
+	}
+
+	todo, err := r.findTodo(args[0])
+	if err != nil {
+		return err
+	}
+
+	// allow "next monday" without quotes
+	until, err := db.ParseSnoozeUntil(strings.Join(args[1:], " "), time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	if err = r.db.Snooze(r.ctx, todo, until); err != nil {
+		return fmt.Errorf("error snoozing todo #%d: %w", todo.ID(), err)
+	}
+
+	fmt.Fprintf(r.stdout, "snoozed #%d %s until %s\n", todo.ID(), todo.Title, until.Format(snoozeTimeFormat))
+
+	return nil
+}
+
+func (r *runner) block(args []string) error {
+	if err := expectArgs("block", args, 2); err != nil {
+		return err
+	}
+
+	todo, blocker, err := r.findDependency(args)
+	if err != nil {
+		return err
+	}
+
+	if err = r.db.AddDependency(r.ctx, todo, blocker); err != nil {
+		return fmt.Errorf("error blocking todo #%d: %w", todo.ID(), err)
+	}
+
+	return r.printChanged(todo)
+}
+
+func (r *runner) unblock(args []string) error {
+	if err := expectArgs("unblock", args, 2); err != nil {
+		return err
+	}
+
+	todo, blocker, err := r.findDependency(args)
+	if err != nil {
+		return err
+	}
+
+	if err = r.db.RemoveDependency(r.ctx, todo, blocker); err != nil {
+		return fmt.Errorf("error unblocking todo #%d: %w", todo.ID(), err)
+	}
+
+	return r.printChanged(todo)
+}
+
+// findDependency returns the todo and the blocker whose ids are given in args.
+func (r *runner) findDependency(args []string) (*db.Todo, *db.Todo, error) {
+	todo, err := r.findTodo(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocker, err := r.findTodo(args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return todo, blocker, nil
+}
+
+func (r *runner) export(args []string) error {
+	flags := newFlagSet("export")
+	formatName := flags.String("format", string(export.FormatJSON), "json, csv or md")
+
+	var statusNames stringList
+
+	flags.Var(&statusNames, "status", "status to export; may be repeated")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("export", positional, 0); err != nil {
+		return err
+	}
+
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	statuses := make([]*db.Status, 0, len(statusNames))
+
+	for _, name := range statusNames {
+		status, err := r.db.StatusByName(r.ctx, name)
+		if err != nil {
+			return err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return export.Write(r.stdout, r.db, format, statuses...)
+}
+
+func (r *runner) importTodos(args []string) error {
+	flags := newFlagSet("import")
+	formatName := flags.String("format", "", "json, csv or todotxt; guessed from the file extension by default")
+	mappingText := flags.String("map", "", "csv columns for fields, e.g. title=Task,labels=Tags")
+	dryRun := flags.Bool("dry-run", false, "report what would change without changing anything")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("import", positional, 1); err != nil {
+		return err
+	}
+
+	source, err := readImport(positional[0], *formatName, *mappingText)
+	if err != nil {
+		return err
+	}
+
+	report, err := importer.Import(r.ctx, r.db, source, *dryRun)
+	if report != nil {
+		r.printImport(report)
+	}
+
+	return err
+}
+
+// readImport reads the file to import in the given format, or the format that its extension suggests.
+func readImport(filename, formatName, mappingText string) (*importer.Source, error) {
+	format, err := importer.FormatForFile(filename)
+	if formatName != "" {
+		format, err = importer.ParseFormat(formatName)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	mapping, err := importer.ParseMapping(mappingText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file to import: %w", err)
+	}
+	defer file.Close()
+
+	switch format {
+	case importer.FormatJSON:
+		return importer.ReadJSON(file)
+	case importer.FormatCSV:
+		return importer.ReadCSV(file, mapping)
+	}
+
+	return importer.ReadTodoTxt(file)
+}
+
+// printImport lists the labels and todos that were added, or would be added in a dry run, and reports warnings on
+// stderr.
+func (r *runner) printImport(report *importer.Report) {
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(r.stderr, "tt: warning: %s\n", warning)
+	}
+
+	if report.DryRun {
+		for _, name := range report.Labels {
+			fmt.Fprintf(r.stdout, "would create label '%s'\n", name)
+		}
+
+		for _, planned := range report.Todos {
+			fmt.Fprintf(r.stdout, "would add '%s' to %s\n", planned.Item.Title, planned.Status.Name)
+		}
+
+		return
+	}
+
+	for _, name := range report.Labels {
+		fmt.Fprintf(r.stdout, "created label '%s'\n", name)
+	}
+
+	for _, planned := range report.Todos {
+		if planned.Todo != nil {
+			r.printTodo(planned.Todo)
+		}
+	}
+}
+
+func (r *runner) serve(args []string) error {
+	flags := newFlagSet("serve")
+	addr := flags.String("addr", "127.0.0.1:7070", "address to listen on")
+	token := flags.String("token", os.Getenv("TT_API_TOKEN"), "token that requests must send; none by default")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("serve", positional, 0); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(r.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(r.stdout, "serving on http://%s\n", *addr)
+
+	return server.New(r.db, *token).ListenAndServe(ctx, *addr)
+}
+
+func (r *runner) label(args []string) error {
+	if err := expectArgs("label", args, 3); err != nil {
+		return err
+	}
+
+	var change func(context.Context, *db.Todo, *db.Label) error
+
+	switch args[0] {
+	case "add":
+		change = r.db.AddTodoLabel
+	case "rm":
+		change = r.db.RemoveTodoLabel
+	default:
+		return fmt.Errorf("%w: unknown label command '%s'", errUsage, args[0])
+	}
+
+	todo, err := r.findTodo(args[1])
+	if err != nil {
+		return err
+	}
+
+	label, err := r.db.LabelByName(r.ctx, args[2])
+	if err != nil {
+		return err
+	}
+
+	if err = change(r.ctx, todo, label); err != nil {
+		return fmt.Errorf("error changing labels of todo #%d: %w", todo.ID(), err)
+	}
+
+	return r.printChanged(todo)
+}
+
+func (r *runner) rank(args []string) error {
+	if err := expectArgs("rank", args, 2); err != nil {
+		return err
+	}
+
+	moves := map[string]func(context.Context, *db.Todo) error{
+		"top":    r.db.MoveToTop,
+		"bottom": r.db.MoveToBottom,
+		"up":     r.db.MoveUp,
+		"down":   r.db.MoveDown,
+	}
+
+	moveFunc, ok := moves[args[1]]
+	if !ok {
+		return fmt.Errorf("%w: unknown position '%s'", errUsage, args[1])
+	}
+
+	todo, err := r.findTodo(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err = moveFunc(r.ctx, todo); err != nil {
+		return fmt.Errorf("error moving todo #%d: %w", todo.ID(), err)
+	}
+
+	return r.printChanged(todo)
+}
+
+func (r *runner) config(args []string) error {
+	if len(args) > 2 {
+		return expectArgs("config", args, 2)
+	}
+
+	if len(args) > 0 && args[0] != db.SettingMaxClosedTodos {
+		return fmt.Errorf("%w: unknown setting '%s'", errUsage, args[0])
+	}
+
+	if len(args) == 2 {
+		limit, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("%w: %s must be a number, not '%s'", errUsage, args[0], args[1])
+		}
+
+		if err = r.db.SetMaxClosedTodos(r.ctx, limit); err != nil {
+			return fmt.Errorf("error changing setting: %w", err)
+		}
+	}
+
+	line := fmt.Sprintf("%s = %d", db.SettingMaxClosedTodos, r.db.MaxClosedTodos())
+	if r.db.MaxClosedTodosOverridden() {
+		line += " (overridden for this session)"
+	}
+
+	fmt.Fprintln(r.stdout, line)
+
+	return nil
+}
+
+// defaultStatusColor is the color of statuses added without one.
+const defaultStatusColor = "#FFFFFF"
+
+func (r *runner) status(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: status expects a command", errUsage)
+	}
+
+	switch args[0] {
+	case "ls":
+		if err := expectArgs("status ls", args[1:], 0); err != nil {
+			return err
+		}
+
+		r.listStatuses()
+
+		return nil
+	case "add":
+		return r.addStatus(args[1:])
+	case "allow", "deny":
+		return r.setTransition(args[0], args[1:])
+	case "limit":
+		return r.setStatusLimit(args[1:])
+	default:
+		return fmt.Errorf("%w: unknown status command '%s'", errUsage, args[0])
+	}
+}
+
+func (r *runner) listStatuses() {
+	statuses := r.db.OrderedStatuses()
+
+	for _, status := range statuses {
+		targets := []string{}
+
+		for _, other := range statuses {
+			if other != status && r.db.CanTransition(status, other) {
+				targets = append(targets, other.Name)
+			}
+		}
+
+		limit := "none"
+		if l := r.db.StatusLimit(status); l > 0 {
+			limit = strconv.Itoa(l)
+		}
+
+		fmt.Fprintf(r.stdout, "%-10s key=%-2s limit=%-5s -> %s\n",
+			status.Name, status.Key, limit, strings.Join(targets, ", "))
+	}
+}
+
+func (r *runner) addStatus(args []string) error {
+	flags := newFlagSet("status add")
+	color := flags.String("c", defaultStatusColor, "color of the status")
+	limit := flags.Int("w", 0, "maximum number of todos in the status")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("status add", positional, 2); err != nil {
+		return err
+	}
+
+	status, err := r.db.NewStatus(r.ctx, positional[0], positional[1], *color, *limit)
+	if err != nil {
+		return fmt.Errorf("error adding status: %w", err)
+	}
+
+	fmt.Fprintf(r.stdout, "added %s with key %s\n", status.Name, status.Key)
+
+	return nil
+}
+
+func (r *runner) setTransition(command string, args []string) error {
+	if err := expectArgs("status "+command, args, 2); err != nil {
+		return err
+	}
+
+	from, err := r.db.StatusByName(r.ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	to, err := r.db.StatusByName(r.ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	if err = r.db.SetTransition(r.ctx, from, to, command == "allow"); err != nil {
+		return fmt.Errorf("error changing transition: %w", err)
+	}
+
+	fmt.Fprintf(r.stdout, "%s %s -> %s\n", command, from.Name, to.Name)
+
+	return nil
+}
+
+func (r *runner) setStatusLimit(args []string) error {
+	if err := expectArgs("status limit", args, 2); err != nil {
+		return err
+	}
+
+	status, err := r.db.StatusByName(r.ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	limit, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("%w: the limit must be a number, not '%s'", errUsage, args[1])
+	}
+
+	// the closed list keeps its own rules for its limit
+	if status.Name == db.StatusClosed {
+		err = r.db.SetMaxClosedTodos(r.ctx, limit)
+	} else {
+		err = r.db.SetWIPLimit(r.ctx, status, limit)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error changing limit: %w", err)
+	}
+
+	fmt.Fprintf(r.stdout, "%s limit = %d\n", status.Name, r.db.StatusLimit(status))
+
+	return nil
+}
+
+func (r *runner) doctor(args []string) error {
+	flags := newFlagSet("doctor")
+	fix := flags.Bool("fix", false, "renumber the todos in each status to fix gaps in their ranks")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = expectArgs("doctor", positional, 0); err != nil {
+		return err
+	}
+
+	problems, err := r.db.CheckIntegrity(r.ctx)
+	if err != nil {
+		return fmt.Errorf("error checking integrity: %w", err)
+	}
+
+	if *fix && len(problems) > 0 {
+		changed, err := r.db.RepairRanks(r.ctx)
+		if err != nil {
+			return fmt.Errorf("error repairing ranks: %w", err)
+		}
+
+		fmt.Fprintf(r.stdout, "renumbered %d todo(s)\n", changed)
+
+		if problems, err = r.db.CheckIntegrity(r.ctx); err != nil {
+			return fmt.Errorf("error checking integrity: %w", err)
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(r.stdout, problem)
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(r.stdout, "no problems found")
+
+		return nil
+	}
+
+	if *fix {
+		return fmt.Errorf("%w: %d problem(s) can't be fixed automatically", errIntegrity, len(problems))
+	}
+
+	return fmt.Errorf("%w: found %d problem(s); tt doctor --fix renumbers ranks", errIntegrity, len(problems))
+}
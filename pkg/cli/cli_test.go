@@ -0,0 +1,468 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/cli"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func getDB(assert *assert.Assertions) *db.Database {
+	tempFile, err := os.CreateTemp("/tmp", "test_cli*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(context.Background(), tempFile.Name())
+	assert.Nil(err)
+
+	return database
+}
+
+// run runs the command line and returns the exit code, stdout and stderr.
+func run(database *db.Database, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	code := cli.Run(context.Background(), database, args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// current returns a new copy of the todo: the commands don't change the copies that the test holds.
+func current(assert *assert.Assertions, database *db.Database, todo *db.Todo) *db.Todo {
+	todo, err := database.TodoByID(context.Background(), todo.ID())
+	assert.Nil(err)
+
+	return todo
+}
+
+func TestAddAndList(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, stdout, stderr := run(database, "add", "write report", "-d", "quarterly", "-l", "urgent", "-l", "task")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "write report [urgent, task]")
+
+	todo := database.Snapshot().Statuses[db.StatusOpen].Todos[0]
+	assert.Equal("write report", todo.Title)
+	assert.Equal("quarterly", todo.Description)
+	assert.Equal(2, len(todo.Labels))
+
+	// flags may come before the title too
+	code, _, stderr = run(database, "add", "-d", "details", "second")
+	assert.Equal(cli.ExitOK, code, stderr)
+
+	code, stdout, _ = run(database, "ls", "open")
+	assert.Equal(cli.ExitOK, code)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Equal(2, len(lines))
+	assert.Contains(lines[0], "1. write report")
+	assert.Contains(lines[1], "2. second")
+
+	// labels are checked before the todo is created
+	code, _, stderr = run(database, "add", "third", "-l", "nonexistent")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, "nonexistent")
+	assert.Equal(2, len(database.Snapshot().Todos))
+}
+
+func TestMoveAndDone(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo, err := database.NewTodo(context.Background(), "finish it", "")
+	assert.Nil(err)
+
+	id := strconv.Itoa(todo.ID())
+
+	// the same status rules apply as in the UI
+	code, _, stderr := run(database, "done", id)
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, db.ErrInvalidTodoMove.Error())
+	assert.Equal(db.StatusOpen, current(assert, database, todo).Status.Name)
+
+	code, _, stderr = run(database, "mv", "#"+id, db.StatusClosed)
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(db.StatusClosed, current(assert, database, todo).Status.Name)
+
+	code, _, stderr = run(database, "done", id)
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(db.StatusDone, current(assert, database, todo).Status.Name)
+}
+
+func TestMaxClosedTodos(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	for i := 0; i <= database.MaxClosedTodos(); i++ {
+		code, _, stderr := run(database, "add", "todo")
+		assert.Equal(cli.ExitOK, code, stderr)
+	}
+
+	for i, todo := range database.Snapshot().Todos {
+		code, _, stderr := run(database, "mv", strconv.Itoa(todo.ID()), db.StatusClosed)
+		if i < database.MaxClosedTodos() {
+			assert.Equal(cli.ExitOK, code, stderr)
+		} else {
+			assert.Equal(cli.ExitError, code)
+			assert.Contains(stderr, db.ErrMaxClosedTodos.Error())
+		}
+	}
+}
+
+func TestLabelAndRank(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	for _, title := range []string{"a", "b", "c"} {
+		code, _, stderr := run(database, "add", title)
+		assert.Equal(cli.ExitOK, code, stderr)
+	}
+
+	last := database.Snapshot().Statuses[db.StatusOpen].Todos[2]
+	id := strconv.Itoa(last.ID())
+
+	code, _, stderr := run(database, "rank", id, "top")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(0, current(assert, database, last).Rank)
+
+	code, _, stderr = run(database, "label", "add", id, "urgent")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(1, len(current(assert, database, last).Labels))
+
+	code, _, stderr = run(database, "label", "rm", id, "urgent")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(0, len(current(assert, database, last).Labels))
+
+	code, _, _ = run(database, "rank", id, "sideways")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, stdout, stderr := run(database, "rm", id)
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "deleted #"+id)
+	assert.Equal(2, len(database.Snapshot().Statuses[db.StatusOpen].Todos))
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, _, stderr := run(database, "frobnicate")
+	assert.Equal(cli.ExitUsage, code)
+	assert.Contains(stderr, "unknown command 'frobnicate'")
+	assert.Contains(stderr, "usage:")
+
+	code, _, _ = run(database, "add")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, _, _ = run(database, "add", "title", "-x")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, _, stderr = run(database, "done", "42")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, "no todo found with id 42")
+
+	code, _, _ = run(database, "done", "forty-two")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, _, stderr = run(database, "ls", "someday")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, db.ErrStatusNotFound.Error()+" named 'someday'")
+
+	code, stdout, _ := run(database, "help")
+	assert.Equal(cli.ExitOK, code)
+	assert.Contains(stdout, "usage:")
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, stdout, _ := run(database, "config")
+	assert.Equal(cli.ExitOK, code)
+	assert.Equal("max_closed_todos = 5\n", stdout)
+
+	code, stdout, stderr := run(database, "config", "max_closed_todos", "3")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal("max_closed_todos = 3\n", stdout)
+	assert.Equal(3, database.MaxClosedTodos())
+
+	code, _, stderr = run(database, "config", "max_closed_todos", "0")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, db.ErrInvalidSetting.Error())
+
+	code, _, _ = run(database, "config", "max_closed_todos", "many")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, _, _ = run(database, "config", "colour")
+	assert.Equal(cli.ExitUsage, code)
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, stdout, stderr := run(database, "status", "add", "review", "v", "-w", "1")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "added review with key v")
+
+	code, _, stderr = run(database, "status", "add", "blocked", "o")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, db.ErrInvalidStatus.Error())
+
+	// r shows the review page in the UI
+	code, _, stderr = run(database, "status", "add", "blocked", "r")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, "the key 'r' is used for other actions in the UI")
+
+	code, _, stderr = run(database, "status", "deny", "review", "done")
+	assert.Equal(cli.ExitOK, code, stderr)
+
+	statuses := database.Snapshot().Statuses
+	assert.False(database.CanTransition(statuses["review"], statuses[db.StatusDone]))
+
+	code, _, stderr = run(database, "status", "limit", "on_hold", "4")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal(4, database.Snapshot().Statuses[db.StatusOnHold].WIPLimit)
+
+	code, stdout, _ = run(database, "status", "ls")
+	assert.Equal(cli.ExitOK, code)
+	assert.Contains(stdout, "review")
+	assert.Contains(stdout, "limit=4")
+
+	code, _, _ = run(database, "status", "limit", "on_hold", "lots")
+	assert.Equal(cli.ExitUsage, code)
+
+	code, _, _ = run(database, "status", "rename")
+	assert.Equal(cli.ExitUsage, code)
+}
+
+func TestSnooze(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo, err := database.NewTodo(context.Background(), "later", "")
+	assert.Nil(err)
+
+	id := strconv.Itoa(todo.ID())
+
+	code, stdout, stderr := run(database, "snooze", id, "next", "monday")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "snoozed #"+id)
+
+	todo, err = database.TodoByID(context.Background(), todo.ID())
+	assert.Nil(err)
+	assert.Equal(db.StatusOnHold, todo.Status.Name)
+	assert.Equal(time.Monday, todo.SnoozedUntil.Weekday())
+
+	code, _, stderr = run(database, "snooze", id, "someday")
+	assert.Equal(cli.ExitUsage, code)
+	assert.Contains(stderr, db.ErrInvalidSnooze.Error())
+}
+
+func TestBlock(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	ctx := context.Background()
+
+	todo, err := database.NewTodo(ctx, "ship", "")
+	assert.Nil(err)
+	blocker, err := database.NewTodo(ctx, "review", "")
+	assert.Nil(err)
+
+	id, blockerID := strconv.Itoa(todo.ID()), strconv.Itoa(blocker.ID())
+
+	code, stdout, stderr := run(database, "block", id, blockerID)
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "(blocked by #"+blockerID+")")
+
+	code, _, stderr = run(database, "block", blockerID, id)
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, db.ErrDependencyCycle.Error())
+
+	assert.Nil(database.ChangeStatus(ctx, todo, todo.Status, database.Snapshot().Statuses[db.StatusClosed]))
+
+	code, _, stderr = run(database, "done", id)
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, "'ship' is waiting on #"+blockerID+" 'review'")
+
+	code, stdout, stderr = run(database, "unblock", id, blockerID)
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.NotContains(stdout, "blocked by")
+
+	code, _, stderr = run(database, "done", id)
+	assert.Equal(cli.ExitOK, code, stderr)
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, _, stderr := run(database, "add", "write report", "-l", "onboarding")
+	assert.Equal(cli.ExitOK, code, stderr)
+
+	code, stdout, stderr := run(database, "export", "--format", "md", "--status", "open")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal("## Open\n\n1. write report `onboarding`\n", stdout)
+
+	code, stdout, stderr = run(database, "export")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, `"schema_version": 1`)
+
+	code, _, stderr = run(database, "export", "--format", "xml")
+	assert.Equal(cli.ExitUsage, code)
+	assert.Contains(stderr, "unknown export format")
+
+	code, _, _ = run(database, "export", "--status", "someday")
+	assert.Equal(cli.ExitError, code)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	file, err := os.CreateTemp("/tmp", "test_import*.txt")
+	assert.Nil(err)
+
+	_, err = file.WriteString("(A) plan launch +launch\nx send invites @email\n")
+	assert.Nil(err)
+	assert.Nil(file.Close())
+
+	code, stdout, stderr := run(database, "import", "--dry-run", file.Name())
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal("would create label 'launch'\nwould create label 'email'\n"+
+		"would add 'plan launch' to open\nwould add 'send invites' to done\n", stdout)
+	assert.Empty(database.Snapshot().Todos)
+
+	code, stdout, stderr = run(database, "import", file.Name())
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Contains(stdout, "created label 'launch'")
+	assert.Contains(stdout, "open       1. plan launch [launch]")
+	assert.Equal(2, len(database.Snapshot().Todos))
+
+	code, _, stderr = run(database, "import", "--format", "xml", file.Name())
+	assert.Equal(cli.ExitUsage, code)
+	assert.Contains(stderr, "unknown import format")
+
+	code, _, _ = run(database, "import", "/tmp/does-not-exist.json")
+	assert.Equal(cli.ExitError, code)
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	code, _, _ := run(database, "serve", "extra")
+	assert.Equal(cli.ExitUsage, code)
+
+	// serving runs until interrupted, so only check that listening errors are reported
+	code, _, stderr := run(database, "serve", "--addr", "127.0.0.1:not-a-port")
+	assert.Equal(cli.ExitError, code)
+	assert.Contains(stderr, "error serving on 127.0.0.1:not-a-port")
+}
+
+func TestDoctor(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_cli*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	code, stdout, _ := run(database, "doctor")
+	assert.Equal(cli.ExitOK, code)
+	assert.Equal("no problems found\n", stdout)
+
+	for _, title := range []string{"first", "second"} {
+		_, err = database.NewTodo(ctx, title, "")
+		assert.Nil(err)
+	}
+
+	database.Close()
+
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	_, err = conn.ExecContext(ctx, `UPDATE todo SET rank=4 WHERE title='second'`)
+	assert.Nil(err)
+	conn.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	code, stdout, stderr := run(database, "doctor")
+	assert.Equal(cli.ExitError, code)
+	assert.Equal("todo #2 'second' has rank 4 in open but should have rank 1\n", stdout)
+	assert.Contains(stderr, "tt doctor --fix")
+
+	code, stdout, stderr = run(database, "doctor", "--fix")
+	assert.Equal(cli.ExitOK, code, stderr)
+	assert.Equal("renumbered 1 todo(s)\nno problems found\n", stdout)
+	assert.Equal(1, database.Snapshot().Statuses[db.StatusOpen].Todos[1].Rank)
+
+	code, _, _ = run(database, "doctor", "extra")
+	assert.Equal(cli.ExitUsage, code)
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
+)
+
+// checklistProgress describes how much of the Todo's checklist is done, e.g. " [3/5]", or "" if it has none.
+func checklistProgress(todo *db.Todo) string {
+	done, total := todo.ChecklistProgress()
+	if total == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" [%d/%d]", done, total)
+}
+
+func (c *Controller) handleChecklistKeys(evt *tcell.EventKey) *tcell.EventKey {
+	// while typing a new item, keys belong to the input field
+	if c.checklistInput.HasFocus() {
+		return evt
+	}
+
+	key := AsKey(evt)
+	if k, ok := c.checklistEvents[key]; ok {
+		c.setErrorText("")
+
+		return k.Action(evt)
+	}
+
+	return evt
+}
+
+// initChecklistEvents defines the actions available on the checklist page.
+func (c *Controller) initChecklistEvents() {
+	c.checklistEvents[KeyShiftN] = KeyEvent{
+		Description: "New Item",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.app.SetFocus(c.checklistInput)
+
+			return nil
+		},
+	}
+
+	c.checklistEvents[KeySpace] = KeyEvent{
+		Description: "Check/Uncheck Item",
+		Action: c.getSelectedChecklistItemAction(func(item *db.ChecklistItem) error {
+			return c.db.SetChecklistItemDone(c.ctx, c.selectedTodo, item, !item.Done)
+		}),
+	}
+
+	c.checklistEvents[KeyShiftK] = KeyEvent{
+		Description: "Move Item Up",
+		Action:      c.getMoveChecklistItemAction(-1),
+	}
+
+	c.checklistEvents[KeyShiftJ] = KeyEvent{
+		Description: "Move Item Down",
+		Action:      c.getMoveChecklistItemAction(1),
+	}
+
+	c.checklistEvents[KeyShiftX] = KeyEvent{
+		Description: "Delete Item",
+		Action: c.getSelectedChecklistItemAction(func(item *db.ChecklistItem) error {
+			return c.db.DeleteChecklistItem(c.ctx, c.selectedTodo, item)
+		}),
+	}
+
+	c.checklistEvents[tcell.KeyEscape] = KeyEvent{
+		Description: "Back",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.showSelectedStatus()
+
+			return nil
+		},
+	}
+}
+
+// getSelectedChecklistItemAction returns an action that calls f with the item selected in the checklist table, if
+// any, and then refreshes the table.
+func (c *Controller) getSelectedChecklistItemAction(
+	f func(*db.ChecklistItem) error,
+) func(*tcell.EventKey) *tcell.EventKey {
+	return func(key *tcell.EventKey) *tcell.EventKey {
+		row, _ := c.checklistTable.GetSelection()
+		if row < 1 || row > len(c.selectedTodo.Checklist) {
+			log.Debug().Msgf("cannot act on checklist item: no item selected (row %d)", row)
+
+			return nil
+		}
+
+		if err := f(c.selectedTodo.Checklist[row-1]); err != nil {
+			c.setErrorText(fmt.Sprintf("error updating checklist: %s", err))
+		}
+
+		c.refreshChecklist(row)
+
+		return nil
+	}
+}
+
+// getMoveChecklistItemAction returns an action that moves the selected item by offset places, keeping it selected.
+func (c *Controller) getMoveChecklistItemAction(offset int) func(*tcell.EventKey) *tcell.EventKey {
+	return func(key *tcell.EventKey) *tcell.EventKey {
+		row, _ := c.checklistTable.GetSelection()
+		idx := row - 1 + offset
+
+		if row < 1 || row > len(c.selectedTodo.Checklist) || idx < 0 || idx >= len(c.selectedTodo.Checklist) {
+			return nil
+		}
+
+		if err := c.db.MoveChecklistItem(c.ctx, c.selectedTodo, c.selectedTodo.Checklist[row-1], idx); err != nil {
+			c.setErrorText(fmt.Sprintf("error moving checklist item: %s", err))
+		}
+
+		c.refreshChecklist(idx + 1)
+
+		return nil
+	}
+}
+
+func (c *Controller) getChecklistGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "checklist"
+
+	c.initEventsHeader(name, c.checklistEvents)
+
+	c.checklistTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+
+	c.checklistInput = tview.NewInputField().SetLabel("New item: ")
+	c.checklistInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter && c.checklistInput.GetText() != "" {
+			if _, err := c.db.AddChecklistItem(c.ctx, c.selectedTodo, c.checklistInput.GetText()); err != nil {
+				c.setErrorText(fmt.Sprintf("error adding checklist item: %s", err))
+
+				return
+			}
+
+			c.refreshContents()
+			c.refreshChecklist(len(c.selectedTodo.Checklist))
+		}
+
+		c.checklistInput.SetText("")
+		c.app.SetFocus(c.checklistTable)
+	})
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.checklistTable, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+	grid.AddItem(c.checklistInput, headerRows*3+2, 0, 1, 1, 0, 0, false)
+
+	return grid
+}
+
+// switchToChecklist shows the checklist of the selectedTodo.
+func (c *Controller) switchToChecklist() {
+	name := "checklist"
+
+	c.checklistInput.SetText("")
+	c.refreshChecklist(1)
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetFocus(c.checklistTable)
+	c.app.SetInputCapture(c.handleChecklistKeys)
+}
+
+// refreshChecklist redraws the checklist table with a new copy of the selectedTodo and selects the given row.
+func (c *Controller) refreshChecklist(row int) {
+	name := "checklist"
+
+	c.refreshContents()
+
+	c.setFormTitle(name, fmt.Sprintf("Checklist: #%d %s%s", c.selectedTodo.ID(), c.selectedTodo.Title,
+		checklistProgress(c.selectedTodo)))
+
+	c.checklistTable.Clear()
+
+	for col, header := range []string{"done", "item"} {
+		c.checklistTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	for idx, item := range c.selectedTodo.Checklist {
+		check := "[ ]"
+		if item.Done {
+			check = "[x]"
+		}
+
+		// escape the brackets so that tview doesn't read them as a color tag
+		c.checklistTable.SetCell(idx+1, 0, tview.NewTableCell(tview.Escape(check)))
+		c.checklistTable.SetCell(idx+1, 1, tview.NewTableCell(item.Text).SetExpansion(1))
+	}
+
+	c.checklistTable.Select(row, 0)
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rs/zerolog/log"
+)
+
+const cancelButton = "Cancel"
+
+// confirm shows a modal asking the user to confirm a destructive action. onConfirm is called if the user presses the
+// button labelled confirmButton; onCancel is called if they cancel, which is the default button.
+func (c *Controller) confirm(message, confirmButton string, onConfirm, onCancel func()) {
+	c.confirmModal.ClearButtons().
+		SetText(message).
+		AddButtons([]string{confirmButton, cancelButton}).
+		SetDoneFunc(func(_ int, buttonLabel string) {
+			if buttonLabel == confirmButton {
+				onConfirm()
+
+				return
+			}
+
+			onCancel()
+		}).
+		SetFocus(1)
+
+	c.pages.SwitchToPage(pageName("confirm"))
+
+	// the modal handles escape itself
+	c.app.SetInputCapture(nil)
+}
+
+func (c *Controller) showSelectedStatus() {
+	c.showStatus(c.selectedStatus.Name)
+}
+
+func (c *Controller) confirmDeleteTodo(todo *db.Todo) {
+	c.confirm(fmt.Sprintf("Delete todo #%d '%s'?", todo.ID(), todo.Title), "Delete", func() {
+		log.Debug().Msgf("deleting todo '%s'", todo.Title)
+
+		status, rank := todo.Status.Name, todo.Rank
+
+		if err := c.db.DeleteTodo(c.ctx, todo); err != nil {
+			c.setErrorText(fmt.Sprintf("error deleting todo: %s", err))
+		} else if rank > 0 {
+			// select the todo above the deleted one
+			c.updateTableSelection(status, rank-1)
+		}
+
+		c.showSelectedStatus()
+	}, c.showSelectedStatus)
+}
+
+// confirmDeleteLabel asks the user to confirm deleting the given Label, then calls back.
+func (c *Controller) confirmDeleteLabel(label *db.Label, back func()) {
+	count := len(c.db.TodosWithLabel(label))
+
+	c.confirm(fmt.Sprintf("Delete label '%s'? It will be removed from %d todo(s).", label.Name, count), "Delete", func() {
+		log.Debug().Msgf("deleting label '%s'", label.Name)
+
+		if err := c.db.DeleteLabel(c.ctx, label); err != nil {
+			c.setErrorText(fmt.Sprintf("error deleting label: %s", err))
+		}
+
+		back()
+	}, back)
+}
@@ -22,12 +22,10 @@ const (
 	errorTextRows = 2
 )
 
-// TODO (medium): view recently done tasks - another page with a list of todos with status Done, updated yesterday?
-
 // Controller mediates between the model and the view.
 type Controller struct {
 	ctx context.Context
-	db  *db.Database
+	db  db.Store
 	app *tview.Application
 
 	// selectedStatus contains the most recently selected Status, which is needed to return when escaping from a form,
@@ -39,12 +37,21 @@ type Controller struct {
 	// Controller maintains programatically named pages that the user can switch between.
 	// Importantly, the contents of each page exist even when not visible.
 	// There's one page for each status, where we display the Todos with that status,
-	// one page with a basic form to add or edit Todos, and one page with a form to add or remove Labels from a Todo.
+	// one page with a basic form to add or edit Todos, one page with a form to add or remove Labels from a Todo,
+	// one page with the history of the selected Todo, and one page to review recently done Todos.
 	pages *tview.Pages
 
 	// statusTables stores one table per status; these are the visible table objects that contain the Todos and a
 	// header row.
 	statusTables map[string]*tview.Table
+	// statusHeaders stores the header of each status page, whose title is refreshed when the page is shown.
+	statusHeaders map[string]*tview.Table
+	// statusContents stores the content of each status table, which knows the Todos shown in each row.
+	statusContents map[string]*StatusContent
+	// filterInput is the filter bar shared by the status pages; it shows the filter of the selectedStatus.
+	filterInput *tview.InputField
+	// previousFilter is the filter of the selectedStatus from before the filterInput got focus.
+	previousFilter *todoFilter
 
 	// formHeaderTables store the tables that make up the headers for the forms; we need access to them because
 	// their titles change depending on the current action.
@@ -53,22 +60,79 @@ type Controller struct {
 	// errorText is a shared component on all pages that displays errors
 	errorText *tview.TextView
 
-	// The todoForm contains fields for the title and description and a save button.
-	todoForm   *tview.Form
-	titleField *tview.InputField
-	descField  *tview.InputField
+	// The todoForm contains fields for the title, description, due date and recurrence and a save button.
+	todoForm    *tview.Form
+	titleField  *tview.InputField
+	descField   *tview.InputField
+	dueField    *tview.InputField
+	repeatField *tview.InputField
 
 	// The labelForm contains a dropdown that lists either Labels that do or do not currently apply to the selectedTodo
-	// depending on whether we are adding or removing Labels. It also contains a save button.
+	// depending on whether we are adding or removing Labels, or all Labels when deleting one. It also contains a save
+	// button.
 	labelForm     *tview.Form
 	labelDropDown *tview.DropDown
-	// addLabel indicates whether we are currently adding or removing a label
-	addLabel bool
+	// labelFormAction indicates whether we are currently adding, removing or deleting a label
+	labelFormAction labelAction
+
+	// confirmModal asks the user to confirm destructive actions such as deletes.
+	confirmModal *tview.Modal
+
+	// labelsTable lists all Labels with the number of Todos that carry them. The labelEditForm creates or edits a
+	// Label: editedLabel is the Label being edited (nil for a new Label), and labelColors holds the colors in the
+	// labelColorDropDown. The labelMergeForm merges the editedLabel into another Label, and labelTodosTable lists the
+	// Todos that carry a Label.
+	labelsTable        *tview.Table
+	labelEditForm      *tview.Form
+	labelNameField     *tview.InputField
+	labelColorDropDown *tview.DropDown
+	labelColors        []string
+	editedLabel        *db.Label
+	labelMergeForm     *tview.Form
+	labelMergeDropDown *tview.DropDown
+	labelTodosTable    *tview.Table
+
+	// The settingsForm edits per-database settings.
+	settingsForm        *tview.Form
+	maxClosedTodosField *tview.InputField
+
+	// historyTable lists the recorded changes to the selectedTodo.
+	historyTable *tview.Table
+
+	// The reviewForm selects the window of time shown in the reviewTable, which lists the Todos that were done (and
+	// optionally abandoned) within that window.
+	reviewForm           *tview.Form
+	reviewWindowDropDown *tview.DropDown
+	reviewFromField      *tview.InputField
+	reviewToField        *tview.InputField
+	reviewAbandonedBox   *tview.Checkbox
+	reviewTable          *tview.Table
+
+	// The dueSoonTable lists todos with due dates across statuses.
+	dueSoonTable *tview.Table
+
+	// The snoozeForm picks when the selectedTodo wakes up from a snooze.
+	snoozeForm       *tview.Form
+	snoozeUntilField *tview.InputField
+
+	// checklistTable lists the checklist items of the selectedTodo; new items are typed into the checklistInput.
+	checklistTable *tview.Table
+	checklistInput *tview.InputField
+
+	// The search overlay shows the todos matching the query in the searchInput in the searchTable.
+	searchInput *tview.InputField
+	searchTable *tview.Table
 
 	// events contains a map of keyboard actions accessible from status pages
 	events map[tcell.Key]KeyEvent
 	// formEvents contains a map of keyboard actions accessible from form pages
 	formEvents map[tcell.Key]KeyEvent
+	// labelEvents contains a map of keyboard actions accessible from the labels page
+	labelEvents map[tcell.Key]KeyEvent
+	// labelFormEvents contains a map of keyboard actions accessible from pages reached from the labels page
+	labelFormEvents map[tcell.Key]KeyEvent
+	// checklistEvents contains a map of keyboard actions accessible from the checklist page
+	checklistEvents map[tcell.Key]KeyEvent
 }
 
 // KeyEvent defines an event associated with a keypress.
@@ -78,12 +142,14 @@ type KeyEvent struct {
 }
 
 // NewController creates a new Controller to run the app.
-func NewController(ctx context.Context, db *db.Database) (*Controller, error) {
+func NewController(ctx context.Context, db db.Store) (*Controller, error) {
 	controller := Controller{
 		ctx:              ctx,
 		db:               db,
 		app:              tview.NewApplication(),
 		statusTables:     map[string]*tview.Table{},
+		statusHeaders:    map[string]*tview.Table{},
+		statusContents:   map[string]*StatusContent{},
 		formHeaderTables: map[string]*tview.Table{},
 	}
 
@@ -106,23 +172,50 @@ func (c *Controller) initSignals() {
 
 // Go starts the app.
 func (c *Controller) Go() {
-	c.selectedStatus = c.db.Statuses[db.StatusClosed]
+	c.selectedStatus = c.status(db.StatusClosed)
 
 	c.initPages()
 
 	c.app.SetInputCapture(c.handleKeys)
 
+	// the Todos may have changed since the filtered tables were last drawn
+	c.app.SetBeforeDrawFunc(func(tcell.Screen) bool {
+		c.refreshContents()
+
+		return false
+	})
+
 	if len(c.selectedStatus.Todos) > 0 {
 		c.setSelectedTodo(-1, c.selectedStatus.Todos[0])
 	}
 
+	c.wakeSnoozedPeriodically()
+	c.reloadPeriodically()
+
 	if err := c.app.SetRoot(c.pages, true).SetFocus(c.pages).Run(); err != nil {
 		panic(err)
 	}
 }
 
-func pageName(status string) string {
-	return fmt.Sprintf("page-%s", status)
+// status returns the Status with the given name, or nil if there is none.
+func (c *Controller) status(name string) *db.Status {
+	status, err := c.db.StatusByName(c.ctx, name)
+	if err != nil {
+		return nil
+	}
+
+	return status
+}
+
+// pageName returns the name of one of the fixed pages, like the todo form.
+func pageName(name string) string {
+	return fmt.Sprintf("page-%s", name)
+}
+
+// statusPageName returns the name of the page that lists the Todos with the given status. Status pages have names
+// of their own so that statuses can be named like fixed pages, e.g. review.
+func statusPageName(status string) string {
+	return fmt.Sprintf("status-%s", status)
 }
 
 func (c *Controller) initPages() {
@@ -130,11 +223,13 @@ func (c *Controller) initPages() {
 
 	c.errorText = tview.NewTextView().SetMaxLines(1).SetTextColor(tcell.ColorRed)
 
-	for status := range c.db.Statuses {
-		c.pages.AddPage(pageName(status),
-			c.getStatusGrid(status),
+	c.initFilterBar()
+
+	for _, status := range c.db.OrderedStatuses() {
+		c.pages.AddPage(statusPageName(status.Name),
+			c.getStatusGrid(status.Name),
 			true,
-			status == db.StatusClosed)
+			status.Name == db.StatusClosed)
 	}
 
 	c.pages.AddPage(pageName("form"),
@@ -146,6 +241,69 @@ func (c *Controller) initPages() {
 		c.getLabelFormGrid(),
 		true,
 		false)
+
+	c.pages.AddPage(pageName("history"),
+		c.getHistoryGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("review"),
+		c.getReviewGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("labels"),
+		c.getLabelsGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("labelEdit"),
+		c.getLabelEditGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("labelMerge"),
+		c.getLabelMergeGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("labelTodos"),
+		c.getLabelTodosGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("dueSoon"),
+		c.getDueSoonGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("snooze"),
+		c.getSnoozeGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("checklist"),
+		c.getChecklistGrid(),
+		true,
+		false)
+
+	c.pages.AddPage(pageName("settings"),
+		c.getSettingsGrid(),
+		true,
+		false)
+
+	c.confirmModal = tview.NewModal()
+
+	c.pages.AddPage(pageName("confirm"),
+		c.confirmModal,
+		true,
+		false)
+
+	// added last so that it's drawn on top of the other pages
+	c.pages.AddPage(pageName("search"),
+		c.getSearchOverlay(),
+		true,
+		false)
 }
 
 func (c *Controller) setErrorText(msg string) {
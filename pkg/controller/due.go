@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+)
+
+// dueCell returns a cell showing when the Todo is due, in red if it is overdue and in yellow if it is due today.
+// Recurring todos are marked with an arrow.
+func dueCell(todo *db.Todo, now time.Time) *tview.TableCell {
+	text := db.FormatDueDate(todo.DueDate)
+	if todo.Recurrence != nil {
+		text += " ↻"
+	}
+
+	cell := tview.NewTableCell(text)
+	if todo.DueDate == nil {
+		return cell
+	}
+
+	today := startOfDay(now)
+
+	switch {
+	case todo.DueDate.Before(today):
+		cell.SetTextColor(tcell.ColorRed)
+	case todo.DueDate.Equal(today):
+		cell.SetTextColor(tcell.ColorYellow)
+	}
+
+	return cell
+}
+
+func (c *Controller) getDueSoonGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "dueSoon"
+
+	c.initFormHeader(name)
+	c.setFormTitle(name, "Due Soon (Enter to go to a todo)")
+
+	c.dueSoonTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+	c.dueSoonTable.SetSelectedFunc(func(row, _ int) {
+		if todo, ok := c.dueSoonTable.GetCell(row, 0).GetReference().(*db.Todo); ok {
+			c.showTodo(todo)
+		}
+	})
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.dueSoonTable, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToDueSoon lists the todos with due dates that are still to be done, soonest first.
+func (c *Controller) switchToDueSoon() {
+	name := "dueSoon"
+
+	c.dueSoonTable.Clear()
+
+	for col, header := range []string{"#", "due", "status", "title", "labels"} {
+		c.dueSoonTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	now := time.Now()
+
+	for idx, todo := range c.db.TodosByDueDate() {
+		row := idx + 1
+
+		c.dueSoonTable.SetCell(row, 0,
+			tview.NewTableCell(fmt.Sprintf("#%d", todo.ID())).SetTextColor(tcell.ColorGray).SetReference(todo))
+		c.dueSoonTable.SetCell(row, 1, dueCell(todo, now))
+		c.dueSoonTable.SetCell(row, 2, tview.NewTableCell(todo.Status.Name))
+		c.dueSoonTable.SetCell(row, 3, tview.NewTableCell(todo.Title).SetExpansion(1))
+		c.dueSoonTable.SetCell(row, 4, tview.NewTableCell(labelNames(todo)).SetExpansion(1))
+	}
+
+	c.dueSoonTable.Select(1, 0).ScrollToBeginning()
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleFormKeys)
+}
+
+// showTodo shows the status page of the given Todo with it selected.
+func (c *Controller) showTodo(todo *db.Todo) {
+	c.updateTableSelection(todo.Status.Name, todo.Rank)
+	c.showStatus(todo.Status.Name)
+}
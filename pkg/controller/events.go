@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"unicode"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/matt-steen/todo-tracker/pkg/db"
@@ -11,6 +13,11 @@ import (
 )
 
 func (c *Controller) handleKeys(evt *tcell.EventKey) *tcell.EventKey {
+	// while typing a filter, keys belong to the filter bar
+	if c.filterInput.HasFocus() {
+		return evt
+	}
+
 	key := AsKey(evt)
 	if k, ok := c.events[key]; ok {
 		c.setErrorText("")
@@ -35,17 +42,33 @@ func (c *Controller) handleFormKeys(evt *tcell.EventKey) *tcell.EventKey {
 func (c *Controller) initEvents() {
 	c.events = map[tcell.Key]KeyEvent{}
 	c.formEvents = map[tcell.Key]KeyEvent{}
+	c.labelEvents = map[tcell.Key]KeyEvent{}
+	c.labelFormEvents = map[tcell.Key]KeyEvent{}
+	c.checklistEvents = map[tcell.Key]KeyEvent{}
 
-	c.initShowEvents(c.events)
-	c.initMoveEvents(c.events)
-
+	c.initLabelsEvent(c.events)
 	c.initFormEvents(c.events)
 	c.initLabelEvents(c.events)
+	c.initDeleteEvents(c.events)
+	c.initHistoryEvent(c.events)
+	c.initReviewEvent(c.events)
+	c.initDueSoonEvent(c.events)
+	c.initSnoozeEvent(c.events)
+	c.initChecklistEvent(c.events)
+	c.initSearchEvent(c.events)
+	c.initFilterEvent(c.events)
+	c.initSettingsEvent(c.events)
 
 	c.initRerankEvents(c.events)
+	c.initUndoEvents(c.events)
 	c.initExitEvent(c.events)
 
+	c.initStatusEvents(c.events)
+
 	c.initCancelEvent(c.formEvents)
+
+	c.initLabelPageEvents()
+	c.initChecklistEvents()
 }
 
 func (c *Controller) getShowAction(status string) func(key *tcell.EventKey) *tcell.EventKey {
@@ -56,42 +79,29 @@ func (c *Controller) getShowAction(status string) func(key *tcell.EventKey) *tce
 	}
 }
 
-func (c *Controller) initShowEvents(events map[tcell.Key]KeyEvent) {
-	events[KeyO] = KeyEvent{
-		Description: "Show Open",
-		Action:      c.getShowAction(db.StatusOpen),
-	}
-
-	events[KeyC] = KeyEvent{
-		Description: "Show Closed",
-		Action:      c.getShowAction(db.StatusClosed),
-	}
-
-	events[KeyD] = KeyEvent{
-		Description: "Show Done",
-		Action:      c.getShowAction(db.StatusDone),
-	}
-
-	events[KeyH] = KeyEvent{
-		Description: "Show On Hold",
-		Action:      c.getShowAction(db.StatusOnHold),
-	}
+func (c *Controller) initLabelsEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyL] = KeyEvent{
+		Description: "Show Labels",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToLabels()
 
-	events[KeyA] = KeyEvent{
-		Description: "Show Abandoned",
-		Action:      c.getShowAction(db.StatusAbandoned),
+			return nil
+		},
 	}
 }
 
 func (c *Controller) getMoveAction(status string) func(key *tcell.EventKey) *tcell.EventKey {
 	return func(key *tcell.EventKey) *tcell.EventKey {
-		err := c.db.ChangeStatus(c.ctx, c.selectedTodo, c.selectedStatus, c.db.Statuses[status])
+		err := c.db.ChangeStatus(c.ctx, c.selectedTodo, c.selectedStatus, c.status(status))
 		if err != nil {
 			c.setErrorText(err.Error())
 
 			return key
 		}
 
+		// the selectedTodo is a copy from before the move
+		c.refreshContents()
+
 		c.updateTableSelection(status, c.selectedTodo.Rank)
 
 		c.showStatus(status)
@@ -100,31 +110,54 @@ func (c *Controller) getMoveAction(status string) func(key *tcell.EventKey) *tce
 	}
 }
 
-func (c *Controller) initMoveEvents(events map[tcell.Key]KeyEvent) {
-	events[KeyShiftO] = KeyEvent{
-		Description: "Move to Open",
-		Action:      c.getMoveAction(db.StatusOpen),
-	}
+// initStatusEvents adds "Show <status>" and "Move to <status>" events for each status, using its key and the
+// uppercase version of it. It must be called after the other events are added so that statuses can't replace them;
+// db.ReservedStatusKeys has to list every letter that the other events use so that new statuses can't get such keys.
+func (c *Controller) initStatusEvents(events map[tcell.Key]KeyEvent) {
+	for _, status := range c.db.OrderedStatuses() {
+		if status.Key == "" {
+			log.Warn().Msgf("status %s has no key; it can't be shown", status.Name)
 
-	events[KeyShiftC] = KeyEvent{
-		Description: "Move to Closed",
-		Action:      c.getMoveAction(db.StatusClosed),
-	}
+			continue
+		}
 
-	events[KeyShiftD] = KeyEvent{
-		Description: "Move to Done",
-		Action:      c.getMoveAction(db.StatusDone),
-	}
+		showKey := tcell.Key(status.Key[0])
+		moveKey := tcell.Key(unicode.ToUpper(rune(status.Key[0])))
 
-	events[KeyShiftH] = KeyEvent{
-		Description: "Move to On Hold",
-		Action:      c.getMoveAction(db.StatusOnHold),
+		if _, ok := events[showKey]; ok {
+			log.Warn().Msgf("key %s for status %s is already used by %s", status.Key, status.Name, events[showKey].Description)
+
+			continue
+		}
+
+		if _, ok := events[moveKey]; ok {
+			log.Warn().Msgf("key %c for status %s is already used by %s", moveKey, status.Name, events[moveKey].Description)
+
+			continue
+		}
+
+		events[showKey] = KeyEvent{
+			Description: "Show " + statusDisplayName(status.Name),
+			Action:      c.getShowAction(status.Name),
+		}
+
+		events[moveKey] = KeyEvent{
+			Description: "Move to " + statusDisplayName(status.Name),
+			Action:      c.getMoveAction(status.Name),
+		}
 	}
+}
 
-	events[KeyShiftA] = KeyEvent{
-		Description: "Move to Abandoned",
-		Action:      c.getMoveAction(db.StatusAbandoned),
+// statusDisplayName returns the name of a status as shown in descriptions, e.g. "On Hold" for on_hold.
+func statusDisplayName(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
 	}
+
+	return strings.Join(words, " ")
 }
 
 func (c *Controller) initFormEvents(events map[tcell.Key]KeyEvent) {
@@ -133,6 +166,8 @@ func (c *Controller) initFormEvents(events map[tcell.Key]KeyEvent) {
 		Action: func(key *tcell.EventKey) *tcell.EventKey {
 			c.titleField.SetText("")
 			c.descField.SetText("")
+			c.dueField.SetText("")
+			c.repeatField.SetText("")
 
 			c.setSelectedTodo(-1, nil)
 			c.switchToForm()
@@ -152,6 +187,8 @@ func (c *Controller) initFormEvents(events map[tcell.Key]KeyEvent) {
 
 			c.titleField.SetText(c.selectedTodo.Title)
 			c.descField.SetText(c.selectedTodo.Description)
+			c.dueField.SetText(db.FormatDueDate(c.selectedTodo.DueDate))
+			c.repeatField.SetText(db.FormatRecurrence(c.selectedTodo.Recurrence))
 
 			log.Debug().Msgf("about to edit todo '%s", c.selectedTodo.Title)
 
@@ -172,6 +209,8 @@ func (c *Controller) initFormEvents(events map[tcell.Key]KeyEvent) {
 
 			c.titleField.SetText(c.selectedTodo.Title)
 			c.descField.SetText(c.selectedTodo.Description)
+			c.dueField.SetText(db.FormatDueDate(c.selectedTodo.DueDate))
+			c.repeatField.SetText(db.FormatRecurrence(c.selectedTodo.Recurrence))
 
 			log.Debug().Msgf("about to duplicate todo '%s", c.selectedTodo.Title)
 
@@ -193,7 +232,7 @@ func (c *Controller) initLabelEvents(events map[tcell.Key]KeyEvent) {
 				return key
 			}
 
-			c.addLabel = true
+			c.labelFormAction = labelActionAdd
 			c.switchToLabelForm()
 
 			return key
@@ -209,7 +248,7 @@ func (c *Controller) initLabelEvents(events map[tcell.Key]KeyEvent) {
 				return key
 			}
 
-			c.addLabel = false
+			c.labelFormAction = labelActionRemove
 			c.switchToLabelForm()
 
 			return key
@@ -217,6 +256,139 @@ func (c *Controller) initLabelEvents(events map[tcell.Key]KeyEvent) {
 	}
 }
 
+func (c *Controller) initDeleteEvents(events map[tcell.Key]KeyEvent) {
+	events[KeyShiftX] = KeyEvent{
+		Description: "Delete Todo",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if c.selectedTodo == nil {
+				log.Debug().Msgf("cannot delete: c.selectedTodo is nil. selectedStatus: %p", c.selectedStatus)
+
+				return key
+			}
+
+			c.confirmDeleteTodo(c.selectedTodo)
+
+			return nil
+		},
+	}
+
+	events[KeyShiftW] = KeyEvent{
+		Description: "Delete Label",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.labelFormAction = labelActionDelete
+			c.switchToLabelForm()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initHistoryEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyShiftI] = KeyEvent{
+		Description: "View History",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if c.selectedTodo == nil {
+				log.Debug().Msgf("cannot show history: c.selectedTodo is nil. selectedStatus: %p", c.selectedStatus)
+
+				return key
+			}
+
+			c.switchToHistory()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initReviewEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyR] = KeyEvent{
+		Description: "Review Recently Done",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToReview()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initDueSoonEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyN] = KeyEvent{
+		Description: "Show Due Soon",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToDueSoon()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initSnoozeEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyShiftZ] = KeyEvent{
+		Description: "Snooze Todo",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if c.selectedTodo == nil {
+				log.Debug().Msgf("cannot snooze: c.selectedTodo is nil. selectedStatus: %p", c.selectedStatus)
+
+				return key
+			}
+
+			c.switchToSnooze()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initChecklistEvent(events map[tcell.Key]KeyEvent) {
+	events[tcell.KeyEnter] = KeyEvent{
+		Description: "View Checklist",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if c.selectedTodo == nil {
+				log.Debug().Msgf("cannot show checklist: c.selectedTodo is nil. selectedStatus: %p", c.selectedStatus)
+
+				return key
+			}
+
+			c.switchToChecklist()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initSearchEvent(events map[tcell.Key]KeyEvent) {
+	events[KeySlash] = KeyEvent{
+		Description: "Search",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToSearch()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initFilterEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyF] = KeyEvent{
+		Description: "Filter",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.focusFilterBar()
+
+			return nil
+		},
+	}
+}
+
+func (c *Controller) initSettingsEvent(events map[tcell.Key]KeyEvent) {
+	events[KeyShiftS] = KeyEvent{
+		Description: "Settings",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToSettings()
+
+			return nil
+		},
+	}
+}
+
 func (c *Controller) getRerankAction(direction string) func(key *tcell.EventKey) *tcell.EventKey {
 	return func(key *tcell.EventKey) *tcell.EventKey {
 		var moveFunc func(ctx context.Context, todo *db.Todo) error
@@ -232,6 +404,16 @@ func (c *Controller) getRerankAction(direction string) func(key *tcell.EventKey)
 			moveFunc = c.db.MoveToBottom
 		}
 
+		// in a filtered table, Todos move past the ones that are shown rather than the hidden ones in between
+		if content := c.statusContents[c.selectedStatus.Name]; content.filter.active() {
+			switch direction {
+			case "up":
+				moveFunc = c.moveAmongShown(content, -1)
+			case "down":
+				moveFunc = c.moveAmongShown(content, 1)
+			}
+		}
+
 		err := moveFunc(c.ctx, c.selectedTodo)
 		if err != nil {
 			c.setErrorText(fmt.Sprintf("error moving %s: %s", direction, err))
@@ -239,12 +421,46 @@ func (c *Controller) getRerankAction(direction string) func(key *tcell.EventKey)
 			return key
 		}
 
+		// the selectedTodo is a copy from before the move
+		c.refreshContents()
+
 		c.updateTableSelection(c.selectedStatus.Name, c.selectedTodo.Rank)
 
 		return key
 	}
 }
 
+// moveAmongShown returns a function that moves a Todo to the rank of the Todo shown offset rows away from it, in
+// place of MoveUp and MoveDown.
+func (c *Controller) moveAmongShown(content *StatusContent, offset int) func(context.Context, *db.Todo) error {
+	return func(ctx context.Context, todo *db.Todo) error {
+		if todo == nil {
+			return db.ErrNilTodo
+		}
+
+		todos := content.todos()
+
+		idx := len(todos)
+
+		for i, shown := range todos {
+			if shown.ID() == todo.ID() {
+				idx = i + offset
+
+				break
+			}
+		}
+
+		switch {
+		case idx < 0:
+			return db.ErrCantMoveFirstTodoUp
+		case idx >= len(todos):
+			return db.ErrCantMoveLastTodoDown
+		}
+
+		return c.db.MoveToRank(ctx, todo, todos[idx].Rank)
+	}
+}
+
 func (c *Controller) initRerankEvents(events map[tcell.Key]KeyEvent) {
 	events[KeyShiftK] = KeyEvent{
 		Description: "Shift Up",
@@ -267,6 +483,38 @@ func (c *Controller) initRerankEvents(events map[tcell.Key]KeyEvent) {
 	}
 }
 
+func (c *Controller) initUndoEvents(events map[tcell.Key]KeyEvent) {
+	events[KeyU] = KeyEvent{
+		Description: "Undo",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if err := c.db.Undo(c.ctx); err != nil {
+				c.setErrorText(err.Error())
+
+				return key
+			}
+
+			c.showStatus(c.selectedStatus.Name)
+
+			return key
+		},
+	}
+
+	events[tcell.KeyCtrlR] = KeyEvent{
+		Description: "Redo",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			if err := c.db.Redo(c.ctx); err != nil {
+				c.setErrorText(err.Error())
+
+				return key
+			}
+
+			c.showStatus(c.selectedStatus.Name)
+
+			return key
+		},
+	}
+}
+
 func (c *Controller) initExitEvent(events map[tcell.Key]KeyEvent) {
 	events[KeyQ] = KeyEvent{
 		Description: "Exit",
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+)
+
+// todoFilter narrows the Todos shown on a status page. It is parsed from text like "+work -someday report": words
+// starting with + or - name labels that Todos must or must not have, and every other word must appear in the title or
+// description.
+type todoFilter struct {
+	include []string
+	exclude []string
+	words   []string
+}
+
+// parseFilter parses the text typed in the filter bar. Matching ignores case.
+func parseFilter(text string) *todoFilter {
+	filter := &todoFilter{}
+
+	for _, field := range strings.Fields(text) {
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			filter.include = append(filter.include, field[1:])
+		case len(field) > 1 && field[0] == '-':
+			filter.exclude = append(filter.exclude, field[1:])
+		default:
+			filter.words = append(filter.words, strings.ToLower(field))
+		}
+	}
+
+	return filter
+}
+
+// active reports whether the filter hides anything; a nil filter is inactive.
+func (f *todoFilter) active() bool {
+	return f != nil && len(f.include)+len(f.exclude)+len(f.words) > 0
+}
+
+func (f *todoFilter) String() string {
+	if f == nil {
+		return ""
+	}
+
+	fields := make([]string, 0, len(f.include)+len(f.exclude)+len(f.words))
+
+	for _, name := range f.include {
+		fields = append(fields, "+"+name)
+	}
+
+	for _, name := range f.exclude {
+		fields = append(fields, "-"+name)
+	}
+
+	return strings.Join(append(fields, f.words...), " ")
+}
+
+func hasLabel(todo *db.Todo, name string) bool {
+	for _, label := range todo.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether the Todo passes the filter.
+func (f *todoFilter) matches(todo *db.Todo) bool {
+	for _, name := range f.include {
+		if !hasLabel(todo, name) {
+			return false
+		}
+	}
+
+	for _, name := range f.exclude {
+		if hasLabel(todo, name) {
+			return false
+		}
+	}
+
+	text := strings.ToLower(todo.Title + "\n" + todo.Description)
+
+	for _, word := range f.words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// initFilterBar creates the filter bar shared by the status pages. The current status is filtered as the user types;
+// Enter keeps the filter and Esc restores the one from before the filter bar got focus.
+func (c *Controller) initFilterBar() {
+	c.filterInput = tview.NewInputField().SetLabel("Filter: ").SetPlaceholder("+label -label text")
+
+	c.filterInput.SetChangedFunc(func(text string) {
+		// showStatus sets the text of the filter bar too, which mustn't reset the selection
+		if !c.filterInput.HasFocus() {
+			return
+		}
+
+		status := c.selectedStatus.Name
+
+		c.statusContents[status].setFilter(parseFilter(text))
+		c.statusTables[status].Select(1, 0).ScrollToBeginning()
+		c.statusHeaders[status].SetCell(0, 0, tview.NewTableCell(c.statusTitle(status)))
+	})
+
+	c.filterInput.SetDoneFunc(func(key tcell.Key) {
+		status := c.selectedStatus.Name
+
+		if key == tcell.KeyEscape {
+			c.statusContents[status].setFilter(c.previousFilter)
+		}
+
+		c.app.SetFocus(c.statusTables[status])
+		c.showStatus(status)
+	})
+}
+
+// focusFilterBar moves the focus to the filter bar, remembering the filter of the current status so that Esc can
+// restore it.
+func (c *Controller) focusFilterBar() {
+	c.previousFilter = c.statusContents[c.selectedStatus.Name].filter
+
+	c.app.SetFocus(c.filterInput)
+}
@@ -9,6 +9,15 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// labelAction identifies what the label form does with the selected Label.
+type labelAction int
+
+const (
+	labelActionAdd labelAction = iota
+	labelActionRemove
+	labelActionDelete
+)
+
 func (c *Controller) switchToForm() {
 	title := "New Todo"
 	if c.selectedTodo != nil {
@@ -27,9 +36,15 @@ func (c *Controller) switchToForm() {
 }
 
 func (c *Controller) switchToLabelForm() {
-	title := "Add Label"
-	if !c.addLabel {
+	var title string
+
+	switch c.labelFormAction {
+	case labelActionAdd:
+		title = "Add Label"
+	case labelActionRemove:
 		title = "Remove Label"
+	case labelActionDelete:
+		title = "Delete Label"
 	}
 
 	name := "labelForm"
@@ -80,10 +95,15 @@ func (c *Controller) setFormTitle(tableName, title string) {
 }
 
 func (c *Controller) initFormHeader(name string) {
+	c.initEventsHeader(name, c.formEvents)
+}
+
+// initEventsHeader creates the header table for a page, listing the keyboard shortcuts in events.
+func (c *Controller) initEventsHeader(name string, events map[tcell.Key]KeyEvent) {
 	c.formHeaderTables[name] = tview.NewTable().SetBorders(false).SetSelectable(false, false)
 	row := 1
 
-	for key, event := range c.formEvents {
+	for key, event := range events {
 		text := fmt.Sprintf("[orange]<%s>[white] %s", tcell.KeyNames[key], event.Description)
 		c.formHeaderTables[name].SetCell(row, 0, tview.NewTableCell(text))
 		row++
@@ -93,17 +113,39 @@ func (c *Controller) initFormHeader(name string) {
 func (c *Controller) initForm() {
 	titleMax := 50
 	descriptionMax := 500
+	dueMax := len(db.DueDateFormat)
+	repeatMax := 30
 
 	c.todoForm = tview.NewForm().
 		AddInputField("Title", "", titleMax, nil, nil).
-		AddInputField("Description", "", descriptionMax, nil, nil)
+		AddInputField("Description", "", descriptionMax, nil, nil).
+		AddInputField("Due (YYYY-MM-DD)", "", dueMax, nil, nil).
+		AddInputField("Repeat", "", repeatMax, nil, nil)
 
 	c.titleField, _ = c.todoForm.GetFormItemByLabel("Title").(*tview.InputField)
 	c.descField, _ = c.todoForm.GetFormItemByLabel("Description").(*tview.InputField)
+	c.dueField, _ = c.todoForm.GetFormItemByLabel("Due (YYYY-MM-DD)").(*tview.InputField)
+	c.repeatField, _ = c.todoForm.GetFormItemByLabel("Repeat").(*tview.InputField)
+	c.repeatField.SetPlaceholder("daily, weekly mon,thu, monthly 15 or every 3 days")
 	c.todoForm.AddButton("Save", func() {
 		var err error
 		var todo *db.Todo
 
+		// check the due date and recurrence first so that a typo doesn't leave a half-saved todo behind
+		due, err := db.ParseDueDate(c.dueField.GetText())
+		if err != nil {
+			c.setErrorText(err.Error())
+
+			return
+		}
+
+		recurrence, err := db.ParseRecurrence(c.repeatField.GetText())
+		if err != nil {
+			c.setErrorText(err.Error())
+
+			return
+		}
+
 		log.Debug().Msgf("saving todo with title '%s'. c.selectedTodo: %p", c.titleField.GetText(), c.selectedTodo)
 		if c.selectedTodo == nil {
 			todo, err = c.db.NewTodo(c.ctx, c.titleField.GetText(), c.descField.GetText())
@@ -116,8 +158,27 @@ func (c *Controller) initForm() {
 			return
 		}
 
+		saved := todo
+		if saved == nil {
+			saved = c.selectedTodo
+		}
+
+		if err = c.db.SetDueDate(c.ctx, saved, due); err != nil {
+			c.setErrorText(fmt.Sprintf("error saving the due date: %s", err))
+
+			return
+		}
+
+		if err = c.db.SetRecurrence(c.ctx, saved, recurrence); err != nil {
+			c.setErrorText(fmt.Sprintf("error saving the recurrence: %s", err))
+
+			return
+		}
+
 		c.titleField.SetText("")
 		c.descField.SetText("")
+		c.dueField.SetText("")
+		c.repeatField.SetText("")
 
 		var rank int
 		// if we don't know where we came from or we created a new todo, then go to open
@@ -138,7 +199,13 @@ func (c *Controller) initForm() {
 func (c *Controller) updateLabelFormOptions() {
 	options := []string{}
 
-	for _, label := range c.db.Labels {
+	for _, label := range c.db.AllLabels() {
+		if c.labelFormAction == labelActionDelete {
+			options = append(options, label.Name)
+
+			continue
+		}
+
 		found := false
 
 		for _, todoLabel := range c.selectedTodo.Labels {
@@ -149,7 +216,7 @@ func (c *Controller) updateLabelFormOptions() {
 			}
 		}
 
-		if (found && !c.addLabel) || (!found && c.addLabel) {
+		if (found && c.labelFormAction == labelActionRemove) || (!found && c.labelFormAction == labelActionAdd) {
 			options = append(options, label.Name)
 		}
 	}
@@ -161,7 +228,7 @@ func (c *Controller) updateLabelFormOptions() {
 func (c *Controller) getSelectedLabel() *db.Label {
 	_, name := c.labelDropDown.GetCurrentOption()
 
-	for _, label := range c.db.Labels {
+	for _, label := range c.db.AllLabels() {
 		if label.Name == name {
 			return label
 		}
@@ -180,17 +247,25 @@ func (c *Controller) initLabelForm() {
 
 	c.labelForm.AddButton("Save", func() {
 		label := c.getSelectedLabel()
+		if label == nil {
+			return
+		}
 
-		if c.addLabel {
+		switch c.labelFormAction {
+		case labelActionAdd:
 			log.Debug().Msgf("adding label '%s' to todo '%s'", label.Name, c.selectedTodo.Title)
 			if err := c.db.AddTodoLabel(c.ctx, c.selectedTodo, label); err != nil {
 				c.setErrorText(fmt.Sprintf("error adding label: %s", err))
 			}
-		} else {
+		case labelActionRemove:
 			log.Debug().Msgf("removing label '%s' to todo '%s'", label.Name, c.selectedTodo.Title)
 			if err := c.db.RemoveTodoLabel(c.ctx, c.selectedTodo, label); err != nil {
 				c.setErrorText(fmt.Sprintf("error removing label: %s", err))
 			}
+		case labelActionDelete:
+			c.confirmDeleteLabel(label, c.showSelectedStatus)
+
+			return
 		}
 
 		c.showStatus(c.selectedStatus.Name)
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+)
+
+const historyTimeFormat = "2006-01-02 15:04"
+
+func (c *Controller) getHistoryGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "history"
+
+	c.initFormHeader(name)
+
+	c.historyTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.historyTable, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToHistory loads the history of the selectedTodo into the history table and shows it.
+func (c *Controller) switchToHistory() {
+	events, err := c.db.History(c.ctx, c.selectedTodo)
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error loading history: %s", err))
+
+		return
+	}
+
+	name := "history"
+
+	c.setFormTitle(name, fmt.Sprintf("History: #%d %s", c.selectedTodo.ID(), c.selectedTodo.Title))
+
+	c.historyTable.Clear()
+
+	for col, header := range []string{"when", "event", "details"} {
+		c.historyTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	for idx, event := range events {
+		row := idx + 1
+
+		c.historyTable.SetCell(row, 0, tview.NewTableCell(event.Datetime.Local().Format(historyTimeFormat)))
+		c.historyTable.SetCell(row, 1, tview.NewTableCell(string(event.Type)))
+		c.historyTable.SetCell(row, 2, tview.NewTableCell(describeEvent(event)).SetExpansion(1))
+	}
+
+	c.historyTable.Select(len(events), 0).ScrollToEnd()
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleFormKeys)
+}
+
+func statusName(status *db.Status) string {
+	if status == nil {
+		return "?"
+	}
+
+	return status.Name
+}
+
+// describeEvent summarizes the change recorded by a TodoEvent.
+func describeEvent(event *db.TodoEvent) string {
+	switch event.Type {
+	case db.EventCreated:
+		return fmt.Sprintf("created '%s' in %s", event.NewTitle, statusName(event.NewStatus))
+	case db.EventEdited:
+		desc := ""
+		if event.OldTitle != event.NewTitle {
+			desc = fmt.Sprintf("title '%s' -> '%s'", event.OldTitle, event.NewTitle)
+		}
+
+		if event.OldDescription != event.NewDescription {
+			if desc != "" {
+				desc += "; "
+			}
+
+			desc += fmt.Sprintf("description '%s' -> '%s'", event.OldDescription, event.NewDescription)
+		}
+
+		return desc
+	case db.EventStatusChanged:
+		return fmt.Sprintf("%s -> %s", statusName(event.OldStatus), statusName(event.NewStatus))
+	case db.EventReranked:
+		return fmt.Sprintf("rank %d -> %d", event.OldRank+1, event.NewRank+1)
+	case db.EventLabelAdded:
+		return fmt.Sprintf("added label %s", event.LabelName)
+	case db.EventLabelRemoved:
+		return fmt.Sprintf("removed label %s", event.LabelName)
+	case db.EventDeleted:
+		return fmt.Sprintf("deleted from %s", statusName(event.OldStatus))
+	case db.EventRestored:
+		return fmt.Sprintf("restored to %s", statusName(event.NewStatus))
+	case db.EventDueDateChanged:
+		return fmt.Sprintf("due '%s' -> '%s'", db.FormatDueDate(event.OldDueDate), db.FormatDueDate(event.NewDueDate))
+	case db.EventSnoozed:
+		return fmt.Sprintf("snoozed in %s until %s", statusName(event.NewStatus),
+			event.SnoozedUntil.Local().Format(historyTimeFormat))
+	case db.EventWoken:
+		return fmt.Sprintf("woke up and moved to the top of %s", statusName(event.NewStatus))
+	case db.EventRecurrenceChanged:
+		return fmt.Sprintf("repeat '%s' -> '%s'", event.OldRecurrence, event.NewRecurrence)
+	case db.EventChecklistChanged, db.EventDependencyAdded, db.EventDependencyRemoved:
+		return event.Detail
+	}
+
+	return ""
+}
@@ -0,0 +1,389 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) handleLabelKeys(evt *tcell.EventKey) *tcell.EventKey {
+	key := AsKey(evt)
+	if k, ok := c.labelEvents[key]; ok {
+		c.setErrorText("")
+
+		return k.Action(evt)
+	}
+
+	return evt
+}
+
+func (c *Controller) handleLabelFormKeys(evt *tcell.EventKey) *tcell.EventKey {
+	key := AsKey(evt)
+	if k, ok := c.labelFormEvents[key]; ok {
+		c.setErrorText("")
+
+		return k.Action(evt)
+	}
+
+	return evt
+}
+
+// initLabelPageEvents defines the actions available on the labels page and on the forms reached from it.
+func (c *Controller) initLabelPageEvents() {
+	c.labelEvents[KeyShiftN] = KeyEvent{
+		Description: "New Label",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToLabelEditForm(nil)
+
+			return nil
+		},
+	}
+
+	c.labelEvents[KeyShiftE] = KeyEvent{
+		Description: "Edit Label",
+		Action:      c.getSelectedLabelAction(c.switchToLabelEditForm),
+	}
+
+	c.labelEvents[KeyShiftM] = KeyEvent{
+		Description: "Merge Label",
+		Action:      c.getSelectedLabelAction(c.switchToLabelMergeForm),
+	}
+
+	c.labelEvents[KeyShiftX] = KeyEvent{
+		Description: "Delete Label",
+		Action: c.getSelectedLabelAction(func(label *db.Label) {
+			c.confirmDeleteLabel(label, c.switchToLabels)
+		}),
+	}
+
+	c.labelEvents[tcell.KeyEnter] = KeyEvent{
+		Description: "View Todos",
+		Action:      c.getSelectedLabelAction(c.switchToLabelTodos),
+	}
+
+	c.labelEvents[tcell.KeyEscape] = KeyEvent{
+		Description: "Back",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.showStatus(c.selectedStatus.Name)
+
+			return nil
+		},
+	}
+
+	c.labelFormEvents[tcell.KeyEscape] = KeyEvent{
+		Description: "Cancel",
+		Action: func(key *tcell.EventKey) *tcell.EventKey {
+			c.switchToLabels()
+
+			return nil
+		},
+	}
+}
+
+// getSelectedLabelAction returns an action that calls f with the Label selected in the labels table, if any.
+func (c *Controller) getSelectedLabelAction(f func(*db.Label)) func(key *tcell.EventKey) *tcell.EventKey {
+	return func(key *tcell.EventKey) *tcell.EventKey {
+		row, _ := c.labelsTable.GetSelection()
+
+		labels := c.db.AllLabels()
+		if row < 1 || row > len(labels) {
+			log.Debug().Msgf("cannot act on label: no label selected (row %d)", row)
+
+			return nil
+		}
+
+		f(labels[row-1])
+
+		return nil
+	}
+}
+
+func (c *Controller) getLabelsGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "labels"
+
+	c.initEventsHeader(name, c.labelEvents)
+	c.setFormTitle(name, "Labels")
+
+	c.labelsTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.labelsTable, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToLabels lists all labels with the number of todos that carry each one.
+func (c *Controller) switchToLabels() {
+	row, _ := c.labelsTable.GetSelection()
+
+	c.labelsTable.Clear()
+
+	for col, header := range []string{"label", "color", "todos"} {
+		c.labelsTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	labels := c.db.AllLabels()
+
+	for idx, label := range labels {
+		c.labelsTable.SetCell(idx+1, 0, tview.NewTableCell(fmt.Sprintf("[%s]%s", label.Color, label.Name)).
+			SetExpansion(1))
+		c.labelsTable.SetCell(idx+1, 1, tview.NewTableCell(fmt.Sprintf("[%s]%s", label.Color, label.Color)))
+		c.labelsTable.SetCell(idx+1, 2, tview.NewTableCell(fmt.Sprint(len(c.db.TodosWithLabel(label)))).
+			SetAlign(tview.AlignRight))
+	}
+
+	// keep the previous selection where possible, e.g. after editing a label
+	if count := len(labels); count > 0 {
+		if row < 1 {
+			row = 1
+		} else if row > count {
+			row = count
+		}
+
+		c.labelsTable.Select(row, 0)
+	}
+
+	c.pages.SwitchToPage(pageName("labels"))
+
+	c.app.SetInputCapture(c.handleLabelKeys)
+}
+
+func (c *Controller) getLabelEditGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "labelEdit"
+
+	c.initEventsHeader(name, c.labelFormEvents)
+
+	nameMax := 20
+
+	c.labelEditForm = tview.NewForm().
+		AddInputField("Name", "", nameMax, nil, nil).
+		AddDropDown("Color", []string{}, -1, nil)
+
+	c.labelNameField, _ = c.labelEditForm.GetFormItemByLabel("Name").(*tview.InputField)
+	c.labelColorDropDown, _ = c.labelEditForm.GetFormItemByLabel("Color").(*tview.DropDown)
+
+	c.labelEditForm.AddButton("Save", c.saveLabel)
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.labelEditForm, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToLabelEditForm shows the form to edit the given Label, or to create a new one if label is nil.
+func (c *Controller) switchToLabelEditForm(label *db.Label) {
+	c.editedLabel = label
+
+	name := "labelEdit"
+	title := "New Label"
+	color := ""
+
+	c.labelColors = db.LabelColors()
+
+	if label != nil {
+		title = fmt.Sprintf("Edit Label: %s", label.Name)
+		color = label.Color
+
+		c.labelNameField.SetText(label.Name)
+	} else {
+		c.labelNameField.SetText("")
+	}
+
+	selected := -1
+
+	for idx, option := range c.labelColors {
+		if option == color {
+			selected = idx
+		}
+	}
+
+	// keep colors chosen outside the palette
+	if selected < 0 && color != "" {
+		c.labelColors = append(c.labelColors, color)
+		selected = len(c.labelColors) - 1
+	}
+
+	options := make([]string, 0, len(c.labelColors))
+	for _, option := range c.labelColors {
+		options = append(options, fmt.Sprintf("[%s]%s", option, option))
+	}
+
+	c.labelColorDropDown.SetOptions(options, nil)
+	c.labelColorDropDown.SetCurrentOption(selected)
+
+	c.setFormTitle(name, title)
+
+	c.labelEditForm.SetFocus(0)
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleLabelFormKeys)
+}
+
+// saveLabel creates or updates the label from the values in the labelEditForm.
+func (c *Controller) saveLabel() {
+	var err error
+
+	name := c.labelNameField.GetText()
+	if name == "" {
+		c.setErrorText("label name cannot be empty")
+
+		return
+	}
+
+	label := c.editedLabel
+
+	if label == nil {
+		label, err = c.db.NewLabel(c.ctx, name)
+	} else if label.Name != name {
+		err = c.db.UpdateLabel(c.ctx, label, name)
+	}
+
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error saving label: %s", err))
+
+		return
+	}
+
+	if idx, _ := c.labelColorDropDown.GetCurrentOption(); idx >= 0 && c.labelColors[idx] != label.Color {
+		if err = c.db.SetLabelColor(c.ctx, label, c.labelColors[idx]); err != nil {
+			c.setErrorText(fmt.Sprintf("error saving label color: %s", err))
+
+			return
+		}
+	}
+
+	c.switchToLabels()
+}
+
+func (c *Controller) getLabelMergeGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "labelMerge"
+
+	c.initEventsHeader(name, c.labelFormEvents)
+
+	c.labelMergeForm = tview.NewForm().
+		AddDropDown("Merge into", []string{}, -1, nil)
+
+	c.labelMergeDropDown, _ = c.labelMergeForm.GetFormItemByLabel("Merge into").(*tview.DropDown)
+
+	c.labelMergeForm.AddButton("Merge", func() {
+		_, name := c.labelMergeDropDown.GetCurrentOption()
+
+		into, err := c.db.LabelByName(c.ctx, name)
+		if err != nil {
+			c.setErrorText(err.Error())
+
+			return
+		}
+
+		c.confirmMergeLabels(c.editedLabel, into)
+	})
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.labelMergeForm, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToLabelMergeForm shows the form to choose the label that the given Label is merged into.
+func (c *Controller) switchToLabelMergeForm(label *db.Label) {
+	c.editedLabel = label
+
+	name := "labelMerge"
+
+	options := []string{}
+
+	for _, other := range c.db.AllLabels() {
+		if other.ID != label.ID {
+			options = append(options, other.Name)
+		}
+	}
+
+	c.labelMergeDropDown.SetOptions(options, nil)
+	c.labelMergeDropDown.SetCurrentOption(-1)
+
+	c.setFormTitle(name, fmt.Sprintf("Merge Label: %s", label.Name))
+
+	c.labelMergeForm.SetFocus(0)
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleLabelFormKeys)
+}
+
+func (c *Controller) confirmMergeLabels(from, into *db.Label) {
+	count := len(c.db.TodosWithLabel(from))
+
+	c.confirm(
+		fmt.Sprintf("Merge label '%s' into '%s'? %d todo(s) will be relabelled.", from.Name, into.Name, count),
+		"Merge",
+		func() {
+			log.Debug().Msgf("merging label '%s' into '%s'", from.Name, into.Name)
+
+			if err := c.db.MergeLabels(c.ctx, from, into); err != nil {
+				c.setErrorText(fmt.Sprintf("error merging labels: %s", err))
+			}
+
+			c.switchToLabels()
+		},
+		c.switchToLabels,
+	)
+}
+
+func (c *Controller) getLabelTodosGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "labelTodos"
+
+	c.initEventsHeader(name, c.labelFormEvents)
+
+	c.labelTodosTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.labelTodosTable, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+// switchToLabelTodos lists the todos in every status that carry the given Label.
+func (c *Controller) switchToLabelTodos(label *db.Label) {
+	name := "labelTodos"
+
+	c.setFormTitle(name, fmt.Sprintf("Todos labelled [%s]%s", label.Color, label.Name))
+
+	c.labelTodosTable.Clear()
+
+	for col, header := range []string{"#", "status", "title", "description"} {
+		c.labelTodosTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	for idx, todo := range c.db.TodosWithLabel(label) {
+		row := idx + 1
+
+		c.labelTodosTable.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("#%d", todo.ID())).SetTextColor(tcell.ColorGray))
+		c.labelTodosTable.SetCell(row, 1, tview.NewTableCell(todo.Status.Name))
+		c.labelTodosTable.SetCell(row, 2, tview.NewTableCell(todo.Title).SetExpansion(1))
+		c.labelTodosTable.SetCell(row, 3, tview.NewTableCell(todo.Description).SetExpansion(descTitleRatio))
+	}
+
+	c.labelTodosTable.Select(1, 0).ScrollToBeginning()
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleLabelFormKeys)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadInterval is how often the database is checked for changes made by other processes.
+const reloadInterval = time.Second
+
+// reloadPeriodically reloads the database whenever another process, like a script or a second tt, changes it, so
+// that the tables don't show stale todos and changes aren't made based on them.
+func (c *Controller) reloadPeriodically() {
+	ticker := time.NewTicker(reloadInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				// the UI owns the copies of the model that it shows, so reload it on the UI goroutine
+				c.app.QueueUpdateDraw(c.reloadIfChanged)
+			}
+		}
+	}()
+}
+
+// reloadIfChanged reloads the database if it has changed and points the status tables, the selectedStatus and the
+// selectedTodo at the reloaded model. The same Todo stays selected if it's still in the same status.
+func (c *Controller) reloadIfChanged() {
+	reloaded, err := c.db.ReloadIfChanged(c.ctx)
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error reloading changes: %s", err))
+
+		return
+	}
+
+	if !reloaded {
+		return
+	}
+
+	log.Info().Msg("the database was changed by another process; reloaded")
+
+	// the selectedTodo is nil if another process deleted it
+	c.refreshContents()
+
+	for name, content := range c.statusContents {
+		if content.status != nil {
+			c.statusHeaders[name].SetCell(0, 0, tview.NewTableCell(c.statusTitle(name)))
+		}
+	}
+
+	for _, status := range c.db.OrderedStatuses() {
+		if _, ok := c.statusContents[status.Name]; !ok {
+			log.Info().Msgf("status %s was added by another process; restart to see it", status.Name)
+		}
+	}
+
+	if c.status(c.selectedStatus.Name) == nil {
+		c.selectedStatus = c.status(db.StatusClosed)
+	}
+
+	// forms keep acting on the selectedTodo, but on its status page the selection follows the table
+	if page, _ := c.pages.GetFrontPage(); page != statusPageName(c.selectedStatus.Name) {
+		return
+	}
+
+	if c.selectedTodo != nil && c.selectedTodo.Status.Name == c.selectedStatus.Name {
+		c.updateTableSelection(c.selectedStatus.Name, c.selectedTodo.Rank)
+
+		return
+	}
+
+	row, _ := c.statusTables[c.selectedStatus.Name].GetSelection()
+	c.setSelectedTodo(row, c.getTodoForRow(row))
+}
+
+// labelByID returns the Label with the given id, or nil if there is none.
+func labelByID(labels []*db.Label, id int) *db.Label {
+	for _, label := range labels {
+		if label.ID == id {
+			return label
+		}
+	}
+
+	return nil
+}
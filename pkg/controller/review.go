@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+)
+
+const (
+	reviewDateFormat     = "2006-01-02"
+	reviewDateTimeFormat = "Mon 2006-01-02 15:04"
+	reviewCustom         = "custom"
+)
+
+var errInvalidReviewRange = errors.New("the end of the range must not be before the start")
+
+// reviewWindows lists the options for the review page, in display order.
+func reviewWindows() []string {
+	return []string{"today", "yesterday", "this week", "last week", reviewCustom}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// reviewRange returns the [from, to) range for the given window relative to now. Weeks start on Monday. The custom
+// window covers the whole days from fromText through toText.
+func reviewRange(window string, now time.Time, fromText, toText string) (time.Time, time.Time, error) {
+	today := startOfDay(now)
+	// time.Weekday starts on Sunday
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+
+	switch window {
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	case "this week":
+		return weekStart, weekStart.AddDate(0, 0, 7), nil
+	case "last week":
+		return weekStart.AddDate(0, 0, -7), weekStart, nil
+	}
+
+	from, err := time.ParseInLocation(reviewDateFormat, fromText, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date '%s': %w", fromText, err)
+	}
+
+	to, err := time.ParseInLocation(reviewDateFormat, toText, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date '%s': %w", toText, err)
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errInvalidReviewRange
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func (c *Controller) getReviewGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "review"
+
+	c.initFormHeader(name)
+	c.setFormTitle(name, "Recently Done")
+	c.initReviewForm()
+
+	c.reviewTable = tview.NewTable().SetBorders(false).SetSelectable(false, false).SetFixed(1, 0)
+
+	formRows := 2
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.reviewForm, headerRows+2, 0, formRows, 1, 0, 0, true)
+	grid.AddItem(c.reviewTable, headerRows+2+formRows, 0, headerRows*2-formRows, 1, 0, 0, false)
+
+	return grid
+}
+
+func (c *Controller) initReviewForm() {
+	dateFieldWidth := 12
+	today := time.Now().Format(reviewDateFormat)
+
+	c.reviewForm = tview.NewForm().SetHorizontal(true).
+		AddDropDown("Window", reviewWindows(), 0, func(string, int) { c.updateReview() }).
+		AddInputField("From", today, dateFieldWidth, nil, func(string) { c.updateReview() }).
+		AddInputField("To", today, dateFieldWidth, nil, func(string) { c.updateReview() }).
+		AddCheckbox("Include abandoned", false, func(bool) { c.updateReview() })
+
+	c.reviewWindowDropDown, _ = c.reviewForm.GetFormItemByLabel("Window").(*tview.DropDown)
+	c.reviewFromField, _ = c.reviewForm.GetFormItemByLabel("From").(*tview.InputField)
+	c.reviewToField, _ = c.reviewForm.GetFormItemByLabel("To").(*tview.InputField)
+	c.reviewAbandonedBox, _ = c.reviewForm.GetFormItemByLabel("Include abandoned").(*tview.Checkbox)
+}
+
+// updateReview reloads the review table for the currently selected window.
+func (c *Controller) updateReview() {
+	// the form calls its change handlers while it is being built
+	if c.reviewTable == nil || c.reviewAbandonedBox == nil {
+		return
+	}
+
+	_, window := c.reviewWindowDropDown.GetCurrentOption()
+
+	from, to, err := reviewRange(window, time.Now(), c.reviewFromField.GetText(), c.reviewToField.GetText())
+	if err != nil {
+		if window == reviewCustom {
+			c.setErrorText(err.Error())
+		}
+
+		return
+	}
+
+	c.setErrorText("")
+
+	completed, err := c.db.TodosCompletedBetween(c.ctx, from, to)
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error loading completed todos: %s", err))
+
+		return
+	}
+
+	if c.reviewAbandonedBox.IsChecked() {
+		abandoned, err := c.db.TodosAbandonedBetween(c.ctx, from, to)
+		if err != nil {
+			c.setErrorText(fmt.Sprintf("error loading abandoned todos: %s", err))
+
+			return
+		}
+
+		completed = mergeCompleted(completed, abandoned)
+	}
+
+	c.setReviewRows(completed)
+}
+
+// mergeCompleted merges two lists of CompletedTodos that are each sorted most recent first.
+func mergeCompleted(a, b []*db.CompletedTodo) []*db.CompletedTodo {
+	merged := make([]*db.CompletedTodo, 0, len(a)+len(b))
+
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].CompletedDatetime.After(a[0].CompletedDatetime) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+
+	merged = append(merged, a...)
+
+	return append(merged, b...)
+}
+
+func (c *Controller) setReviewRows(completed []*db.CompletedTodo) {
+	c.reviewTable.Clear()
+
+	for col, header := range []string{"completed", "status", "title", "description"} {
+		c.reviewTable.SetCell(0, col, tview.NewTableCell(header).SetTextColor(tcell.ColorYellow))
+	}
+
+	for idx, entry := range completed {
+		row := idx + 1
+
+		c.reviewTable.SetCell(row, 0, tview.NewTableCell(entry.CompletedDatetime.Local().Format(reviewDateTimeFormat)))
+		c.reviewTable.SetCell(row, 1, tview.NewTableCell(entry.Todo.Status.Name))
+		c.reviewTable.SetCell(row, 2, tview.NewTableCell(entry.Todo.Title).SetExpansion(1))
+		c.reviewTable.SetCell(row, 3, tview.NewTableCell(entry.Todo.Description).SetExpansion(descTitleRatio))
+	}
+}
+
+func (c *Controller) switchToReview() {
+	name := "review"
+
+	c.updateReview()
+
+	c.reviewForm.SetFocus(0)
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.app.SetInputCapture(c.handleFormKeys)
+}
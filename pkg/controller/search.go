@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+)
+
+// getSearchOverlay returns a box in the middle of the screen with a search field above the results, which is shown
+// on top of the current page.
+func (c *Controller) getSearchOverlay() tview.Primitive {
+	c.searchInput = tview.NewInputField().SetLabel("Search: ")
+	c.searchInput.SetChangedFunc(c.updateSearchResults)
+
+	// the field keeps the focus, so the arrow keys move through the results while typing
+	c.searchInput.SetInputCapture(func(evt *tcell.EventKey) *tcell.EventKey {
+		switch evt.Key() {
+		case tcell.KeyDown:
+			c.moveSearchSelection(1)
+
+			return nil
+		case tcell.KeyUp:
+			c.moveSearchSelection(-1)
+
+			return nil
+		}
+
+		return evt
+	})
+
+	c.searchInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			row, _ := c.searchTable.GetSelection()
+			if todo, ok := c.searchTable.GetCell(row, 0).GetReference().(*db.Todo); ok {
+				c.showTodo(todo)
+			}
+		case tcell.KeyEscape:
+			c.pages.HidePage(pageName("search"))
+			c.showSelectedStatus()
+		}
+	})
+
+	c.searchTable = tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0)
+
+	box := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(c.searchInput, 1, 0, true).
+		AddItem(c.searchTable, 0, 1, false)
+	box.SetBorder(true).SetTitle(" Search (↑/↓ to choose, Enter to go to a todo, Esc to close) ")
+
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(box, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// switchToSearch shows the search overlay with an empty query.
+func (c *Controller) switchToSearch() {
+	c.searchInput.SetText("")
+	c.updateSearchResults("")
+
+	c.pages.ShowPage(pageName("search"))
+
+	c.app.SetFocus(c.searchInput)
+
+	// the search field needs every key
+	c.app.SetInputCapture(nil)
+}
+
+// updateSearchResults lists the todos that match the query, best matches first.
+func (c *Controller) updateSearchResults(query string) {
+	results, err := c.db.Search(c.ctx, query)
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error searching: %s", err))
+
+		return
+	}
+
+	c.searchTable.Clear()
+
+	for col, header := range []string{"#", "status", "title", "labels"} {
+		c.searchTable.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	for idx, todo := range results {
+		row := idx + 1
+
+		c.searchTable.SetCell(row, 0,
+			tview.NewTableCell(fmt.Sprintf("#%d", todo.ID())).SetTextColor(tcell.ColorGray).SetReference(todo))
+		c.searchTable.SetCell(row, 1, tview.NewTableCell(todo.Status.Name))
+		c.searchTable.SetCell(row, 2, titleCell(todo).SetExpansion(1))
+		c.searchTable.SetCell(row, 3, tview.NewTableCell(labelNames(todo)).SetExpansion(1))
+	}
+
+	c.searchTable.Select(1, 0).ScrollToBeginning()
+}
+
+// moveSearchSelection selects the result offset rows below the current one, staying within the results.
+func (c *Controller) moveSearchSelection(offset int) {
+	row, _ := c.searchTable.GetSelection()
+
+	row += offset
+	if row < 1 || row >= c.searchTable.GetRowCount() {
+		return
+	}
+
+	c.searchTable.Select(row, 0)
+}
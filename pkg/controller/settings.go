@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rivo/tview"
+)
+
+func (c *Controller) getSettingsGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "settings"
+
+	c.initFormHeader(name)
+	c.setFormTitle(name, "Settings")
+
+	limitWidth := 4
+
+	c.settingsForm = tview.NewForm().
+		AddInputField("Max closed todos", "", limitWidth, tview.InputFieldInteger, nil)
+
+	c.maxClosedTodosField, _ = c.settingsForm.GetFormItemByLabel("Max closed todos").(*tview.InputField)
+
+	c.settingsForm.AddButton("Save", func() {
+		limit, err := strconv.Atoi(c.maxClosedTodosField.GetText())
+		if err != nil {
+			c.setErrorText(fmt.Sprintf("max closed todos must be a number: %s", err))
+
+			return
+		}
+
+		if err = c.db.SetMaxClosedTodos(c.ctx, limit); err != nil {
+			c.setErrorText(fmt.Sprintf("error saving settings: %s", err))
+
+			return
+		}
+
+		c.showStatus(c.selectedStatus.Name)
+	})
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.settingsForm, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+func (c *Controller) switchToSettings() {
+	name := "settings"
+
+	c.maxClosedTodosField.SetText(strconv.Itoa(c.db.MaxClosedTodos()))
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.settingsForm.SetFocus(0)
+
+	c.app.SetInputCapture(c.handleFormKeys)
+
+	if c.db.MaxClosedTodosOverridden() {
+		c.setErrorText("max closed todos is set by TT_MAX_CLOSED_TODOS; saved changes apply once it is unset")
+	}
+}
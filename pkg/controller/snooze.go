@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
+)
+
+// wakeInterval is how often snoozed todos are checked while the app is running.
+const wakeInterval = time.Minute
+
+func (c *Controller) getSnoozeGrid() *tview.Grid {
+	grid := tview.NewGrid().SetBorders(true)
+
+	name := "snooze"
+
+	c.initFormHeader(name)
+
+	untilWidth := 20
+
+	c.snoozeForm = tview.NewForm().
+		AddInputField("Until", "", untilWidth, nil, nil)
+
+	c.snoozeUntilField, _ = c.snoozeForm.GetFormItemByLabel("Until").(*tview.InputField)
+	c.snoozeUntilField.SetPlaceholder("2d, tomorrow, next monday")
+
+	c.snoozeForm.AddButton("Snooze", func() {
+		until, err := db.ParseSnoozeUntil(c.snoozeUntilField.GetText(), time.Now())
+		if err != nil {
+			c.setErrorText(err.Error())
+
+			return
+		}
+
+		if err = c.db.Snooze(c.ctx, c.selectedTodo, until); err != nil {
+			c.setErrorText(fmt.Sprintf("error snoozing todo: %s", err))
+
+			return
+		}
+
+		c.showSelectedStatus()
+	})
+
+	grid.AddItem(c.formHeaderTables[name], 0, 0, headerRows, 1, 0, 0, false)
+	grid.AddItem(c.errorText, headerRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.snoozeForm, headerRows+2, 0, headerRows*2, 1, 0, 0, true)
+
+	return grid
+}
+
+func (c *Controller) switchToSnooze() {
+	name := "snooze"
+
+	c.setFormTitle(name, fmt.Sprintf("Snooze: #%d %s", c.selectedTodo.ID(), c.selectedTodo.Title))
+
+	c.snoozeUntilField.SetText("")
+
+	c.pages.SwitchToPage(pageName(name))
+
+	c.snoozeForm.SetFocus(0)
+
+	c.app.SetInputCapture(c.handleFormKeys)
+}
+
+// wakeSnoozedPeriodically returns snoozed todos to the open list once their time is up, for as long as the app runs.
+func (c *Controller) wakeSnoozedPeriodically() {
+	ticker := time.NewTicker(wakeInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				// the UI owns the copies of the model that it shows, so wake todos on the UI goroutine
+				c.app.QueueUpdateDraw(c.wakeSnoozed)
+			}
+		}
+	}()
+}
+
+func (c *Controller) wakeSnoozed() {
+	woken, err := c.db.WakeSnoozed(c.ctx)
+	if err != nil {
+		c.setErrorText(fmt.Sprintf("error waking snoozed todos: %s", err))
+
+		return
+	}
+
+	if len(woken) > 0 {
+		log.Info().Msgf("%d snoozed todo(s) woke up", len(woken))
+
+		c.statusHeaders[db.StatusOpen].SetCell(0, 0, tview.NewTableCell(c.statusTitle(db.StatusOpen)))
+		c.statusHeaders[db.StatusOnHold].SetCell(0, 0, tview.NewTableCell(c.statusTitle(db.StatusOnHold)))
+	}
+}
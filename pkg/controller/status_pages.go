@@ -12,13 +12,15 @@ import (
 
 func (c *Controller) getStatusGrid(status string) *tview.Grid {
 	header := c.getStatusHeader(status)
+	c.statusHeaders[status] = header
 	c.statusTables[status] = c.getTable(status)
 
 	grid := tview.NewGrid().SetBorders(true)
 
 	grid.AddItem(header, 0, 0, headerRows, 1, 0, 0, false)
 	grid.AddItem(c.errorText, headerRows+1, 0, errorTextRows, 1, 0, 0, false)
-	grid.AddItem(c.statusTables[status], headerRows+errorTextRows+1, 0, headerRows*2, 1, 0, 0, true)
+	grid.AddItem(c.filterInput, headerRows+errorTextRows+1, 0, 1, 1, 0, 0, false)
+	grid.AddItem(c.statusTables[status], headerRows+errorTextRows+2, 0, headerRows*2, 1, 0, 0, true)
 
 	return grid
 }
@@ -31,7 +33,7 @@ func (c *Controller) getStatusHeader(status string) *tview.Table {
 	table := tview.NewTable().SetBorders(false).SetSelectable(false, false)
 
 	row := 0
-	table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("[yellow]%s", status)))
+	table.SetCell(row, 0, tview.NewTableCell(c.statusTitle(status)))
 	row++
 
 	shortcuts := map[int][]string{
@@ -70,10 +72,69 @@ func (c *Controller) getStatusHeader(status string) *tview.Table {
 	return table
 }
 
+// statusTitle returns the title shown in the header of a status page, in the color of the status. Statuses with a
+// limit also show how full they are, and filtered statuses show the filter and how many Todos pass it.
+func (c *Controller) statusTitle(status string) string {
+	s := c.status(status)
+	count := len(s.Todos)
+
+	title := fmt.Sprintf("[%s]%s", s.Color, status)
+
+	limit := c.db.StatusLimit(s)
+	if limit > 0 {
+		title += fmt.Sprintf(" (%d/%d)", count, limit)
+	}
+
+	if content := c.statusContents[status]; content != nil && content.filter.active() {
+		title += fmt.Sprintf(" [white]filter: %s (%d of %d shown)",
+			tview.Escape(content.filter.String()), len(content.todos()), count)
+	}
+
+	if limit > 0 && count > limit {
+		title += " [red]over the limit; move something out before adding more"
+	}
+
+	return title
+}
+
+// refreshContents points the status tables, the selectedStatus, the selectedTodo and the editedLabel at a new
+// Snapshot of the database and makes the tables filter their Todos again. The Store returns copies, so besides before
+// every draw, it's needed whenever the Todos may have changed and they are used right away, e.g. to select a Todo that
+// was just moved. The selectedTodo is nil if it was deleted.
+func (c *Controller) refreshContents() {
+	snapshot := c.db.Snapshot()
+
+	for name, content := range c.statusContents {
+		content.status = snapshot.Statuses[name]
+		content.refresh()
+	}
+
+	if c.selectedStatus != nil {
+		if status, ok := snapshot.Statuses[c.selectedStatus.Name]; ok {
+			c.selectedStatus = status
+		}
+	}
+
+	if c.selectedTodo != nil {
+		todo, err := snapshot.TodoByID(c.ctx, c.selectedTodo.ID())
+		if err != nil {
+			todo = nil
+		}
+
+		c.selectedTodo = todo
+	}
+
+	if c.editedLabel != nil {
+		c.editedLabel = labelByID(snapshot.Labels, c.editedLabel.ID)
+	}
+}
+
 func (c *Controller) getTodoForRow(row int) *db.Todo {
+	todos := c.statusContents[c.selectedStatus.Name].todos()
+
 	// adjust for the header row
-	if idx := row - 1; idx < len(c.selectedStatus.Todos) && idx >= 0 {
-		return c.selectedStatus.Todos[idx]
+	if idx := row - 1; idx < len(todos) && idx >= 0 {
+		return todos[idx]
 	}
 
 	return nil
@@ -88,8 +149,9 @@ func (c *Controller) getTable(status string) *tview.Table {
 	table := tview.NewTable().SetBorders(false)
 
 	statusContent := &StatusContent{
-		status: c.db.Statuses[status],
+		status: c.status(status),
 	}
+	c.statusContents[status] = statusContent
 
 	table.SetContent(statusContent)
 
@@ -104,14 +166,30 @@ func (c *Controller) getTable(status string) *tview.Table {
 	return table
 }
 
-// updateTableSelection updates the selection for the table matching the given status to keep it
-// in sync with recently taken actions, e.g. when moving a Todo up or down.
+// updateTableSelection selects the Todo with the given rank in the table matching the given status to keep it
+// in sync with recently taken actions, e.g. when moving a Todo up or down. If the filter hides that Todo, the next
+// visible one is selected instead.
 func (c *Controller) updateTableSelection(status string, rank int) {
-	if c.statusTables[status].GetRowCount() > rank {
-		c.statusTables[status].Select(rank+1, 0)
-	} else {
-		log.Warn().Msgf("couldn't select; rank was too high: %d (row count: %d)", rank, c.statusTables[status].GetRowCount())
+	c.refreshContents()
+
+	todos := c.statusContents[status].todos()
+	if len(todos) == 0 {
+		log.Warn().Msgf("couldn't select rank %d: no todos shown in %s", rank, status)
+
+		return
 	}
+
+	row := len(todos)
+
+	for idx, todo := range todos {
+		if todo.Rank >= rank {
+			row = idx + 1
+
+			break
+		}
+	}
+
+	c.statusTables[status].Select(row, 0)
 }
 
 func (c *Controller) setSelectedTodo(row int, todo *db.Todo) {
@@ -138,18 +216,25 @@ func (c *Controller) setSelectedTodo(row int, todo *db.Todo) {
 }
 
 func (c *Controller) showStatus(status string) {
-	c.selectedStatus = c.db.Statuses[status]
+	c.selectedStatus = c.status(status)
+
+	c.refreshContents()
+
+	c.statusHeaders[status].SetCell(0, 0, tview.NewTableCell(c.statusTitle(status)))
 
 	c.app.SetInputCapture(c.handleKeys)
 
+	c.filterInput.SetText(c.statusContents[status].filter.String())
+
 	row, _ := c.statusTables[status].GetSelection()
 
-	length := len(c.selectedStatus.Todos)
+	todos := c.statusContents[status].todos()
+	length := len(todos)
 
 	if length > row-1 && row-1 >= 0 {
-		c.setSelectedTodo(row, c.selectedStatus.Todos[row-1])
+		c.setSelectedTodo(row, todos[row-1])
 	} else if length > 0 {
-		c.setSelectedTodo(length, c.selectedStatus.Todos[length-1])
+		c.setSelectedTodo(length, todos[length-1])
 	} else {
 		c.setSelectedTodo(-1, nil)
 	}
@@ -158,5 +243,5 @@ func (c *Controller) showStatus(status string) {
 		c.updateTableSelection(c.selectedStatus.Name, c.selectedTodo.Rank)
 	}
 
-	c.pages.SwitchToPage(pageName(status))
+	c.pages.SwitchToPage(statusPageName(status))
 }
@@ -2,36 +2,126 @@ package controller
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/matt-steen/todo-tracker/pkg/db"
 	"github.com/rivo/tview"
 )
 
-// labelColors is a list of colors for labels to alternate through so that todos with common labels are easier to spot.
-func labelColors() []string {
-	return []string{
-		"#FF0000",
-		"#00FF00",
-		"#0000FF",
-		"#FFFF00",
-		"#FF00FF",
-		"#00FFFF",
-		"#FFFFFF",
-		"#AA0000",
-		"#00AA00",
-		"#0000AA",
-		"#AAAA00",
-		"#AA00AA",
-		"#00AAAA",
-		"#AAAAAA",
+// snoozeTimeFormat is used to show when snoozed todos wake up.
+const snoozeTimeFormat = "Mon 2006-01-02 15:04"
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 30 * day
+	year  = 365 * day
+)
+
+// humanizeAge describes how long before now the given time was, e.g. "3d ago".
+func humanizeAge(t *time.Time, now time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	age := now.Sub(*t)
+
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", age/time.Minute)
+	case age < day:
+		return fmt.Sprintf("%dh ago", age/time.Hour)
+	case age < week:
+		return fmt.Sprintf("%dd ago", age/day)
+	case age < month:
+		return fmt.Sprintf("%dw ago", age/week)
+	case age < year:
+		return fmt.Sprintf("%dmo ago", age/month)
+	}
+
+	return fmt.Sprintf("%dy ago", age/year)
+}
+
+// labelNames lists the Labels of the Todo, each in its own color.
+func labelNames(todo *db.Todo) string {
+	labels := ""
+
+	for _, label := range todo.Labels {
+		if len(labels) > 0 {
+			labels += ", "
+		}
+
+		labels += fmt.Sprintf("[%s]%s", label.Color, label.Name)
+	}
+
+	return labels
+}
+
+// titleCell returns a cell with the title of the Todo and the progress of its checklist. Blocked todos are marked,
+// snoozed todos show when they wake up, and todos that have just woken up are highlighted.
+func titleCell(todo *db.Todo) *tview.TableCell {
+	title := todo.Title + checklistProgress(todo)
+	if todo.Blocked() {
+		title = "⛔ " + title
 	}
+
+	switch {
+	case todo.Woken:
+		return tview.NewTableCell("⏰ " + title).SetTextColor(tcell.ColorOrange)
+	case todo.SnoozedUntil != nil:
+		return tview.NewTableCell(fmt.Sprintf("%s (until %s)", title,
+			todo.SnoozedUntil.Local().Format(snoozeTimeFormat)))
+	}
+
+	return tview.NewTableCell(title)
 }
 
 // StatusContent implements tview.TableContent, which tview.Table uses to update data.
 type StatusContent struct {
 	tview.TableContentReadOnly
 	status *db.Status
+	// filter narrows the Todos shown in the table; nil shows them all.
+	filter *todoFilter
+	// shown caches the Todos that pass an active filter, since the table asks for every cell; nil means that it must
+	// be rebuilt.
+	shown []*db.Todo
+}
+
+// setFilter replaces the filter of the table.
+func (s *StatusContent) setFilter(filter *todoFilter) {
+	s.filter = filter
+	s.shown = nil
+}
+
+// refresh makes the table filter the Todos of its status again, after they or the status itself have changed.
+func (s *StatusContent) refresh() {
+	s.shown = nil
+}
+
+// todos returns the Todos shown in the table, in rank order. Row r of the table shows todos()[r-1].
+func (s *StatusContent) todos() []*db.Todo {
+	if s.status == nil {
+		return nil
+	}
+
+	if !s.filter.active() {
+		return s.status.Todos
+	}
+
+	if s.shown == nil {
+		s.shown = []*db.Todo{}
+
+		for _, todo := range s.status.Todos {
+			if s.filter.matches(todo) {
+				s.shown = append(s.shown, todo)
+			}
+		}
+	}
+
+	return s.shown
 }
 
 // GetCell returns the cell at the given position or nil if no cell.
@@ -39,41 +129,51 @@ func (s *StatusContent) GetCell(row, col int) *tview.TableCell {
 	if row == 0 {
 		switch col {
 		case 0:
-			return tview.NewTableCell("title").SetExpansion(1).
+			return tview.NewTableCell("#").
 				SetTextColor(tcell.ColorYellow).SetSelectable(false)
 		case 1:
-			return tview.NewTableCell("description").SetExpansion(descTitleRatio).
+			return tview.NewTableCell("title").SetExpansion(1).
 				SetTextColor(tcell.ColorYellow).SetSelectable(false)
 		case 2:
+			return tview.NewTableCell("description").SetExpansion(descTitleRatio).
+				SetTextColor(tcell.ColorYellow).SetSelectable(false)
+		case 3:
 			return tview.NewTableCell("labels").SetExpansion(1).
 				SetTextColor(tcell.ColorYellow).SetSelectable(false)
+		case 4:
+			return tview.NewTableCell("due").
+				SetTextColor(tcell.ColorYellow).SetSelectable(false)
+		case 5:
+			return tview.NewTableCell("created").
+				SetTextColor(tcell.ColorYellow).SetSelectable(false)
+		case 6:
+			return tview.NewTableCell("updated").
+				SetTextColor(tcell.ColorYellow).SetSelectable(false)
 		}
 	}
 
-	if s.status == nil {
+	todos := s.todos()
+	if row > len(todos) {
 		return nil
 	}
 
-	todo := s.status.Todos[row-1]
+	todo := todos[row-1]
 
 	switch col {
 	case 0:
-		return tview.NewTableCell(todo.Title).SetExpansion(1).SetReference(todo)
+		return tview.NewTableCell(fmt.Sprintf("#%d", todo.ID())).SetTextColor(tcell.ColorGray)
 	case 1:
-		return tview.NewTableCell(todo.Description).SetExpansion(descTitleRatio)
+		return titleCell(todo).SetExpansion(1).SetReference(todo)
 	case 2:
-		labels := ""
-		for _, label := range todo.Labels {
-			if len(labels) > 0 {
-				labels += ", "
-			}
-
-			colors := labelColors()
-
-			labels += fmt.Sprintf("[%s]%s", colors[label.ID%len(colors)], label.Name)
-		}
-
-		return tview.NewTableCell(labels).SetExpansion(1)
+		return tview.NewTableCell(todo.Description).SetExpansion(descTitleRatio)
+	case 3:
+		return tview.NewTableCell(labelNames(todo)).SetExpansion(1)
+	case 4:
+		return dueCell(todo, time.Now())
+	case 5:
+		return tview.NewTableCell(humanizeAge(todo.CreatedDatetime, time.Now()))
+	case 6:
+		return tview.NewTableCell(humanizeAge(todo.UpdatedDatetime, time.Now()))
 	}
 
 	return nil
@@ -81,14 +181,10 @@ func (s *StatusContent) GetCell(row, col int) *tview.TableCell {
 
 // GetRowCount returns the number of rows in the table.
 func (s *StatusContent) GetRowCount() int {
-	if s.status != nil {
-		return len(s.status.Todos) + 1
-	}
-
-	return 1
+	return len(s.todos()) + 1
 }
 
 // GetColumnCount returns the number of columns in the table.
 func (s *StatusContent) GetColumnCount() int {
-	return 3
+	return 7
 }
@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEmptyChecklistItem is returned from AddChecklistItem when the text is empty.
+	ErrEmptyChecklistItem = errors.New("checklist items must have text")
+	// ErrChecklistItemNotFound is returned when a ChecklistItem doesn't belong to the given Todo.
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+)
+
+// ChecklistProgress returns the number of checked items and the total number of items in the Todo's checklist.
+func (t *Todo) ChecklistProgress() (int, int) {
+	done := 0
+
+	for _, item := range t.Checklist {
+		if item.Done {
+			done++
+		}
+	}
+
+	return done, len(t.Checklist)
+}
+
+func (d *Database) loadChecklists(ctx context.Context) error {
+	rows, err := d.conn.QueryContext(ctx, `SELECT id, todo_id, text, done FROM checklist_item ORDER BY todo_id, rank`)
+	if err != nil {
+		return fmt.Errorf("error loading checklist items: %w", err)
+	}
+
+	defer rows.Close()
+
+	todos := make(map[int]*Todo, len(d.todos))
+	for _, todo := range d.todos {
+		todos[todo.id] = todo
+	}
+
+	for rows.Next() {
+		var (
+			item   ChecklistItem
+			todoID int
+		)
+
+		if err = rows.Scan(&item.id, &todoID, &item.Text, &item.Done); err != nil {
+			return fmt.Errorf("error scanning checklist item: %w", err)
+		}
+
+		if todo, ok := todos[todoID]; ok {
+			todo.Checklist = append(todo.Checklist, &item)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error scanning checklist items: %w", err)
+	}
+
+	return nil
+}
+
+// checklistIndex returns the position of the ChecklistItem in the list, or -1 if it isn't there.
+func checklistIndex(items []*ChecklistItem, item *ChecklistItem) int {
+	for i, it := range items {
+		if it == item {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// persistChecklistOrder rewrites the ranks of the given items, which make up the whole checklist of a Todo.
+func persistChecklistOrder(ctx context.Context, txn *sql.Tx, items []*ChecklistItem) error {
+	for rank, item := range items {
+		if _, err := txn.ExecContext(ctx, `UPDATE checklist_item SET rank=$1 WHERE id=$2`, rank, item.id); err != nil {
+			return fmt.Errorf("error updating rank of checklist item '%s': %w", item.Text, err)
+		}
+	}
+
+	return nil
+}
+
+// insertChecklistItems adds the items to the checklist of the Todo in the given transaction, keeping the ids of
+// items that have one so that deleted items can be restored.
+func insertChecklistItems(ctx context.Context, txn *sql.Tx, todo *Todo, items []*ChecklistItem) error {
+	for rank, item := range items {
+		var id sql.NullInt64
+		if item.id != 0 {
+			id = sql.NullInt64{Int64: int64(item.id), Valid: true}
+		}
+
+		result, err := txn.ExecContext(ctx,
+			`INSERT INTO checklist_item (id, todo_id, text, done, rank) VALUES ($1, $2, $3, $4, $5)`,
+			id, todo.id, item.Text, item.Done, rank,
+		)
+		if err != nil {
+			return fmt.Errorf("error adding checklist item '%s' to todo '%s': %w", item.Text, todo.Title, err)
+		}
+
+		itemID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error getting id of checklist item '%s': %w", item.Text, err)
+		}
+
+		item.id = int(itemID)
+	}
+
+	return nil
+}
+
+// changeChecklist applies a change to the checklist of a Todo in a single transaction along with a history event
+// describing it. change runs inside the transaction and apply updates the in-memory state once it commits.
+func (d *Database) changeChecklist(
+	ctx context.Context, todo *Todo, detail string, change func(*sql.Tx) error, apply func(),
+) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if err = change(txn); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	event := &TodoEvent{Type: EventChecklistChanged, Detail: detail}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	apply()
+
+	todo.UpdatedDatetime = &event.Datetime
+
+	return nil
+}
+
+// AddChecklistItem adds an unchecked item with the given text to the end of the Todo's checklist.
+func (d *Database) AddChecklistItem(ctx context.Context, todo *Todo, text string) (*ChecklistItem, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, ErrEmptyChecklistItem
+	}
+
+	item := &ChecklistItem{Text: text}
+	idx := len(todo.Checklist)
+
+	if err = d.restoreChecklistItem(ctx, todo, item, idx); err != nil {
+		return nil, err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("adding '%s' to the checklist of todo '%s'", text, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.deleteChecklistItem(ctx, todo, item)
+		},
+		redo: func(ctx context.Context) error {
+			return d.restoreChecklistItem(ctx, todo, item, idx)
+		},
+	})
+
+	itemCopy := *item
+
+	return &itemCopy, nil
+}
+
+// restoreChecklistItem inserts the item at the given position in the Todo's checklist.
+func (d *Database) restoreChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem, idx int) error {
+	items := append(append(append([]*ChecklistItem{}, todo.Checklist[:idx]...), item), todo.Checklist[idx:]...)
+
+	return d.changeChecklist(ctx, todo, fmt.Sprintf("added '%s'", item.Text),
+		func(txn *sql.Tx) error {
+			if err := insertChecklistItems(ctx, txn, todo, []*ChecklistItem{item}); err != nil {
+				return err
+			}
+
+			return persistChecklistOrder(ctx, txn, items)
+		},
+		func() { todo.Checklist = items },
+	)
+}
+
+// CheckChecklistItem marks the item in the Todo's checklist as done.
+func (d *Database) CheckChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem) error {
+	return d.SetChecklistItemDone(ctx, todo, item, true)
+}
+
+// UncheckChecklistItem marks the item in the Todo's checklist as not done.
+func (d *Database) UncheckChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem) error {
+	return d.SetChecklistItemDone(ctx, todo, item, false)
+}
+
+// SetChecklistItemDone checks or unchecks the item in the Todo's checklist.
+func (d *Database) SetChecklistItemDone(ctx context.Context, todo *Todo, item *ChecklistItem, done bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, item, err := d.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	if item.Done == done {
+		return nil
+	}
+
+	if err = d.setChecklistItemDone(ctx, todo, item, done); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("checking '%s' in the checklist of todo '%s'", item.Text, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.setChecklistItemDone(ctx, todo, item, !done)
+		},
+		redo: func(ctx context.Context) error {
+			return d.setChecklistItemDone(ctx, todo, item, done)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) setChecklistItemDone(ctx context.Context, todo *Todo, item *ChecklistItem, done bool) error {
+	detail := fmt.Sprintf("checked '%s'", item.Text)
+	if !done {
+		detail = fmt.Sprintf("unchecked '%s'", item.Text)
+	}
+
+	return d.changeChecklist(ctx, todo, detail,
+		func(txn *sql.Tx) error {
+			_, err := txn.ExecContext(ctx, `UPDATE checklist_item SET done=$1 WHERE id=$2`, done, item.id)
+			if err != nil {
+				return fmt.Errorf("error updating checklist item '%s': %w", item.Text, err)
+			}
+
+			return nil
+		},
+		func() { item.Done = done },
+	)
+}
+
+// MoveChecklistItem moves the item to the given position in the Todo's checklist, which starts at 0. Positions past
+// the end of the checklist move the item to the end.
+func (d *Database) MoveChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem, idx int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, item, err := d.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	oldIdx := checklistIndex(todo.Checklist, item)
+
+	if idx < 0 || idx >= len(todo.Checklist) {
+		idx = len(todo.Checklist) - 1
+	}
+
+	if idx == oldIdx {
+		return nil
+	}
+
+	if err = d.moveChecklistItem(ctx, todo, item, idx); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("moving '%s' in the checklist of todo '%s'", item.Text, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.moveChecklistItem(ctx, todo, item, oldIdx)
+		},
+		redo: func(ctx context.Context) error {
+			return d.moveChecklistItem(ctx, todo, item, idx)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) moveChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem, idx int) error {
+	items := make([]*ChecklistItem, 0, len(todo.Checklist))
+
+	for _, it := range todo.Checklist {
+		if it != item {
+			items = append(items, it)
+		}
+	}
+
+	items = append(items[:idx], append([]*ChecklistItem{item}, items[idx:]...)...)
+
+	return d.changeChecklist(ctx, todo, fmt.Sprintf("moved '%s' to position %d", item.Text, idx+1),
+		func(txn *sql.Tx) error { return persistChecklistOrder(ctx, txn, items) },
+		func() { todo.Checklist = items },
+	)
+}
+
+// DeleteChecklistItem removes the item from the Todo's checklist.
+func (d *Database) DeleteChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, item, err := d.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	idx := checklistIndex(todo.Checklist, item)
+
+	if err = d.deleteChecklistItem(ctx, todo, item); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("deleting '%s' from the checklist of todo '%s'", item.Text, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.restoreChecklistItem(ctx, todo, item, idx)
+		},
+		redo: func(ctx context.Context) error {
+			return d.deleteChecklistItem(ctx, todo, item)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) deleteChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem) error {
+	items := make([]*ChecklistItem, 0, len(todo.Checklist))
+
+	for _, it := range todo.Checklist {
+		if it != item {
+			items = append(items, it)
+		}
+	}
+
+	return d.changeChecklist(ctx, todo, fmt.Sprintf("deleted '%s'", item.Text),
+		func(txn *sql.Tx) error {
+			if _, err := txn.ExecContext(ctx, `DELETE FROM checklist_item WHERE id=$1`, item.id); err != nil {
+				return fmt.Errorf("error deleting checklist item '%s': %w", item.Text, err)
+			}
+
+			return persistChecklistOrder(ctx, txn, items)
+		},
+		func() { todo.Checklist = items },
+	)
+}
+
+// liveChecklistItem looks up the Todo with liveTodo, and the item with the id of the given one in its checklist.
+func (m *model) liveChecklistItem(todo *Todo, item *ChecklistItem) (*Todo, *ChecklistItem, error) {
+	todo, err := m.liveTodo(todo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if item != nil {
+		for _, live := range todo.Checklist {
+			if live.id == item.id {
+				return todo, live, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w in todo '%s'", ErrChecklistItemNotFound, todo.Title)
+}
@@ -0,0 +1,117 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func checklistTexts(todo *db.Todo) []string {
+	texts := []string{}
+	for _, item := range todo.Checklist {
+		texts = append(texts, item.Text)
+	}
+
+	return texts
+}
+
+func TestChecklist(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_checklist*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	todo := addTodo(assert, database, "migrate service X", "")
+	other := addTodo(assert, database, "other", "")
+
+	_, err = database.AddChecklistItem(ctx, todo, "")
+	assert.ErrorIs(err, db.ErrEmptyChecklistItem)
+
+	step1, err := database.AddChecklistItem(ctx, todo, "step 1")
+	assert.Nil(err)
+	step2, err := database.AddChecklistItem(ctx, todo, "step 2")
+	assert.Nil(err)
+	step3, err := database.AddChecklistItem(ctx, todo, "step 3")
+	assert.Nil(err)
+
+	assert.Nil(database.CheckChecklistItem(ctx, todo, step1))
+	assert.Nil(database.CheckChecklistItem(ctx, todo, step3))
+	assert.Nil(database.UncheckChecklistItem(ctx, todo, step3))
+
+	done, total := storeTodo(assert, database, todo).ChecklistProgress()
+	assert.Equal(1, done)
+	assert.Equal(3, total)
+
+	assert.Nil(database.MoveChecklistItem(ctx, todo, step3, 0))
+	assert.Equal([]string{"step 3", "step 1", "step 2"}, checklistTexts(storeTodo(assert, database, todo)))
+
+	assert.Nil(database.DeleteChecklistItem(ctx, todo, step1))
+	assert.Equal([]string{"step 3", "step 2"}, checklistTexts(storeTodo(assert, database, todo)))
+
+	// items must belong to the todo
+	assert.ErrorIs(database.CheckChecklistItem(ctx, other, step2), db.ErrChecklistItemNotFound)
+	assert.ErrorIs(database.DeleteChecklistItem(ctx, todo, step1), db.ErrChecklistItemNotFound)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal([]string{"step 3", "step 1", "step 2"}, checklistTexts(storeTodo(assert, database, todo)))
+	assert.True(storeTodo(assert, database, todo).Checklist[1].Done)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal([]string{"step 1", "step 2", "step 3"}, checklistTexts(storeTodo(assert, database, todo)))
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+	assert.Equal(db.EventChecklistChanged, events[len(events)-1].Type)
+	assert.Equal("moved 'step 3' to position 3", events[len(events)-1].Detail)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	reloaded := database.Snapshot().Statuses[db.StatusOpen].Todos[0]
+	assert.Equal([]string{"step 1", "step 2", "step 3"}, checklistTexts(reloaded))
+	assert.True(reloaded.Checklist[0].Done)
+	assert.Equal(step1.ID(), reloaded.Checklist[0].ID())
+}
+
+func TestDeleteTodoWithChecklist(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addDefaultTodo(assert, database)
+
+	item, err := database.AddChecklistItem(ctx, todo, "step 1")
+	assert.Nil(err)
+	assert.Nil(database.CheckChecklistItem(ctx, todo, item))
+
+	assert.Nil(database.DeleteTodo(ctx, todo))
+	assert.Nil(database.Undo(ctx))
+
+	restored := storeTodo(assert, database, todo).Checklist
+	assert.Equal(1, len(restored))
+	assert.Equal(item.ID(), restored[0].ID())
+	assert.True(restored[0].Done)
+
+	// the restored item can still be changed
+	assert.Nil(database.UncheckChecklistItem(ctx, todo, item))
+	assert.False(storeTodo(assert, database, todo).Checklist[0].Done)
+}
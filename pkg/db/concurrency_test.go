@@ -0,0 +1,192 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	workers = 8
+	rounds  = 10
+)
+
+// assertRanks checks that the todos in each status are ranked 0, 1, 2... in order.
+func assertRanks(assert *assert.Assertions, statuses map[string]*db.Status) {
+	for _, status := range statuses {
+		for i, todo := range status.Todos {
+			assert.Equal(i, todo.Rank, "rank of '%s' in %s", todo.Title, status.Name)
+			assert.Equal(status, todo.Status)
+		}
+	}
+}
+
+func statusTitles(status *db.Status) []string {
+	return todoTitles(status.Todos)
+}
+
+func todoTitles(todos []*db.Todo) []string {
+	titles := make([]string, 0, len(todos))
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+
+	return titles
+}
+
+// TestConcurrentChanges creates, reranks and moves todos from many goroutines at once while others read snapshots;
+// run it with -race to check that the Database is safe for concurrent use.
+func TestConcurrentChanges(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_concurrency*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	open := database.Snapshot().Statuses[db.StatusOpen]
+	closed := database.Snapshot().Statuses[db.StatusClosed]
+	done := database.Snapshot().Statuses[db.StatusDone]
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < rounds; i++ {
+				// each goroutine only moves its own todos, so it knows which status they are in
+				todo, err := database.NewTodo(ctx, fmt.Sprintf("todo %d-%d", w, i), "")
+				assert.Nil(err)
+
+				// the new todo may already be on top if the others were moved out of open
+				if err = database.MoveToTop(ctx, todo); err != nil {
+					assert.ErrorIs(err, db.ErrCantMoveFirstTodoUp)
+				}
+
+				// the closed list is often full while other goroutines use it
+				err = database.ChangeStatus(ctx, todo, open, closed)
+				if err != nil {
+					assert.ErrorIs(err, db.ErrMaxClosedTodos)
+
+					continue
+				}
+
+				if i%2 == 0 {
+					assert.Nil(database.ChangeStatus(ctx, todo, closed, done))
+				}
+			}
+		}(w)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < rounds; i++ {
+				snapshot := database.Snapshot()
+				assertRanks(assert, snapshot.Statuses)
+				assert.LessOrEqual(len(snapshot.Statuses[db.StatusClosed].Todos), db.DefaultMaxClosedTodos)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(workers*rounds, len(database.Snapshot().Todos))
+	assertRanks(assert, database.Snapshot().Statuses)
+
+	// the persisted ranks match the in-memory ones
+	database.Close()
+
+	reloaded, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer reloaded.Close()
+
+	for name, status := range database.Snapshot().Statuses {
+		assert.Equal(statusTitles(status), statusTitles(reloaded.Snapshot().Statuses[name]), name)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	first := addTodo(assert, database, "first", "")
+	second := addTodo(assert, database, "second", "")
+	assert.Nil(database.AddDependency(ctx, second, first))
+	assert.Nil(database.AddTodoLabel(ctx, first, database.Snapshot().Labels[0]))
+
+	_, err := database.AddChecklistItem(ctx, first, "step")
+	assert.Nil(err)
+
+	snapshot := database.Snapshot()
+
+	// later changes don't show up in the snapshot
+	assert.Nil(database.MoveToTop(ctx, second))
+	assert.Nil(database.UpdateTodo(ctx, first, "renamed", ""))
+	assert.Nil(database.SetLabelColor(ctx, database.Snapshot().Labels[0], "#123456"))
+	addTodo(assert, database, "third", "")
+
+	assert.Equal(2, len(snapshot.Todos))
+	assert.Equal([]string{"first", "second"}, statusTitles(snapshot.Statuses[db.StatusOpen]))
+	assert.Equal(1, snapshot.Statuses[db.StatusOpen].Todos[1].Rank)
+	assert.NotEqual("#123456", snapshot.Labels[0].Color)
+
+	// the copies refer to each other rather than to the live model
+	snapFirst, err := snapshot.TodoByID(ctx, first.ID())
+	assert.Nil(err)
+	assert.Equal("first", snapFirst.Title)
+
+	current := storeTodo(assert, database, first)
+	assert.NotSame(current, snapFirst)
+	assert.Same(snapshot.Statuses[db.StatusOpen], snapFirst.Status)
+	assert.Same(snapshot.Labels[0], snapFirst.Labels[0])
+	assert.Equal("step", snapFirst.Checklist[0].Text)
+	assert.NotSame(current.Checklist[0], snapFirst.Checklist[0])
+
+	snapSecond, err := snapshot.TodoByID(ctx, second.ID())
+	assert.Nil(err)
+	assert.Same(snapFirst, snapSecond.BlockedBy[0])
+
+	assert.Equal(db.StatusOpen, snapshot.OrderedStatuses()[0].Name)
+
+	_, err = snapshot.TodoByID(ctx, 42)
+	assert.ErrorIs(err, db.ErrTodoNotFound)
+}
+
+func TestChangeStatusFromStaleStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addDefaultTodo(assert, database)
+	open := database.Snapshot().Statuses[db.StatusOpen]
+	closed := database.Snapshot().Statuses[db.StatusClosed]
+
+	assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+
+	// a caller that still thinks the todo is open, e.g. because it read an older snapshot, can't move it
+	err := database.ChangeStatus(ctx, todo, open, database.Snapshot().Statuses[db.StatusOnHold])
+	assert.ErrorIs(err, db.ErrInvalidTodoMove)
+	assert.Equal(closed.Name, storeTodo(assert, database, todo).Status.Name)
+}
@@ -3,11 +3,9 @@ package db
 import (
 	"context"
 	"database/sql"
-
-	// embed must be imported to allow us to embed base.sql.
-	_ "embed"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	// use the sqlite db driver.
@@ -15,22 +13,16 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// MaxClosedTodos defines the size of the closed todo list. This is intended to constrict work to items on this list,
-// which encourages focus and prioritization.
-const MaxClosedTodos = 5
+// DefaultMaxClosedTodos defines the size of the closed todo list unless it is changed with SetMaxClosedTodos. This
+// is intended to constrict work to items on this list, which encourages focus and prioritization.
+const DefaultMaxClosedTodos = 5
 
 const updateRankSQL = `UPDATE todo SET rank=$1 WHERE id=$2`
 
-//go:embed base.sql
-var baseSQL string
-
 var (
 	// ErrMaxClosedTodos is returned from ChangeStatus when attempting to move a todo to the closed list when it is
-	// full (i.e., it already has MaxClosedTodos todos).
-	ErrMaxClosedTodos = fmt.Errorf(
-		"there are already %d closed todos; complete or abandon something before starting something new",
-		MaxClosedTodos,
-	)
+	// full (i.e., it already has MaxClosedTodos() todos).
+	ErrMaxClosedTodos = errors.New("the closed list is full")
 	// ErrInvalidTodoMoveNoStatusChange is returned from ChangeStatus when the old and new statuses are the same.
 	ErrInvalidTodoMoveNoStatusChange = errors.New("cannot move a todo from one status to itself")
 
@@ -42,34 +34,64 @@ var (
 	ErrCantMoveLastTodoDown = errors.New("cannot move down the last todo")
 	// ErrNilTodo is returned when a modification is attempted on a nil Todo.
 	ErrNilTodo = errors.New("no Todo is currently selected")
+	// ErrNilLabel is returned when a modification is attempted with a nil Label.
+	ErrNilLabel = errors.New("no Label is currently selected")
 	// ErrEmptyTitle is returned when a new or modified todo has no title.
 	ErrEmptyTitle = errors.New("Todo title cannot be empty")
+	// ErrTodoNotFound is returned from TodoByID when no Todo has the given id, and when changing a Todo that has been
+	// deleted.
+	ErrTodoNotFound = errors.New("no todo found")
+	// ErrLabelNotFound is returned from LabelByName when no Label has the given name, and when using a Label that has
+	// been deleted.
+	ErrLabelNotFound = errors.New("no label found")
 )
 
-// Database manages the db connection and the state of the system.
+// Database is the Store backed by sqlite: it manages the db connection and keeps the state of the system in memory,
+// in its model. Its methods hold a lock, so they may be called from any goroutine, and return copies; see Store.
 type Database struct {
-	conn     *sql.DB
-	Statuses map[string]*Status
-	Labels   []*Label
-	Todos    []*Todo
+	conn  *sql.DB
+	clock func() time.Time
+	// mu guards the in-memory model: exported methods hold it while they read or change the model, and unexported
+	// methods expect their callers to hold it.
+	mu sync.RWMutex
+
+	model
+
+	// fts is set when the todo_search full-text index is available.
+	fts bool
+	// dataVersion is sqlite's data_version when the model was last loaded; see ReloadIfChanged.
+	dataVersion int64
 }
 
-// NewDatabase connects to the sqlite database at the given filename, initializes the structure
-// if not present, and loads existing data into memory.
+// NewDatabase connects to the sqlite database at the given filename, migrates the schema to the latest version,
+// and loads existing data into memory.
 func NewDatabase(ctx context.Context, filename string) (*Database, error) {
 	conn, err := sql.Open("sqlite3", filename)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to sqlite db at %s: %w", filename, err)
 	}
 
+	// a single connection serializes access within this process, and lets data_version tell when other processes
+	// change the database
+	conn.SetMaxOpenConns(1)
+
 	database := Database{
-		conn:     conn,
-		Statuses: map[string]*Status{},
-		Labels:   []*Label{},
-		Todos:    []*Todo{},
+		conn:  conn,
+		clock: time.Now,
+		model: newModel(),
+	}
+
+	err = database.migrate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.initSearch(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err = database.initialize(ctx)
+	database.dataVersion, err = database.readDataVersion(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -79,20 +101,23 @@ func NewDatabase(ctx context.Context, filename string) (*Database, error) {
 		return nil, err
 	}
 
+	if _, err = database.WakeSnoozed(ctx); err != nil {
+		return nil, err
+	}
+
 	return &database, nil
 }
 
-func (d *Database) initialize(ctx context.Context) error {
-	// run idempotent setup sql to create empty tables if they don't exist
-	if _, err := d.conn.ExecContext(ctx, baseSQL); err != nil {
-		return fmt.Errorf("error running base sql: %w", err)
-	}
-
-	return nil
+// now returns the current time according to the Database's clock.
+func (d *Database) now() time.Time {
+	return d.clock()
 }
 
 // Close closes the database connection.
 func (d *Database) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	if err := d.conn.Close(); err != nil {
 		return fmt.Errorf("error closing db: %w", err)
 	}
@@ -123,6 +148,11 @@ func (d *Database) loadData(ctx context.Context) error {
 		return err
 	}
 
+	err = d.loadTransitions(ctx)
+	if err != nil {
+		return err
+	}
+
 	err = d.loadTodos(ctx)
 	if err != nil {
 		return err
@@ -133,11 +163,21 @@ func (d *Database) loadData(ctx context.Context) error {
 		return err
 	}
 
+	err = d.loadChecklists(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = d.loadDependencies(ctx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (d *Database) loadLabels(ctx context.Context) error {
-	labelSQL := `SELECT id, name FROM label`
+	labelSQL := `SELECT id, name, color FROM label`
 
 	rows, err := d.conn.QueryContext(ctx, labelSQL)
 	if err != nil {
@@ -147,14 +187,22 @@ func (d *Database) loadLabels(ctx context.Context) error {
 	defer rows.Close()
 
 	for rows.Next() {
-		var label Label
+		var (
+			label Label
+			color sql.NullString
+		)
 
-		err = rows.Scan(&label.ID, &label.Name)
+		err = rows.Scan(&label.ID, &label.Name, &color)
 		if err != nil {
 			return fmt.Errorf("error scanning label: %w", err)
 		}
 
-		d.Labels = append(d.Labels, &label)
+		label.Color = color.String
+		if !color.Valid {
+			label.Color = defaultLabelColor(label.ID)
+		}
+
+		d.labels = append(d.labels, &label)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -165,7 +213,7 @@ func (d *Database) loadLabels(ctx context.Context) error {
 }
 
 func (d *Database) loadStatuses(ctx context.Context) error {
-	statusSQL := `SELECT id, name FROM status`
+	statusSQL := `SELECT id, name, display_order, key, color, wip_limit FROM status`
 
 	rows, err := d.conn.QueryContext(ctx, statusSQL)
 	if err != nil {
@@ -175,14 +223,19 @@ func (d *Database) loadStatuses(ctx context.Context) error {
 	defer rows.Close()
 
 	for rows.Next() {
-		var status Status
+		var (
+			status Status
+			key    sql.NullString
+		)
 
-		err = rows.Scan(&status.id, &status.Name)
+		err = rows.Scan(&status.id, &status.Name, &status.DisplayOrder, &key, &status.Color, &status.WIPLimit)
 		if err != nil {
 			return fmt.Errorf("error scanning status: %w", err)
 		}
 
-		d.Statuses[status.Name] = &status
+		status.Key = key.String
+
+		d.statuses[status.Name] = &status
 	}
 
 	if err = rows.Err(); err != nil {
@@ -195,7 +248,8 @@ func (d *Database) loadStatuses(ctx context.Context) error {
 func (d *Database) loadTodos(ctx context.Context) error {
 	log.Debug().Msgf("loading todos from db...")
 
-	todoSQL := `SELECT id, title, description, status_id, rank, created_datetime, updated_datetime
+	todoSQL := `SELECT id, title, description, status_id, rank, created_datetime, updated_datetime, due_date,
+					recurrence, snoozed_until, woken
 				FROM todo
 				ORDER BY status_id, rank`
 
@@ -209,7 +263,12 @@ func (d *Database) loadTodos(ctx context.Context) error {
 	for rows.Next() {
 		var todo Todo
 
-		var statusID int
+		var (
+			statusID   int
+			dueDate    sql.NullTime
+			recurrence sql.NullString
+			snoozed    sql.NullTime
+		)
 
 		err = rows.Scan(
 			&todo.id,
@@ -219,14 +278,28 @@ func (d *Database) loadTodos(ctx context.Context) error {
 			&todo.Rank,
 			&todo.CreatedDatetime,
 			&todo.UpdatedDatetime,
+			&dueDate,
+			&recurrence,
+			&snoozed,
+			&todo.Woken,
 		)
 		if err != nil {
 			return fmt.Errorf("error scanning todo: %w", err)
 		}
 
-		d.Todos = append(d.Todos, &todo)
+		todo.DueDate = dueDateFromDB(dueDate)
+
+		if snoozed.Valid {
+			todo.SnoozedUntil = &snoozed.Time
+		}
+
+		if todo.Recurrence, err = recurrenceFromDB(recurrence); err != nil {
+			return fmt.Errorf("error parsing recurrence of todo '%s': %w", todo.Title, err)
+		}
+
+		d.todos = append(d.todos, &todo)
 
-		for _, status := range d.Statuses {
+		for _, status := range d.statuses {
 			if status.id == statusID {
 				status.Todos = append(status.Todos, &todo)
 				todo.Status = status
@@ -240,7 +313,7 @@ func (d *Database) loadTodos(ctx context.Context) error {
 		return fmt.Errorf("error scanning todos: %w", err)
 	}
 
-	for key, status := range d.Statuses {
+	for key, status := range d.statuses {
 		for _, todo := range status.Todos {
 			log.Debug().Str("status", key).Str("todo", todo.Title).Int("rank", todo.Rank).Msgf("")
 		}
@@ -273,7 +346,7 @@ func (d *Database) loadTodoLabels(ctx context.Context) error {
 
 		var label *Label
 
-		for _, l := range d.Labels {
+		for _, l := range d.labels {
 			if l.ID == labelID {
 				label = l
 
@@ -281,7 +354,14 @@ func (d *Database) loadTodoLabels(ctx context.Context) error {
 			}
 		}
 
-		for _, todo := range d.Todos {
+		// CheckIntegrity reports todo_labels without a label
+		if label == nil {
+			log.Warn().Int("todo_id", todoID).Int("label_id", labelID).Msg("skipping todo_label of missing label")
+
+			continue
+		}
+
+		for _, todo := range d.todos {
 			if todo.id == todoID {
 				todo.Labels = append(todo.Labels, label)
 
@@ -300,70 +380,239 @@ func (d *Database) loadTodoLabels(ctx context.Context) error {
 // NewTodo creates a new Todo with the given title and description; the Todo is added
 // at the end of the open list.
 func (d *Database) NewTodo(ctx context.Context, title, description string) (*Todo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.newTodo(ctx, title, description, d.statuses[StatusOpen], nil)
+}
+
+// NewTodoWithLabels creates a new Todo like NewTodo, and adds the Labels to it in the same transaction, so that the
+// Todo is never left behind with only some of them. A Label that is given twice is added once.
+func (d *Database) NewTodoWithLabels(ctx context.Context, title, description string, labels []*Label) (*Todo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	live := make([]*Label, 0, len(labels))
+
+	for _, label := range labels {
+		label, err := d.liveLabel(label)
+		if err != nil {
+			return nil, err
+		}
+
+		if labelIndex(live, label) < 0 {
+			live = append(live, label)
+		}
+	}
+
+	return d.newTodo(ctx, title, description, d.statuses[StatusOpen], live)
+}
+
+// NewTodoInStatus creates a new Todo with the given title and description at the end of the given status, e.g. when
+// importing todos. The Todo doesn't move from another status, so transitions aren't checked, but limits are.
+func (d *Database) NewTodoInStatus(ctx context.Context, title, description string, status *Status) (*Todo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status, err := d.liveStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.checkLimit(status); err != nil {
+		return nil, err
+	}
+
+	return d.newTodo(ctx, title, description, status, nil)
+}
+
+func (d *Database) newTodo(
+	ctx context.Context, title, description string, status *Status, labels []*Label,
+) (*Todo, error) {
 	if len(title) == 0 {
 		return nil, ErrEmptyTitle
 	}
 
-	open := d.Statuses[StatusOpen]
-
-	rank := len(open.Todos)
-	now := time.Now()
+	rank := len(status.Todos)
+	now := d.now()
 	todo := &Todo{
 		Title:           title,
 		Description:     description,
-		Labels:          []*Label{},
+		Labels:          append([]*Label{}, labels...),
 		Rank:            rank,
-		Status:          open,
+		Status:          status,
 		CreatedDatetime: &now,
 		UpdatedDatetime: &now,
 	}
 
-	result, err := d.conn.ExecContext(ctx,
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	result, err := txn.ExecContext(ctx,
 		`INSERT INTO todo (title, description, status_id, rank, created_datetime, updated_datetime) 
 		     VALUES ($1, $2, $3, $4, $5, $6)`,
-		todo.Title, todo.Description, open.id, todo.Rank, todo.CreatedDatetime, todo.UpdatedDatetime,
+		todo.Title, todo.Description, status.id, todo.Rank, todo.CreatedDatetime, todo.UpdatedDatetime,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error adding todo: %w", err)
+		return nil, rollbackOnError(txn, fmt.Errorf("error adding todo: %w", err))
 	}
 
 	todoID, err := result.LastInsertId()
 	if err != nil {
-		return nil, fmt.Errorf("error getting id of new todo %s: %w", title, err)
+		return nil, rollbackOnError(txn, fmt.Errorf("error getting id of new todo %s: %w", title, err))
 	}
 
-	open.Todos = append(open.Todos, todo)
 	todo.id = int(todoID)
 
-	return todo, nil
+	event := &TodoEvent{
+		Type:           EventCreated,
+		NewStatus:      status,
+		NewRank:        rank,
+		NewTitle:       title,
+		NewDescription: description,
+		Datetime:       now,
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return nil, rollbackOnError(txn, err)
+	}
+
+	for _, label := range labels {
+		if err = d.insertTodoLabel(ctx, txn, todo, label, now); err != nil {
+			return nil, rollbackOnError(txn, err)
+		}
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	status.Todos = append(status.Todos, todo)
+	d.todos = append(d.todos, todo)
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("creating todo '%s'", title),
+		undo: func(ctx context.Context) error {
+			return d.deleteTodo(ctx, todo)
+		},
+		redo: func(ctx context.Context) error {
+			return d.restoreTodo(ctx, todo, status, rank)
+		},
+	})
+
+	return d.todoCopy(todo), nil
 }
 
 // UpdateTodo updates the Todo with the given title and description.
 func (d *Database) UpdateTodo(ctx context.Context, todo *Todo, title, description string) error {
-	if todo == nil {
-		return ErrNilTodo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	return d.editTodo(ctx, todo, title, description)
+}
+
+// PatchTodo changes the title and/or description of a Todo like UpdateTodo; a nil title or description keeps the
+// current one. The current values are read while holding the lock, so concurrent patches of different fields, e.g.
+// from the HTTP API, don't undo each other.
+func (d *Database) PatchTodo(ctx context.Context, todo *Todo, title, description *string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	newTitle, newDescription := todo.Title, todo.Description
+	if title != nil {
+		newTitle = *title
 	}
 
+	if description != nil {
+		newDescription = *description
+	}
+
+	return d.editTodo(ctx, todo, newTitle, newDescription)
+}
+
+// editTodo changes the title and description of a live Todo and records the change for undo.
+func (d *Database) editTodo(ctx context.Context, todo *Todo, title, description string) error {
 	if len(title) == 0 {
 		return ErrEmptyTitle
 	}
 
-	_, err := d.conn.ExecContext(ctx,
+	oldTitle, oldDescription := todo.Title, todo.Description
+
+	if err := d.updateTodo(ctx, todo, title, description); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("editing todo '%s'", title),
+		undo: func(ctx context.Context) error {
+			return d.updateTodo(ctx, todo, oldTitle, oldDescription)
+		},
+		redo: func(ctx context.Context) error {
+			return d.updateTodo(ctx, todo, title, description)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) updateTodo(ctx context.Context, todo *Todo, title, description string) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
 		`UPDATE todo SET title=$1, description=$2 WHERE id=$3`,
 		title, description, todo.id,
 	)
 	if err != nil {
-		return fmt.Errorf("error updating todo: %w", err)
+		return rollbackOnError(txn, fmt.Errorf("error updating todo: %w", err))
+	}
+
+	event := &TodoEvent{
+		Type:           EventEdited,
+		OldTitle:       todo.Title,
+		NewTitle:       title,
+		OldDescription: todo.Description,
+		NewDescription: description,
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
 	}
 
 	todo.Title = title
 	todo.Description = description
+	todo.UpdatedDatetime = &event.Datetime
 
 	return nil
 }
 
 // NewLabel creates a new label with the given name.
 func (d *Database) NewLabel(ctx context.Context, name string) (*Label, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkLabelName(nil, name); err != nil {
+		return nil, err
+	}
+
 	result, err := d.conn.ExecContext(ctx, `INSERT INTO label (name) VALUES ($1)`, name)
 	if err != nil {
 		return nil, fmt.Errorf("error adding label %s: %w", name, err)
@@ -374,25 +623,150 @@ func (d *Database) NewLabel(ctx context.Context, name string) (*Label, error) {
 		return nil, fmt.Errorf("error getting id of new label %s: %w", name, err)
 	}
 
-	label := &Label{ID: int(id), Name: name}
-	d.Labels = append(d.Labels, label)
+	label := &Label{ID: int(id), Name: name, Color: defaultLabelColor(int(id))}
+	d.labels = append(d.labels, label)
 
-	return label, nil
+	return labelCopies([]*Label{label})[0], nil
 }
 
 // UpdateLabel updates the label name.
 func (d *Database) UpdateLabel(ctx context.Context, label *Label, name string) error {
-	_, err := d.conn.ExecContext(ctx, `UPDATE label SET name=$1 WHERE id=$2`, name, label.ID)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	label, err := d.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	if err = d.checkLabelName(label, name); err != nil {
+		return err
+	}
+
+	_, err = d.conn.ExecContext(ctx, `UPDATE label SET name=$1 WHERE id=$2`, name, label.ID)
 	if err != nil {
 		return fmt.Errorf("error updating label: %w", err)
 	}
 
 	label.Name = name
 
+	for _, todo := range d.todosWithLabel(label) {
+		if err = d.indexTodo(ctx, d.conn, todo); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func validateStatusChange(todo *Todo, oldStatus, newStatus *Status) error {
+// DeleteTodo deletes a Todo along with its labels and moves up the Todos below it in its status.
+func (d *Database) DeleteTodo(ctx context.Context, todo *Todo) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	status, rank := todo.Status, todo.Rank
+
+	if err = d.deleteTodo(ctx, todo); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("deleting todo '%s'", todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.restoreTodo(ctx, todo, status, rank)
+		},
+		redo: func(ctx context.Context) error {
+			return d.deleteTodo(ctx, todo)
+		},
+	})
+
+	return nil
+}
+
+// DeleteLabel deletes a Label and removes it from every Todo that has it.
+func (d *Database) DeleteLabel(ctx context.Context, label *Label) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	label, err := d.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	idx := labelIndex(d.labels, label)
+
+	positions, err := d.deleteLabel(ctx, label)
+	if err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("deleting label '%s'", label.Name),
+		undo: func(ctx context.Context) error {
+			return d.restoreLabel(ctx, label, idx, positions)
+		},
+		redo: func(ctx context.Context) error {
+			_, err := d.deleteLabel(ctx, label)
+
+			return err
+		},
+	})
+
+	return nil
+}
+
+// TodoByID returns the Todo with the given id.
+func (d *Database) TodoByID(_ context.Context, id int) (*Todo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	todo, err := d.todoByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.todoCopy(todo), nil
+}
+
+func (m *model) todoByID(id int) (*Todo, error) {
+	for _, todo := range m.todos {
+		if todo.id == id {
+			return todo, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w with id %d", ErrTodoNotFound, id)
+}
+
+// LabelByName returns the Label with the given name.
+func (d *Database) LabelByName(_ context.Context, name string) (*Label, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	label, err := d.labelByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return labelCopies([]*Label{label})[0], nil
+}
+
+func (m *model) labelByName(name string) (*Label, error) {
+	for _, label := range m.labels {
+		if label.Name == name {
+			return label, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w named '%s'", ErrLabelNotFound, name)
+}
+
+func (m *model) validateStatusChange(todo *Todo, oldStatus, newStatus *Status) error {
 	if todo == nil {
 		return ErrNilTodo
 	}
@@ -401,19 +775,46 @@ func validateStatusChange(todo *Todo, oldStatus, newStatus *Status) error {
 		return ErrInvalidTodoMoveNoStatusChange
 	}
 
-	if newStatus.Name == StatusClosed && len(newStatus.Todos) >= MaxClosedTodos {
-		return ErrMaxClosedTodos
+	// another goroutine may have moved the Todo since the caller looked at it
+	if todo.Status != oldStatus {
+		return fmt.Errorf("%w: '%s' is in %s, not %s", ErrInvalidTodoMove, todo.Title, todo.Status.Name, oldStatus.Name)
 	}
 
-	if oldStatus.Name == StatusClosed && newStatus.Name == StatusOpen {
+	if !m.canTransition(oldStatus, newStatus) {
 		return fmt.Errorf("%w from %s to %s", ErrInvalidTodoMove, oldStatus.Name, newStatus.Name)
 	}
 
-	if (oldStatus.Name == StatusOpen || oldStatus.Name == StatusOnHold) && newStatus.Name == StatusDone {
-		return fmt.Errorf("%w from %s to %s", ErrInvalidTodoMove, oldStatus.Name, newStatus.Name)
+	if newStatus.Name == StatusDone {
+		if blockers := todo.OpenBlockers(); len(blockers) > 0 {
+			// the error outlives the lock, so it carries copies
+			copies := m.todoCopies(append([]*Todo{todo}, blockers...))
+
+			return &BlockedError{Todo: copies[0], Blockers: copies[1:]}
+		}
 	}
 
-	return nil
+	return m.checkLimit(newStatus)
+}
+
+// checkLimit returns an error if the status is already as full as its limit allows.
+func (m *model) checkLimit(status *Status) error {
+	limit := m.statusLimit(status)
+	if limit == 0 || len(status.Todos) < limit {
+		return nil
+	}
+
+	if status.Name == StatusClosed {
+		return fmt.Errorf(
+			"%w: there are already %d closed todos and the limit is %d; "+
+				"complete or abandon something before starting something new",
+			ErrMaxClosedTodos, len(status.Todos), limit,
+		)
+	}
+
+	return fmt.Errorf(
+		"%w: there are already %d todos in %s and the limit is %d",
+		ErrWIPLimitReached, len(status.Todos), status.Name, limit,
+	)
 }
 
 func (d *Database) persistStatusChange(ctx context.Context, todo *Todo, oldStatus, newStatus *Status) error {
@@ -424,7 +825,7 @@ func (d *Database) persistStatusChange(ctx context.Context, todo *Todo, oldStatu
 
 	_, err = txn.ExecContext(
 		ctx,
-		`UPDATE todo SET status_id=$1, rank=$2 WHERE id=$3`,
+		`UPDATE todo SET status_id=$1, rank=$2, snoozed_until=NULL, woken=0 WHERE id=$3`,
 		newStatus.id,
 		len(newStatus.Todos),
 		todo.id,
@@ -433,6 +834,17 @@ func (d *Database) persistStatusChange(ctx context.Context, todo *Todo, oldStatu
 		return rollbackOnError(txn, fmt.Errorf("error updating todo: %w", err))
 	}
 
+	event := &TodoEvent{
+		Type:      EventStatusChanged,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		OldRank:   todo.Rank,
+		NewRank:   len(newStatus.Todos),
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
 	for _, todoToUpdate := range oldStatus.Todos[todo.Rank+1:] {
 		log.Debug().Msgf("decrementing rank IN DB for todo %s", todoToUpdate.Title)
 
@@ -451,10 +863,12 @@ func (d *Database) persistStatusChange(ctx context.Context, todo *Todo, oldStatu
 		return fmt.Errorf("error committing changes: %w", err)
 	}
 
+	todo.UpdatedDatetime = &event.Datetime
+
 	return nil
 }
 
-func (d *Database) localStatusChange(todo *Todo, oldStatus, newStatus *Status) {
+func (m *model) localStatusChange(todo *Todo, oldStatus, newStatus *Status) {
 	// don't change objects until after transaction is committed to avoid complexity of reversion if the commit fails
 	for _, todoToUpdate := range oldStatus.Todos[todo.Rank+1:] {
 		todoToUpdate.Rank--
@@ -475,6 +889,8 @@ func (d *Database) localStatusChange(todo *Todo, oldStatus, newStatus *Status) {
 
 	todo.Status = newStatus
 	todo.Rank = len(newStatus.Todos) - 1
+	todo.SnoozedUntil = nil
+	todo.Woken = false
 	log.Debug().Msgf("setting rank on moved todo to %d", todo.Rank)
 
 	if todo.Rank < 0 {
@@ -484,7 +900,39 @@ func (d *Database) localStatusChange(todo *Todo, oldStatus, newStatus *Status) {
 
 // ChangeStatus moves a Todo from one status to another.
 func (d *Database) ChangeStatus(ctx context.Context, todo *Todo, oldStatus, newStatus *Status) error {
-	if err := validateStatusChange(todo, oldStatus, newStatus); err != nil {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, oldStatus, newStatus, err := d.liveStatusChange(todo, oldStatus, newStatus)
+	if err != nil {
+		return err
+	}
+
+	return d.changeStatus(ctx, todo, oldStatus, newStatus)
+}
+
+// MoveToStatus moves a Todo from whichever status it is in when the lock is taken to another, like ChangeStatus. It
+// suits callers like the HTTP API that can't know the current status of a Todo that others may move concurrently.
+func (d *Database) MoveToStatus(ctx context.Context, todo *Todo, status *Status) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if status, err = d.liveStatus(status); err != nil {
+		return err
+	}
+
+	return d.changeStatus(ctx, todo, todo.Status, status)
+}
+
+// changeStatus moves a live Todo from one live status to another and records the move for undo.
+func (d *Database) changeStatus(ctx context.Context, todo *Todo, oldStatus, newStatus *Status) error {
+	err := d.validateStatusChange(todo, oldStatus, newStatus)
+	if err != nil {
 		return err
 	}
 
@@ -497,12 +945,31 @@ func (d *Database) ChangeStatus(ctx context.Context, todo *Todo, oldStatus, newS
 		log.Debug().Msgf("current rank for todo %s: %d", todoToUpdate.Title, todoToUpdate.Rank)
 	}
 
-	if err := d.persistStatusChange(ctx, todo, oldStatus, newStatus); err != nil {
+	old := placementOf(todo)
+
+	if err = d.persistStatusChange(ctx, todo, oldStatus, newStatus); err != nil {
 		return err
 	}
 
 	d.localStatusChange(todo, oldStatus, newStatus)
 
+	description := fmt.Sprintf("moving todo '%s' to %s", todo.Title, newStatus.Name)
+
+	if newStatus.Name != StatusDone || todo.Recurrence == nil {
+		d.pushMove(description, todo, old)
+
+		return nil
+	}
+
+	next, err := d.recur(ctx, todo)
+	if err != nil {
+		d.pushMove(description, todo, old)
+
+		return fmt.Errorf("todo '%s' is done, but the next occurrence couldn't be created: %w", todo.Title, err)
+	}
+
+	d.pushRecur(description, todo, next, old)
+
 	return nil
 }
 
@@ -510,14 +977,32 @@ func (d *Database) ChangeStatus(ctx context.Context, todo *Todo, oldStatus, newS
 // and increases the ranking of the previous Todo.
 // If the last Todo is passed, return ErrCantMoveFirstTodoUp.
 func (d *Database) MoveUp(ctx context.Context, todo *Todo) error {
-	if todo == nil {
-		return ErrNilTodo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
 	}
 
 	if todo.Rank == 0 {
 		return ErrCantMoveFirstTodoUp
 	}
 
+	old := placementOf(todo)
+
+	if err = d.swapWithPrevious(ctx, todo, todo); err != nil {
+		return err
+	}
+
+	d.pushMove(fmt.Sprintf("moving todo '%s' up", todo.Title), todo, old)
+
+	return nil
+}
+
+// swapWithPrevious swaps the ranks of a Todo and the Todo before it. The rerank is recorded in the history of
+// subject, which is the Todo the user asked to move: either todo itself or the Todo before it.
+func (d *Database) swapWithPrevious(ctx context.Context, todo, subject *Todo) error {
 	todos := todo.Status.Todos
 
 	prevTodo := todos[todo.Rank-1]
@@ -527,6 +1012,21 @@ func (d *Database) MoveUp(ctx context.Context, todo *Todo) error {
 		return fmt.Errorf("error opening transaction: %w", err)
 	}
 
+	event := &TodoEvent{
+		Type:      EventReranked,
+		OldStatus: subject.Status,
+		NewStatus: subject.Status,
+		OldRank:   subject.Rank,
+		NewRank:   prevTodo.Rank,
+	}
+	if subject == prevTodo {
+		event.NewRank = todo.Rank
+	}
+
+	if err = d.recordChange(ctx, txn, subject, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
 	// swapping values fails since we have a unique index on status_id + rank; set to -1 temporarily
 	_, err = txn.ExecContext(ctx, updateRankSQL, -1, prevTodo.id)
 	if err != nil {
@@ -548,6 +1048,8 @@ func (d *Database) MoveUp(ctx context.Context, todo *Todo) error {
 		return fmt.Errorf("error committing changes: %w", err)
 	}
 
+	subject.UpdatedDatetime = &event.Datetime
+
 	todos[todo.Rank-1], todos[todo.Rank] = todos[todo.Rank], todos[todo.Rank-1]
 
 	todo.Rank--
@@ -560,8 +1062,12 @@ func (d *Database) MoveUp(ctx context.Context, todo *Todo) error {
 // and reduces the ranking of the next Todo.
 // If the last Todo is passed, return ErrCantMoveLastTodoDown.
 func (d *Database) MoveDown(ctx context.Context, todo *Todo) error {
-	if todo == nil {
-		return ErrNilTodo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
 	}
 
 	if todo.Rank >= len(todo.Status.Todos)-1 {
@@ -579,15 +1085,27 @@ func (d *Database) MoveDown(ctx context.Context, todo *Todo) error {
 		nextTodo.Rank,
 	)
 
-	return d.MoveUp(ctx, nextTodo)
+	old := placementOf(todo)
+
+	if err = d.swapWithPrevious(ctx, nextTodo, todo); err != nil {
+		return err
+	}
+
+	d.pushMove(fmt.Sprintf("moving todo '%s' down", todo.Title), todo, old)
+
+	return nil
 }
 
 // MoveToTop moves a Todo to the top of the list and moves everything else down (meaning it
 // increases all higher rankings by 1)
 // If the first Todo is passed, return ErrCantMoveFirstTodoUp.
 func (d *Database) MoveToTop(ctx context.Context, todo *Todo) error {
-	if todo == nil {
-		return ErrNilTodo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
 	}
 
 	if todo.Rank == 0 {
@@ -601,6 +1119,17 @@ func (d *Database) MoveToTop(ctx context.Context, todo *Todo) error {
 		return fmt.Errorf("error opening transaction: %w", err)
 	}
 
+	event := &TodoEvent{
+		Type:      EventReranked,
+		OldStatus: todo.Status,
+		NewStatus: todo.Status,
+		OldRank:   todo.Rank,
+		NewRank:   0,
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
 	// updates are executed in arbitrary order, so to avoid violating the unique
 	// index on status_id + rank, we set rank to a negative value for all todos
 	// with higher rankings, update the rank of the desired todo, and then correctly
@@ -629,6 +1158,10 @@ func (d *Database) MoveToTop(ctx context.Context, todo *Todo) error {
 		return fmt.Errorf("error committing changes: %w", err)
 	}
 
+	todo.UpdatedDatetime = &event.Datetime
+
+	old := placementOf(todo)
+
 	for idx := todo.Rank - 1; idx > -1; idx-- {
 		todos[idx].Rank++
 		todos[idx+1] = todos[idx]
@@ -637,6 +1170,8 @@ func (d *Database) MoveToTop(ctx context.Context, todo *Todo) error {
 	todo.Rank = 0
 	todos[0] = todo
 
+	d.pushMove(fmt.Sprintf("moving todo '%s' to the top", todo.Title), todo, old)
+
 	return nil
 }
 
@@ -644,8 +1179,12 @@ func (d *Database) MoveToTop(ctx context.Context, todo *Todo) error {
 // decreases all lower rankings by 1)
 // If the last Todo is passed, return ErrCantMoveLastTodoDown.
 func (d *Database) MoveToBottom(ctx context.Context, todo *Todo) error {
-	if todo == nil {
-		return ErrNilTodo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
 	}
 
 	if todo.Rank >= len(todo.Status.Todos)-1 {
@@ -659,17 +1198,25 @@ func (d *Database) MoveToBottom(ctx context.Context, todo *Todo) error {
 		return fmt.Errorf("error opening transaction: %w", err)
 	}
 
-	// set rank to -1 temporarily to avoid violating unique index on status_id + rank
-	_, err = txn.ExecContext(ctx, updateRankSQL, -1, todo.id)
-	if err != nil {
-		return rollbackOnError(txn, fmt.Errorf("error temp updating todo: %w", err))
+	event := &TodoEvent{
+		Type:      EventReranked,
+		OldStatus: todo.Status,
+		NewStatus: todo.Status,
+		OldRank:   todo.Rank,
+		NewRank:   len(todos) - 1,
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
 	}
 
-	updateRanksSQL := `UPDATE todo SET rank=rank - 1 WHERE rank > $1 AND status_id = $2`
+	// as in MoveToTop, updates are executed in arbitrary order, so to avoid violating the unique index on
+	// status_id + rank, we set rank to a negative value for all todos with lower rankings, update the rank of the
+	// desired todo, and then correctly set other ranks to positive values.
+	updateRanksNegativeSQL := `UPDATE todo SET rank=rank * -1 WHERE rank > $1 AND status_id = $2`
 
-	_, err = txn.ExecContext(ctx, updateRanksSQL, todo.Rank, todo.Status.id)
+	_, err = txn.ExecContext(ctx, updateRanksNegativeSQL, todo.Rank, todo.Status.id)
 	if err != nil {
-		return rollbackOnError(txn, fmt.Errorf("error updating todos: %w", err))
+		return rollbackOnError(txn, fmt.Errorf("error temp updating todos: %w", err))
 	}
 
 	_, err = txn.ExecContext(ctx, updateRankSQL, len(todos)-1, todo.id)
@@ -677,11 +1224,22 @@ func (d *Database) MoveToBottom(ctx context.Context, todo *Todo) error {
 		return rollbackOnError(txn, fmt.Errorf("error updating todo: %w", err))
 	}
 
+	updateRanksSQL := `UPDATE todo SET rank=(rank * -1) - 1 WHERE rank < 0 AND status_id = $1`
+
+	_, err = txn.ExecContext(ctx, updateRanksSQL, todo.Status.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error updating todos: %w", err))
+	}
+
 	err = txn.Commit()
 	if err != nil {
 		return fmt.Errorf("error committing changes: %w", err)
 	}
 
+	todo.UpdatedDatetime = &event.Datetime
+
+	old := placementOf(todo)
+
 	for idx := todo.Rank + 1; idx < len(todos); idx++ {
 		todos[idx].Rank--
 		todos[idx-1] = todos[idx]
@@ -690,12 +1248,106 @@ func (d *Database) MoveToBottom(ctx context.Context, todo *Todo) error {
 	todo.Rank = len(todos) - 1
 	todos[len(todos)-1] = todo
 
+	d.pushMove(fmt.Sprintf("moving todo '%s' to the bottom", todo.Title), todo, old)
+
 	return nil
 }
 
-// AddTodoLabel adds a Label to a Todo.
+// MoveToRank moves a Todo to the given rank in its status and shifts the Todos in between by one, e.g. to move it past
+// Todos that a filter hides. Moving a Todo to its own rank does nothing.
+func (d *Database) MoveToRank(ctx context.Context, todo *Todo, rank int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if rank < 0 || rank >= len(todo.Status.Todos) {
+		return fmt.Errorf("%w: %s has no rank %d", ErrInvalidTodoMove, todo.Status.Name, rank)
+	}
+
+	if rank == todo.Rank {
+		return nil
+	}
+
+	old := placementOf(todo)
+	to := old
+	to.rank = rank
+
+	if err = d.placeTodo(ctx, todo, to); err != nil {
+		return err
+	}
+
+	d.pushMove(fmt.Sprintf("moving todo '%s' to rank %d", todo.Title, rank), todo, old)
+
+	return nil
+}
+
+// AddTodoLabel adds a Label to a Todo. Adding a Label that the Todo already has does nothing.
 func (d *Database) AddTodoLabel(ctx context.Context, todo *Todo, label *Label) error {
-	_, err := d.conn.ExecContext(ctx,
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, label, err := d.liveTodoLabel(todo, label)
+	if err != nil {
+		return err
+	}
+
+	if labelIndex(todo.Labels, label) >= 0 {
+		return nil
+	}
+
+	idx := len(todo.Labels)
+
+	if err = d.addTodoLabel(ctx, todo, label, idx); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("adding label '%s' to todo '%s'", label.Name, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.removeTodoLabel(ctx, todo, label)
+		},
+		redo: func(ctx context.Context) error {
+			return d.addTodoLabel(ctx, todo, label, idx)
+		},
+	})
+
+	return nil
+}
+
+// addTodoLabel adds a Label to a Todo at the given position in its list of labels.
+func (d *Database) addTodoLabel(ctx context.Context, todo *Todo, label *Label, idx int) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	now := d.now()
+	if err = d.insertTodoLabel(ctx, txn, todo, label, now); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &now
+
+	labels := make([]*Label, 0, len(todo.Labels)+1)
+	labels = append(labels, todo.Labels[:idx]...)
+	labels = append(labels, label)
+	todo.Labels = append(labels, todo.Labels[idx:]...)
+
+	return nil
+}
+
+// insertTodoLabel adds a Label to a Todo in the transaction and records the change at the given time.
+func (d *Database) insertTodoLabel(ctx context.Context, txn *sql.Tx, todo *Todo, label *Label, at time.Time) error {
+	_, err := txn.ExecContext(ctx,
 		`INSERT INTO todo_label (todo_id, label_id) VALUES ($1, $2)`,
 		todo.id, label.ID,
 	)
@@ -703,21 +1355,67 @@ func (d *Database) AddTodoLabel(ctx context.Context, todo *Todo, label *Label) e
 		return fmt.Errorf("error adding label '%s' to todo '%s': %w", label.Name, todo.Title, err)
 	}
 
-	todo.Labels = append(todo.Labels, label)
+	return d.recordChange(ctx, txn, todo, &TodoEvent{Type: EventLabelAdded, Datetime: at}, label)
+}
+
+// RemoveTodoLabel removes a Label from a Todo. Removing a Label that the Todo doesn't have does nothing.
+func (d *Database) RemoveTodoLabel(ctx context.Context, todo *Todo, label *Label) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, label, err := d.liveTodoLabel(todo, label)
+	if err != nil {
+		return err
+	}
+
+	idx := labelIndex(todo.Labels, label)
+	if idx < 0 {
+		return nil
+	}
+
+	if err = d.removeTodoLabel(ctx, todo, label); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("removing label '%s' from todo '%s'", label.Name, todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.addTodoLabel(ctx, todo, label, idx)
+		},
+		redo: func(ctx context.Context) error {
+			return d.removeTodoLabel(ctx, todo, label)
+		},
+	})
 
 	return nil
 }
 
-// RemoveTodoLabel removes a Label from a Todo.
-func (d *Database) RemoveTodoLabel(ctx context.Context, todo *Todo, label *Label) error {
-	_, err := d.conn.ExecContext(ctx,
+func (d *Database) removeTodoLabel(ctx context.Context, todo *Todo, label *Label) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
 		`DELETE FROM todo_label WHERE todo_id = $1 AND label_id = $2`,
 		todo.id, label.ID,
 	)
 	if err != nil {
-		return fmt.Errorf("error removing label '%s' from todo '%s': %w", label.Name, todo.Title, err)
+		return rollbackOnError(txn, fmt.Errorf("error removing label '%s' from todo '%s': %w", label.Name, todo.Title, err))
 	}
 
+	event := &TodoEvent{Type: EventLabelRemoved}
+	if err = d.recordChange(ctx, txn, todo, event, label); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &event.Datetime
+
 	// remove the label from the list
 	for i, l := range todo.Labels {
 		if l.ID == label.ID {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/matt-steen/todo-tracker/pkg/db"
 	"github.com/stretchr/testify/assert"
@@ -43,7 +44,10 @@ func TestNewDatabaseBadFile(t *testing.T) {
 	database, err := db.NewDatabase(context.Background(), "/alwfkjasfd/asdflkjdsal.sqlite")
 	assert.Nil(database)
 	assert.NotNil(err)
-	assert.Equal("error running base sql: unable to open database file: no such file or directory", err.Error())
+	assert.Equal(
+		"error opening migration transaction: unable to open database file: no such file or directory",
+		err.Error(),
+	)
 }
 
 func TestNewDatabase(t *testing.T) {
@@ -71,9 +75,11 @@ func TestNewDatabaseIdempotent(t *testing.T) {
 	database, err := db.NewDatabase(context.Background(), tempFile.Name())
 	assert.NotNil(database)
 	assert.Nil(err)
-	assert.Equal(0, len(database.Todos))
-	assert.Equal(5, len(database.Statuses))
-	assert.Equal(9, len(database.Labels))
+
+	snapshot := database.Snapshot()
+	assert.Equal(0, len(snapshot.Todos))
+	assert.Equal(5, len(snapshot.Statuses))
+	assert.Equal(9, len(snapshot.Labels))
 
 	err = database.Close()
 	assert.Nil(err)
@@ -81,9 +87,11 @@ func TestNewDatabaseIdempotent(t *testing.T) {
 	database2, err := db.NewDatabase(context.Background(), tempFile.Name())
 	assert.NotNil(database2)
 	assert.Nil(err)
-	assert.Equal(0, len(database2.Todos))
-	assert.Equal(5, len(database2.Statuses))
-	assert.Equal(9, len(database2.Labels))
+
+	snapshot = database2.Snapshot()
+	assert.Equal(0, len(snapshot.Todos))
+	assert.Equal(5, len(snapshot.Statuses))
+	assert.Equal(9, len(snapshot.Labels))
 
 	database2.Close()
 }
@@ -114,12 +122,16 @@ func TestLoadComplexState(t *testing.T) {
 	err = database.AddTodoLabel(ctx, todo1, label)
 	assert.Nil(err)
 
-	err = database.AddTodoLabel(ctx, todo1, database.Labels[0])
+	snapshot := database.Snapshot()
+
+	err = database.AddTodoLabel(ctx, todo1, snapshot.Labels[0])
 	assert.Nil(err)
 
-	err = database.ChangeStatus(ctx, todo2, database.Statuses[db.StatusOpen], database.Statuses[db.StatusClosed])
+	err = database.ChangeStatus(ctx, todo2, snapshot.Statuses[db.StatusOpen], snapshot.Statuses[db.StatusClosed])
 	assert.Nil(err)
 
+	todo1 = storeTodo(assert, database, todo1)
+
 	database.Close()
 
 	database2, err := db.NewDatabase(ctx, tempFile.Name())
@@ -127,21 +139,24 @@ func TestLoadComplexState(t *testing.T) {
 
 	defer database2.Close()
 
-	assert.Equal(3, len(database2.Todos))
-	assert.Equal(2, len(database2.Statuses[db.StatusOpen].Todos))
-	assert.Equal(1, len(database2.Statuses[db.StatusClosed].Todos))
+	snapshot = database2.Snapshot()
+	open, closed := snapshot.Statuses[db.StatusOpen], snapshot.Statuses[db.StatusClosed]
 
-	assert.Equal(database2.Statuses[db.StatusOpen].Todos[0].Title, todo1.Title)
-	assert.Equal(database2.Statuses[db.StatusOpen].Todos[1].Title, todo3.Title)
+	assert.Equal(3, len(snapshot.Todos))
+	assert.Equal(2, len(open.Todos))
+	assert.Equal(1, len(closed.Todos))
 
-	assert.Equal(0, database2.Statuses[db.StatusOpen].Todos[0].Rank)
-	assert.Equal(1, database2.Statuses[db.StatusOpen].Todos[1].Rank)
+	assert.Equal(open.Todos[0].Title, todo1.Title)
+	assert.Equal(open.Todos[1].Title, todo3.Title)
 
-	assert.Equal(database2.Statuses[db.StatusClosed].Todos[0].Title, todo2.Title)
-	assert.Equal(0, database2.Statuses[db.StatusClosed].Todos[0].Rank)
+	assert.Equal(0, open.Todos[0].Rank)
+	assert.Equal(1, open.Todos[1].Rank)
+
+	assert.Equal(closed.Todos[0].Title, todo2.Title)
+	assert.Equal(0, closed.Todos[0].Rank)
 
 	assert.Equal(newLabelName, todo1.Labels[0].Name)
-	assert.Equal(database2.Labels[0].Name, todo1.Labels[1].Name)
+	assert.Equal(snapshot.Labels[0].Name, todo1.Labels[1].Name)
 }
 
 func TestNewLabel(t *testing.T) {
@@ -179,6 +194,9 @@ func TestUpdateLabel(t *testing.T) {
 	name = "heuer"
 	err = database.UpdateLabel(ctx, label, name)
 	assert.Nil(err)
+
+	label, err = database.LabelByName(ctx, name)
+	assert.Nil(err)
 	assert.Equal(name, label.Name)
 
 	database.Close()
@@ -186,10 +204,71 @@ func TestUpdateLabel(t *testing.T) {
 	database2, err := db.NewDatabase(ctx, tempFile.Name())
 	assert.Nil(err)
 
-	assert.Equal(name, database2.Labels[len(database2.Labels)-1].Name)
+	labels := database2.AllLabels()
+	assert.Equal(name, labels[len(labels)-1].Name)
 	database2.Close()
 }
 
+func TestLabelByName(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	created, err := database.NewLabel(ctx, "busywork")
+	assert.Nil(err)
+
+	label, err := database.LabelByName(ctx, "busywork")
+	assert.Nil(err)
+	assert.Equal(created, label)
+
+	label, err = database.LabelByName(ctx, "nonexistent")
+	assert.Nil(label)
+	assert.ErrorIs(err, db.ErrLabelNotFound)
+}
+
+func TestTodoByID(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_database*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	first := addTodo(assert, database, "first", "")
+	second := addTodo(assert, database, "second", "")
+	assert.NotEqual(first.ID(), second.ID())
+
+	todo, err := database.TodoByID(ctx, second.ID())
+	assert.Nil(err)
+	assert.Equal(second, todo)
+
+	todo, err = database.TodoByID(ctx, second.ID()+1)
+	assert.Nil(todo)
+	assert.ErrorIs(err, db.ErrTodoNotFound)
+
+	database.Close()
+
+	// ids are stable across sessions
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	todo, err = database.TodoByID(ctx, first.ID())
+	assert.Nil(err)
+	assert.Equal("first", todo.Title)
+}
+
 func TestNewTodo(t *testing.T) {
 	t.Parallel()
 
@@ -208,13 +287,113 @@ func TestNewTodo(t *testing.T) {
 	assert.Equal(description, todo.Description)
 
 	// confirm that the new todo was added to the end of the list for the open status
-	assert.Equal(database.Statuses[db.StatusOpen].Todos[todo.Rank].Title, title)
+	assert.Equal(database.Snapshot().Statuses[db.StatusOpen].Todos[todo.Rank].Title, title)
 
 	todo1, err := database.NewTodo(ctx, "", description)
 	assert.Nil(todo1)
 	assert.ErrorIs(err, db.ErrEmptyTitle)
 }
 
+func TestNewTodoInStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_todo_in_status*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	done := storeStatus(assert, database, db.StatusDone)
+	closed := storeStatus(assert, database, db.StatusClosed)
+
+	// open todos can't be done directly, but new todos can start out done
+	todo, err := database.NewTodoInStatus(ctx, "already finished", "", done)
+	assert.Nil(err)
+	assert.Equal(done.Name, todo.Status.Name)
+	assert.Equal(todo, storeStatus(assert, database, db.StatusDone).Todos[0])
+
+	assert.Nil(database.SetMaxClosedTodos(ctx, 1))
+
+	_, err = database.NewTodoInStatus(ctx, "in progress", "", closed)
+	assert.Nil(err)
+
+	todo, err = database.NewTodoInStatus(ctx, "too much", "", closed)
+	assert.Nil(todo)
+	assert.ErrorIs(err, db.ErrMaxClosedTodos)
+	assert.Equal(1, len(storeStatus(assert, database, db.StatusClosed).Todos))
+
+	database.Close()
+
+	// reload to confirm that the todos were saved with the right status
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot := database.Snapshot()
+	assert.Equal("already finished", snapshot.Statuses[db.StatusDone].Todos[0].Title)
+	assert.Equal("in progress", snapshot.Statuses[db.StatusClosed].Todos[0].Title)
+}
+
+func TestNewTodoWithLabels(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_todo_with_labels*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	labels := database.AllLabels()
+	task, urgent := labels[0], labels[3]
+
+	todo, err := database.NewTodoWithLabels(ctx, "ship it", "", []*db.Label{urgent, task, urgent})
+	assert.Nil(err)
+	assert.Equal([]*db.Label{urgent, task}, todo.Labels)
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+	assert.Equal(3, len(events))
+	assert.Equal(db.EventLabelAdded, events[2].Type)
+
+	// a label that has been deleted fails the whole change, so no todo is left behind without it
+	assert.Nil(database.DeleteLabel(ctx, task))
+
+	_, err = database.NewTodoWithLabels(ctx, "half labelled", "", []*db.Label{urgent, task})
+	assert.ErrorIs(err, db.ErrLabelNotFound)
+	assert.Equal(1, len(database.Snapshot().Todos))
+
+	// undoing the creation removes the todo with its labels, and redoing it restores both
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(database.Undo(ctx))
+	assert.Empty(database.Snapshot().Todos)
+	assert.Nil(database.Redo(ctx))
+	assert.Equal([]*db.Label{urgent, task}, database.Snapshot().Todos[0].Labels)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot := database.Snapshot()
+	assert.Equal(1, len(snapshot.Todos))
+	assert.Equal(2, len(snapshot.Todos[0].Labels))
+
+	problems, err := database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Empty(problems)
+}
+
 func TestUpdateTodo(t *testing.T) {
 	t.Parallel()
 
@@ -238,6 +417,7 @@ func TestUpdateTodo(t *testing.T) {
 	err = database.UpdateTodo(ctx, todo, title, description)
 	assert.Nil(err)
 
+	todo = storeTodo(assert, database, todo)
 	assert.Equal(title, todo.Title)
 	assert.Equal(description, todo.Description)
 
@@ -245,6 +425,42 @@ func TestUpdateTodo(t *testing.T) {
 	assert.ErrorIs(err, db.ErrEmptyTitle)
 }
 
+func TestPatchTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addTodo(assert, database, "review a proposal", "")
+
+	description := "it's about something important"
+	assert.Nil(database.PatchTodo(ctx, todo, nil, &description))
+
+	todo = storeTodo(assert, database, todo)
+	assert.Equal("review a proposal", todo.Title)
+	assert.Equal(description, todo.Description)
+
+	title := "review an important proposal"
+	assert.Nil(database.PatchTodo(ctx, todo, &title, nil))
+
+	todo = storeTodo(assert, database, todo)
+	assert.Equal(title, todo.Title)
+	assert.Equal(description, todo.Description)
+
+	empty := ""
+	assert.ErrorIs(database.PatchTodo(ctx, todo, &empty, nil), db.ErrEmptyTitle)
+
+	assert.Nil(database.Undo(ctx))
+
+	todo = storeTodo(assert, database, todo)
+	assert.Equal("review a proposal", todo.Title)
+	assert.Equal(description, todo.Description)
+}
+
 func TestAddTodoLabel(t *testing.T) {
 	t.Parallel()
 
@@ -255,12 +471,12 @@ func TestAddTodoLabel(t *testing.T) {
 
 	todo := addDefaultTodo(assert, database)
 
-	label := database.Labels[0]
+	label := database.AllLabels()[0]
 
 	err := database.AddTodoLabel(context.Background(), todo, label)
 	assert.Nil(err)
 
-	assert.Equal(label.Name, todo.Labels[0].Name)
+	assert.Equal(label.Name, storeTodo(assert, database, todo).Labels[0].Name)
 }
 
 func TestAddTodoLabelTwice(t *testing.T) {
@@ -273,19 +489,17 @@ func TestAddTodoLabelTwice(t *testing.T) {
 
 	todo := addDefaultTodo(assert, database)
 
-	label := database.Labels[0]
+	label := database.AllLabels()[0]
 
 	err := database.AddTodoLabel(context.Background(), todo, label)
 	assert.Nil(err)
 
-	assert.Equal(label.Name, todo.Labels[0].Name)
+	assert.Equal(label.Name, storeTodo(assert, database, todo).Labels[0].Name)
 
 	err = database.AddTodoLabel(context.Background(), todo, label)
-	assert.NotNil(err)
-	assert.Equal(
-		"error adding label 'task' to todo 'do some work': UNIQUE constraint failed: todo_label.todo_id, todo_label.label_id",
-		err.Error(),
-	)
+	assert.Nil(err)
+
+	assert.Equal([]*db.Label{label}, storeTodo(assert, database, todo).Labels)
 }
 
 func TestRemoveTodoLabel(t *testing.T) {
@@ -297,22 +511,136 @@ func TestRemoveTodoLabel(t *testing.T) {
 	defer database.Close()
 
 	todo := addDefaultTodo(assert, database)
+	labels := database.AllLabels()
 
-	err := database.AddTodoLabel(context.Background(), todo, database.Labels[0])
+	err := database.AddTodoLabel(context.Background(), todo, labels[0])
 	assert.Nil(err)
 
-	err = database.AddTodoLabel(context.Background(), todo, database.Labels[1])
+	err = database.AddTodoLabel(context.Background(), todo, labels[1])
 	assert.Nil(err)
 
-	err = database.AddTodoLabel(context.Background(), todo, database.Labels[2])
+	err = database.AddTodoLabel(context.Background(), todo, labels[2])
 	assert.Nil(err)
 
-	err = database.RemoveTodoLabel(context.Background(), todo, database.Labels[0])
+	err = database.RemoveTodoLabel(context.Background(), todo, labels[0])
 	assert.Nil(err)
 
 	// confirm preservation of the order of the remaining labels
-	assert.Equal(database.Labels[1].Name, todo.Labels[0].Name)
-	assert.Equal(database.Labels[2].Name, todo.Labels[1].Name)
+	todo = storeTodo(assert, database, todo)
+	assert.Equal(labels[1].Name, todo.Labels[0].Name)
+	assert.Equal(labels[2].Name, todo.Labels[1].Name)
+
+	// removing a label the todo doesn't have changes nothing, not even the history
+	updated := *todo.UpdatedDatetime
+
+	events, err := database.History(context.Background(), todo)
+	assert.Nil(err)
+
+	assert.Nil(database.RemoveTodoLabel(context.Background(), todo, labels[0]))
+
+	after, err := database.History(context.Background(), todo)
+	assert.Nil(err)
+	assert.Equal(len(events), len(after))
+	assert.Equal(updated, *storeTodo(assert, database, todo).UpdatedDatetime)
+}
+
+func TestDeleteTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_database*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	assert.Nil(database.AddTodoLabel(ctx, todo2, database.AllLabels()[0]))
+
+	assert.ErrorIs(database.DeleteTodo(ctx, nil), db.ErrNilTodo)
+
+	assert.Nil(database.DeleteTodo(ctx, todo2))
+
+	snapshot := database.Snapshot()
+	assertTitles(assert, snapshot.Statuses[db.StatusOpen], "todo 1", "todo 3")
+	assert.Equal(2, len(snapshot.Todos))
+
+	_, err = database.TodoByID(ctx, todo2.ID())
+	assert.ErrorIs(err, db.ErrTodoNotFound)
+
+	// the freed rank can be reused
+	addTodo(assert, database, "todo 4", "")
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 3", "todo 4")
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot = database.Snapshot()
+	assertTitles(assert, snapshot.Statuses[db.StatusOpen], "todo 1", "todo 3", "todo 4")
+	assert.Equal(3, len(snapshot.Todos))
+}
+
+func TestDeleteLabel(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_database*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+
+	label, err := database.LabelByName(ctx, "onboarding")
+	assert.Nil(err)
+
+	labels := database.AllLabels()
+
+	assert.Nil(database.AddTodoLabel(ctx, todo1, labels[0]))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, label))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, labels[1]))
+	assert.Nil(database.AddTodoLabel(ctx, todo2, label))
+
+	assert.Nil(database.DeleteLabel(ctx, label))
+
+	assert.Equal([]*db.Label{labels[0], labels[1]}, storeTodo(assert, database, todo1).Labels)
+	assert.Equal(0, len(storeTodo(assert, database, todo2).Labels))
+	assert.Equal(8, len(database.AllLabels()))
+
+	_, err = database.LabelByName(ctx, "onboarding")
+	assert.ErrorIs(err, db.ErrLabelNotFound)
+
+	events, err := database.History(ctx, todo2)
+	assert.Nil(err)
+	assert.Equal(db.EventLabelRemoved, events[len(events)-1].Type)
+	assert.Equal("onboarding", events[len(events)-1].LabelName)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot := database.Snapshot()
+	assert.Equal(8, len(snapshot.Labels))
+	assert.Equal(2, len(snapshot.Statuses[db.StatusOpen].Todos[0].Labels))
+	assert.Equal(0, len(snapshot.Statuses[db.StatusOpen].Todos[1].Labels))
 }
 
 func TestChangeStatus(t *testing.T) {
@@ -333,25 +661,59 @@ func TestChangeStatus(t *testing.T) {
 	assert.Equal(2, todo3.Rank)
 	assert.Equal(3, todo4.Rank)
 
-	assert.Equal(todo2.Status, database.Statuses[db.StatusOpen])
+	snapshot := database.Snapshot()
+	assert.Equal(db.StatusOpen, todo2.Status.Name)
 
 	err := database.ChangeStatus(
 		context.Background(),
 		todo2,
-		database.Statuses[db.StatusOpen],
-		database.Statuses[db.StatusClosed],
+		snapshot.Statuses[db.StatusOpen],
+		snapshot.Statuses[db.StatusClosed],
 	)
 	assert.Nil(err)
 
+	snapshot = database.Snapshot()
+	todo1, todo2 = storeTodo(assert, database, todo1), storeTodo(assert, database, todo2)
+	todo3, todo4 = storeTodo(assert, database, todo3), storeTodo(assert, database, todo4)
+
 	assert.Equal(0, todo2.Rank)
-	assert.Equal(1, len(database.Statuses[db.StatusClosed].Todos))
-	assert.Equal(database.Statuses[db.StatusClosed], todo2.Status)
-	assert.Equal("todo 2", database.Statuses[db.StatusClosed].Todos[0].Title)
+	assert.Equal(1, len(snapshot.Statuses[db.StatusClosed].Todos))
+	assert.Equal(snapshot.Statuses[db.StatusClosed], todo2.Status)
+	assert.Equal("todo 2", snapshot.Statuses[db.StatusClosed].Todos[0].Title)
 
 	assert.Equal(0, todo1.Rank)
 	assert.Equal(1, todo3.Rank)
 	assert.Equal(2, todo4.Rank)
-	assert.Equal(3, len(database.Statuses[db.StatusOpen].Todos))
+	assert.Equal(3, len(snapshot.Statuses[db.StatusOpen].Todos))
+}
+
+func TestMoveToStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addTodo(assert, database, "todo 1", "")
+	closed, done := storeStatus(assert, database, db.StatusClosed), storeStatus(assert, database, db.StatusDone)
+
+	// the todo moves from whatever status it is in, like open and then closed
+	assert.Nil(database.MoveToStatus(ctx, todo, closed))
+	assert.Equal(closed.Name, storeTodo(assert, database, todo).Status.Name)
+	assert.Nil(database.MoveToStatus(ctx, todo, done))
+	assert.Equal(done.Name, storeTodo(assert, database, todo).Status.Name)
+
+	// the transitions and limits still apply
+	assert.ErrorIs(database.MoveToStatus(ctx, todo, done), db.ErrInvalidTodoMoveNoStatusChange)
+	assert.ErrorIs(database.MoveToStatus(ctx, nil, done), db.ErrNilTodo)
+	assert.ErrorIs(database.MoveToStatus(ctx, todo, nil), db.ErrStatusNotFound)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(closed.Name, storeTodo(assert, database, todo).Status.Name)
+	assertRanks(assert, database.Snapshot().Statuses)
 }
 
 func initTestChangeStatusErrors(assert *assert.Assertions) (*db.Database, map[string]*db.Todo) {
@@ -368,16 +730,16 @@ func initTestChangeStatusErrors(assert *assert.Assertions) (*db.Database, map[st
 	err := database.ChangeStatus(
 		ctx,
 		todos[db.StatusClosed],
-		database.Statuses[db.StatusOpen],
-		database.Statuses[db.StatusClosed],
+		database.Snapshot().Statuses[db.StatusOpen],
+		database.Snapshot().Statuses[db.StatusClosed],
 	)
 	assert.Nil(err)
 
 	err = database.ChangeStatus(
 		ctx,
 		todos[db.StatusOnHold],
-		database.Statuses[db.StatusOpen],
-		database.Statuses[db.StatusOnHold],
+		database.Snapshot().Statuses[db.StatusOpen],
+		database.Snapshot().Statuses[db.StatusOnHold],
 	)
 	assert.Nil(err)
 
@@ -428,8 +790,8 @@ func TestChangeStatusErrors(t *testing.T) {
 		err := database.ChangeStatus(
 			context.Background(),
 			todos[testCase.oldStatus],
-			database.Statuses[testCase.oldStatus],
-			database.Statuses[testCase.newStatus],
+			database.Snapshot().Statuses[testCase.oldStatus],
+			database.Snapshot().Statuses[testCase.newStatus],
 		)
 		assert.NotNil(err)
 		assert.Equal(testCase.expectedErrorMessage, err.Error(), testCase.name)
@@ -455,8 +817,8 @@ func TestChangeStatusValidatesClosedListLimit(t *testing.T) {
 		err := database.ChangeStatus(
 			context.Background(),
 			todo,
-			database.Statuses[db.StatusOpen],
-			database.Statuses[db.StatusClosed],
+			database.Snapshot().Statuses[db.StatusOpen],
+			database.Snapshot().Statuses[db.StatusClosed],
 		)
 
 		if idx < 5 {
@@ -481,8 +843,9 @@ func TestMoveUpTodo(t *testing.T) {
 	assert.Equal(0, todo1.Rank)
 	assert.Equal(1, todo2.Rank)
 
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
+	open := storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(todo1.Title, open.Todos[0].Title)
+	assert.Equal(todo2.Title, open.Todos[1].Title)
 
 	err := database.MoveUp(context.Background(), todo1)
 	assert.ErrorIs(err, db.ErrCantMoveFirstTodoUp)
@@ -490,11 +853,13 @@ func TestMoveUpTodo(t *testing.T) {
 	err = database.MoveUp(context.Background(), todo2)
 	assert.Nil(err)
 
+	todo1, todo2 = storeTodo(assert, database, todo1), storeTodo(assert, database, todo2)
 	assert.Equal(1, todo1.Rank)
 	assert.Equal(0, todo2.Rank)
 
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
+	open = storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(todo2.Title, open.Todos[0].Title)
+	assert.Equal(todo1.Title, open.Todos[1].Title)
 }
 
 func TestMoveDownTodo(t *testing.T) {
@@ -511,8 +876,9 @@ func TestMoveDownTodo(t *testing.T) {
 	assert.Equal(0, todo1.Rank)
 	assert.Equal(1, todo2.Rank)
 
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
+	open := storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(todo1.Title, open.Todos[0].Title)
+	assert.Equal(todo2.Title, open.Todos[1].Title)
 
 	err := database.MoveDown(context.Background(), todo2)
 	assert.ErrorIs(err, db.ErrCantMoveLastTodoDown)
@@ -520,11 +886,13 @@ func TestMoveDownTodo(t *testing.T) {
 	err = database.MoveDown(context.Background(), todo1)
 	assert.Nil(err)
 
+	todo1, todo2 = storeTodo(assert, database, todo1), storeTodo(assert, database, todo2)
 	assert.Equal(1, todo1.Rank)
 	assert.Equal(0, todo2.Rank)
 
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
+	open = storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(todo2.Title, open.Todos[0].Title)
+	assert.Equal(todo1.Title, open.Todos[1].Title)
 }
 
 func TestMoveTodoToTop(t *testing.T) {
@@ -549,32 +917,28 @@ func TestMoveTodoToTop(t *testing.T) {
 	todo4 := addTodo(assert, database, "todo 4", "")
 	todo5 := addTodo(assert, database, "todo 5", "")
 
+	open, closed := storeStatus(assert, database, db.StatusOpen), storeStatus(assert, database, db.StatusClosed)
+
 	// move todos 4 and 5 to closed to confirm that their ranks don't change
-	err = database.ChangeStatus(
-		context.Background(),
-		todo4,
-		database.Statuses[db.StatusOpen],
-		database.Statuses[db.StatusClosed],
-	)
+	err = database.ChangeStatus(context.Background(), todo4, open, closed)
 	assert.Nil(err)
 
-	err = database.ChangeStatus(
-		context.Background(),
-		todo5,
-		database.Statuses[db.StatusOpen],
-		database.Statuses[db.StatusClosed],
-	)
+	err = database.ChangeStatus(context.Background(), todo5, open, closed)
 	assert.Nil(err)
 
-	assert.Equal(0, todo1.Rank)
-	assert.Equal(1, todo2.Rank)
-	assert.Equal(2, todo3.Rank)
-	assert.Equal(0, todo4.Rank)
-	assert.Equal(1, todo5.Rank)
+	snapshot := database.Snapshot()
+	todos := snapshot.Todos
+
+	assert.Equal(0, todos[0].Rank)
+	assert.Equal(1, todos[1].Rank)
+	assert.Equal(2, todos[2].Rank)
+	assert.Equal(0, todos[3].Rank)
+	assert.Equal(1, todos[4].Rank)
 
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo3.Title, database.Statuses[db.StatusOpen].Todos[2].Title)
+	open = snapshot.Statuses[db.StatusOpen]
+	assert.Equal(todo1.Title, open.Todos[0].Title)
+	assert.Equal(todo2.Title, open.Todos[1].Title)
+	assert.Equal(todo3.Title, open.Todos[2].Title)
 
 	err = database.MoveToTop(context.Background(), todo1)
 	assert.ErrorIs(err, db.ErrCantMoveFirstTodoUp)
@@ -582,13 +946,17 @@ func TestMoveTodoToTop(t *testing.T) {
 	err = database.MoveToTop(context.Background(), todo3)
 	assert.Nil(err)
 
-	assert.Equal(0, todo3.Rank)
-	assert.Equal(1, todo1.Rank)
-	assert.Equal(2, todo2.Rank)
+	snapshot = database.Snapshot()
+	todos = snapshot.Todos
 
-	assert.Equal(todo3.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[2].Title)
+	assert.Equal(0, todos[2].Rank)
+	assert.Equal(1, todos[0].Rank)
+	assert.Equal(2, todos[1].Rank)
+
+	open = snapshot.Statuses[db.StatusOpen]
+	assert.Equal(todo3.Title, open.Todos[0].Title)
+	assert.Equal(todo1.Title, open.Todos[1].Title)
+	assert.Equal(todo2.Title, open.Todos[2].Title)
 
 	// confirm that data was saved correctly
 	database2, err := db.NewDatabase(ctx, tempFile.Name())
@@ -596,11 +964,12 @@ func TestMoveTodoToTop(t *testing.T) {
 
 	defer database2.Close()
 
-	assert.Equal(todo3.Title, database2.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo1.Title, database2.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo2.Title, database2.Statuses[db.StatusOpen].Todos[2].Title)
-	assert.Equal(todo4.Title, database2.Statuses[db.StatusClosed].Todos[0].Title)
-	assert.Equal(todo5.Title, database2.Statuses[db.StatusClosed].Todos[1].Title)
+	snapshot = database2.Snapshot()
+	assert.Equal(todo3.Title, snapshot.Statuses[db.StatusOpen].Todos[0].Title)
+	assert.Equal(todo1.Title, snapshot.Statuses[db.StatusOpen].Todos[1].Title)
+	assert.Equal(todo2.Title, snapshot.Statuses[db.StatusOpen].Todos[2].Title)
+	assert.Equal(todo4.Title, snapshot.Statuses[db.StatusClosed].Todos[0].Title)
+	assert.Equal(todo5.Title, snapshot.Statuses[db.StatusClosed].Todos[1].Title)
 }
 
 func TestMoveTodoToBottom(t *testing.T) {
@@ -627,9 +996,10 @@ func TestMoveTodoToBottom(t *testing.T) {
 	assert.Equal(1, todo2.Rank)
 	assert.Equal(2, todo3.Rank)
 
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo3.Title, database.Statuses[db.StatusOpen].Todos[2].Title)
+	open := storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(todo1.Title, open.Todos[0].Title)
+	assert.Equal(todo2.Title, open.Todos[1].Title)
+	assert.Equal(todo3.Title, open.Todos[2].Title)
 
 	err = database.MoveToBottom(ctx, todo3)
 	assert.ErrorIs(err, db.ErrCantMoveLastTodoDown)
@@ -637,13 +1007,17 @@ func TestMoveTodoToBottom(t *testing.T) {
 	err = database.MoveToBottom(context.Background(), todo1)
 	assert.Nil(err)
 
-	assert.Equal(0, todo2.Rank)
-	assert.Equal(1, todo3.Rank)
-	assert.Equal(2, todo1.Rank)
+	snapshot := database.Snapshot()
+	todos := snapshot.Todos
+
+	assert.Equal(0, todos[1].Rank)
+	assert.Equal(1, todos[2].Rank)
+	assert.Equal(2, todos[0].Rank)
 
-	assert.Equal(todo2.Title, database.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo3.Title, database.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo1.Title, database.Statuses[db.StatusOpen].Todos[2].Title)
+	open = snapshot.Statuses[db.StatusOpen]
+	assert.Equal(todo2.Title, open.Todos[0].Title)
+	assert.Equal(todo3.Title, open.Todos[1].Title)
+	assert.Equal(todo1.Title, open.Todos[2].Title)
 
 	// confirm that data was saved correctly
 	database2, err := db.NewDatabase(ctx, tempFile.Name())
@@ -651,7 +1025,100 @@ func TestMoveTodoToBottom(t *testing.T) {
 
 	defer database2.Close()
 
-	assert.Equal(todo2.Title, database2.Statuses[db.StatusOpen].Todos[0].Title)
-	assert.Equal(todo3.Title, database2.Statuses[db.StatusOpen].Todos[1].Title)
-	assert.Equal(todo1.Title, database2.Statuses[db.StatusOpen].Todos[2].Title)
+	open = database2.Snapshot().Statuses[db.StatusOpen]
+	assert.Equal(todo2.Title, open.Todos[0].Title)
+	assert.Equal(todo3.Title, open.Todos[1].Title)
+	assert.Equal(todo1.Title, open.Todos[2].Title)
+}
+
+// fakeClock is a clock for tests that only moves when advanced.
+type fakeClock struct {
+	current time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.current
+}
+
+func (f *fakeClock) advance() time.Time {
+	f.current = f.current.Add(time.Minute)
+
+	return f.current
+}
+
+func newFakeClock(database *db.Database) *fakeClock {
+	clock := &fakeClock{current: time.Date(2022, time.January, 10, 9, 0, 0, 0, time.UTC)}
+	database.SetClock(clock.now)
+
+	return clock
+}
+
+func TestTimestamps(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_new_database*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	clock := newFakeClock(database)
+	created := clock.now()
+
+	todo := addTodo(assert, database, "todo 1", "")
+	other := addTodo(assert, database, "todo 2", "")
+
+	assert.Equal(created, *todo.CreatedDatetime)
+	assert.Equal(created, *todo.UpdatedDatetime)
+
+	snapshot := database.Snapshot()
+	open := snapshot.Statuses[db.StatusOpen]
+	closed := snapshot.Statuses[db.StatusClosed]
+	label := snapshot.Labels[0]
+
+	operations := []struct {
+		name string
+		op   func() error
+	}{
+		{"update", func() error { return database.UpdateTodo(ctx, todo, "todo one", "") }},
+		{"move down", func() error { return database.MoveDown(ctx, todo) }},
+		{"move up", func() error { return database.MoveUp(ctx, todo) }},
+		{"move to bottom", func() error { return database.MoveToBottom(ctx, todo) }},
+		{"move to top", func() error { return database.MoveToTop(ctx, todo) }},
+		{"add label", func() error { return database.AddTodoLabel(ctx, todo, label) }},
+		{"remove label", func() error { return database.RemoveTodoLabel(ctx, todo, label) }},
+		{"change status", func() error { return database.ChangeStatus(ctx, todo, open, closed) }},
+		{"undo", func() error { return database.Undo(ctx) }},
+	}
+
+	for _, operation := range operations {
+		now := clock.advance()
+
+		assert.Nil(operation.op(), operation.name)
+
+		current := storeTodo(assert, database, todo)
+		assert.Equal(now, *current.UpdatedDatetime, operation.name)
+		assert.Equal(created, *current.CreatedDatetime, operation.name)
+	}
+
+	// only the todo that was acted upon is stamped, even though the ranks of others changed
+	assert.Equal(created, *storeTodo(assert, database, other).UpdatedDatetime)
+
+	todo = storeTodo(assert, database, todo)
+
+	database2, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database2.Close()
+
+	reloaded := database2.Snapshot().Statuses[db.StatusOpen].Todos[todo.Rank]
+	assert.Equal(todo.Title, reloaded.Title)
+	assert.True(clock.now().Equal(*reloaded.UpdatedDatetime))
+	assert.True(created.Equal(*reloaded.CreatedDatetime))
 }
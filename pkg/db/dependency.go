@@ -0,0 +1,348 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrBlocked is wrapped by BlockedError, so errors.Is(err, ErrBlocked) reports whether a Todo was blocked.
+	ErrBlocked = errors.New("the todo is blocked")
+	// ErrDependencyCycle is returned from AddDependency when the new dependency would make a Todo block itself.
+	ErrDependencyCycle = errors.New("a todo cannot block itself, directly or indirectly")
+	// ErrDependencyNotFound is returned from RemoveDependency when the Todo isn't blocked by the other one.
+	ErrDependencyNotFound = errors.New("dependency not found")
+	// ErrDoneTodoBlocked is returned from AddDependency when a Todo that is done would wait on one that isn't done or
+	// abandoned.
+	ErrDoneTodoBlocked = errors.New("a todo that is done can't wait on an unfinished todo")
+)
+
+// BlockedError is returned when a Todo can't be done because some of the Todos that block it aren't done or
+// abandoned yet.
+type BlockedError struct {
+	Todo     *Todo
+	Blockers []*Todo
+}
+
+func (e *BlockedError) Error() string {
+	blockers := make([]string, 0, len(e.Blockers))
+	for _, blocker := range e.Blockers {
+		blockers = append(blockers, fmt.Sprintf("#%d '%s'", blocker.id, blocker.Title))
+	}
+
+	return fmt.Sprintf("%s: '%s' is waiting on %s", ErrBlocked, e.Todo.Title, strings.Join(blockers, ", "))
+}
+
+// Unwrap returns ErrBlocked.
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// OpenBlockers returns the Todos that block this one and are neither done nor abandoned.
+func (t *Todo) OpenBlockers() []*Todo {
+	var blockers []*Todo
+
+	for _, blocker := range t.BlockedBy {
+		if !Finished(blocker.Status) {
+			blockers = append(blockers, blocker)
+		}
+	}
+
+	return blockers
+}
+
+// Finished reports whether Todos in the given status no longer block anything: they are done or abandoned.
+func Finished(status *Status) bool {
+	return status.Name == StatusDone || status.Name == StatusAbandoned
+}
+
+// Blocked reports whether the Todo can't be done yet because of its OpenBlockers.
+func (t *Todo) Blocked() bool {
+	return len(t.OpenBlockers()) > 0
+}
+
+func (d *Database) loadDependencies(ctx context.Context) error {
+	rows, err := d.conn.QueryContext(ctx, `SELECT todo_id, blocker_id FROM todo_dependency ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("error loading dependencies: %w", err)
+	}
+
+	defer rows.Close()
+
+	todos := make(map[int]*Todo, len(d.todos))
+	for _, todo := range d.todos {
+		todos[todo.id] = todo
+	}
+
+	for rows.Next() {
+		var todoID, blockerID int
+
+		if err = rows.Scan(&todoID, &blockerID); err != nil {
+			return fmt.Errorf("error scanning dependency: %w", err)
+		}
+
+		todo, ok := todos[todoID]
+		blocker, found := todos[blockerID]
+
+		if ok && found {
+			todo.BlockedBy = append(todo.BlockedBy, blocker)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error scanning dependencies: %w", err)
+	}
+
+	return nil
+}
+
+// blockedBy reports whether todo is blocked by blocker, directly or through other Todos.
+func blockedBy(todo, blocker *Todo) bool {
+	seen := map[*Todo]bool{}
+	queue := []*Todo{todo}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, b := range current.BlockedBy {
+			if b == blocker {
+				return true
+			}
+
+			if !seen[b] {
+				seen[b] = true
+
+				queue = append(queue, b)
+			}
+		}
+	}
+
+	return false
+}
+
+// dependencyIndex returns the position of blocker in the BlockedBy list of todo, or -1 if it isn't there.
+func dependencyIndex(todo, blocker *Todo) int {
+	for i, b := range todo.BlockedBy {
+		if b == blocker {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// dependentsOf returns the Todos that are directly blocked by the given Todo.
+func (m *model) dependentsOf(todo *Todo) []*Todo {
+	var dependents []*Todo
+
+	for _, t := range m.todos {
+		if dependencyIndex(t, todo) >= 0 {
+			dependents = append(dependents, t)
+		}
+	}
+
+	return dependents
+}
+
+// AddDependency records that todo is blocked by blocker, so todo can't be done until blocker is done or abandoned.
+// Adding a dependency that already exists does nothing. A Todo that is already done can only wait on Todos that are
+// Finished.
+func (d *Database) AddDependency(ctx context.Context, todo, blocker *Todo) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if blocker, err = d.liveTodo(blocker); err != nil {
+		return err
+	}
+
+	if dependencyIndex(todo, blocker) >= 0 {
+		return nil
+	}
+
+	if todo == blocker || blockedBy(blocker, todo) {
+		return fmt.Errorf("%w: #%d '%s' already waits on #%d '%s'",
+			ErrDependencyCycle, blocker.id, blocker.Title, todo.id, todo.Title)
+	}
+
+	if todo.Status.Name == StatusDone && !Finished(blocker.Status) {
+		return fmt.Errorf("%w: #%d '%s' is done, but #%d '%s' is %s",
+			ErrDoneTodoBlocked, todo.id, todo.Title, blocker.id, blocker.Title, blocker.Status.Name)
+	}
+
+	idx := len(todo.BlockedBy)
+
+	if err = d.addDependency(ctx, todo, blocker, idx); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("blocking todo '%s' by '%s'", todo.Title, blocker.Title),
+		undo: func(ctx context.Context) error {
+			return d.removeDependency(ctx, todo, blocker)
+		},
+		redo: func(ctx context.Context) error {
+			return d.addDependency(ctx, todo, blocker, idx)
+		},
+	})
+
+	return nil
+}
+
+// addDependency blocks todo by blocker, adding blocker at the given position in todo's BlockedBy list.
+func (d *Database) addDependency(ctx context.Context, todo, blocker *Todo, idx int) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`INSERT INTO todo_dependency (todo_id, blocker_id) VALUES ($1, $2)`,
+		todo.id, blocker.id,
+	)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error blocking todo '%s' by '%s': %w", todo.Title, blocker.Title, err))
+	}
+
+	event := &TodoEvent{
+		Type:   EventDependencyAdded,
+		Detail: fmt.Sprintf("blocked by #%d '%s'", blocker.id, blocker.Title),
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &event.Datetime
+
+	blockers := make([]*Todo, 0, len(todo.BlockedBy)+1)
+	blockers = append(blockers, todo.BlockedBy[:idx]...)
+	blockers = append(blockers, blocker)
+	todo.BlockedBy = append(blockers, todo.BlockedBy[idx:]...)
+
+	return nil
+}
+
+// RemoveDependency records that todo is no longer blocked by blocker.
+func (d *Database) RemoveDependency(ctx context.Context, todo, blocker *Todo) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if blocker, err = d.liveTodo(blocker); err != nil {
+		return err
+	}
+
+	idx := dependencyIndex(todo, blocker)
+	if idx < 0 {
+		return fmt.Errorf("%w: #%d '%s' isn't blocked by #%d '%s'",
+			ErrDependencyNotFound, todo.id, todo.Title, blocker.id, blocker.Title)
+	}
+
+	if err = d.removeDependency(ctx, todo, blocker); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("unblocking todo '%s' from '%s'", todo.Title, blocker.Title),
+		undo: func(ctx context.Context) error {
+			return d.addDependency(ctx, todo, blocker, idx)
+		},
+		redo: func(ctx context.Context) error {
+			return d.removeDependency(ctx, todo, blocker)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) removeDependency(ctx context.Context, todo, blocker *Todo) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`DELETE FROM todo_dependency WHERE todo_id=$1 AND blocker_id=$2`,
+		todo.id, blocker.id,
+	)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error unblocking todo '%s' from '%s': %w", todo.Title, blocker.Title, err))
+	}
+
+	event := &TodoEvent{
+		Type:   EventDependencyRemoved,
+		Detail: fmt.Sprintf("no longer blocked by #%d '%s'", blocker.id, blocker.Title),
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &event.Datetime
+
+	if idx := dependencyIndex(todo, blocker); idx >= 0 {
+		todo.BlockedBy = append(todo.BlockedBy[:idx:idx], todo.BlockedBy[idx+1:]...)
+	}
+
+	return nil
+}
+
+// liveTodos returns the given Todos that are still in the model, leaving out any that were deleted since.
+func (m *model) liveTodos(todos []*Todo) []*Todo {
+	live := make(map[*Todo]bool, len(m.todos))
+	for _, todo := range m.todos {
+		live[todo] = true
+	}
+
+	var kept []*Todo
+
+	for _, todo := range todos {
+		if live[todo] {
+			kept = append(kept, todo)
+		}
+	}
+
+	return kept
+}
+
+// insertDependencies restores the dependencies of a Todo removed by deleteTodo in the given transaction: the Todos
+// that block it, and the Todos that it blocks. The caller drops the Todos that were deleted in the meantime with
+// liveTodos first.
+func insertDependencies(ctx context.Context, txn *sql.Tx, todo *Todo) error {
+	for _, blocker := range todo.BlockedBy {
+		_, err := txn.ExecContext(ctx,
+			`INSERT INTO todo_dependency (todo_id, blocker_id) VALUES ($1, $2)`, todo.id, blocker.id)
+		if err != nil {
+			return fmt.Errorf("error restoring blocker '%s' of todo '%s': %w", blocker.Title, todo.Title, err)
+		}
+	}
+
+	for _, dependent := range todo.dependents {
+		_, err := txn.ExecContext(ctx,
+			`INSERT INTO todo_dependency (todo_id, blocker_id) VALUES ($1, $2)`, dependent.id, todo.id)
+		if err != nil {
+			return fmt.Errorf("error restoring todo '%s' as a blocker of '%s': %w", todo.Title, dependent.Title, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_dependencies*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	a := addTodo(assert, database, "a", "")
+	b := addTodo(assert, database, "b", "")
+	c := addTodo(assert, database, "c", "")
+
+	// c is blocked by b, which is blocked by a
+	assert.Nil(database.AddDependency(ctx, b, a))
+	assert.Nil(database.AddDependency(ctx, c, b))
+	assert.Nil(database.AddDependency(ctx, c, b))
+
+	blockedBy := storeTodo(assert, database, c).BlockedBy
+	assert.Equal(1, len(blockedBy))
+	assert.Equal(b.ID(), blockedBy[0].ID())
+
+	assert.ErrorIs(database.AddDependency(ctx, a, a), db.ErrDependencyCycle)
+	assert.ErrorIs(database.AddDependency(ctx, a, b), db.ErrDependencyCycle)
+	assert.ErrorIs(database.AddDependency(ctx, a, c), db.ErrDependencyCycle)
+	assert.ErrorIs(database.RemoveDependency(ctx, a, c), db.ErrDependencyNotFound)
+
+	assert.True(storeTodo(assert, database, b).Blocked())
+	assert.False(storeTodo(assert, database, a).Blocked())
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	c, err = database.TodoByID(ctx, c.ID())
+	assert.Nil(err)
+
+	assert.Equal(1, len(c.BlockedBy))
+	assert.Equal("b", c.BlockedBy[0].Title)
+
+	assert.Nil(database.RemoveDependency(ctx, c, c.BlockedBy[0]))
+	assert.Empty(storeTodo(assert, database, c).BlockedBy)
+
+	events, err := database.History(ctx, c)
+	assert.Nil(err)
+	assert.Equal(db.EventDependencyRemoved, events[len(events)-1].Type)
+	assert.Equal("no longer blocked by #2 'b'", events[len(events)-1].Detail)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(1, len(storeTodo(assert, database, c).BlockedBy))
+
+	assert.Nil(database.Redo(ctx))
+	assert.Empty(storeTodo(assert, database, c).BlockedBy)
+}
+
+func TestChangeStatusBlocked(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	snapshot := database.Snapshot()
+	open := snapshot.Statuses[db.StatusOpen]
+	closed := snapshot.Statuses[db.StatusClosed]
+	done := snapshot.Statuses[db.StatusDone]
+	abandoned := snapshot.Statuses[db.StatusAbandoned]
+
+	todo := addTodo(assert, database, "deploy", "")
+	blocker1 := addTodo(assert, database, "review", "")
+	blocker2 := addTodo(assert, database, "test", "")
+
+	assert.Nil(database.AddDependency(ctx, todo, blocker1))
+	assert.Nil(database.AddDependency(ctx, todo, blocker2))
+
+	// blocked todos can still be started
+	assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+
+	err := database.ChangeStatus(ctx, todo, closed, done)
+	assert.ErrorIs(err, db.ErrBlocked)
+
+	var blockedErr *db.BlockedError
+
+	assert.True(errors.As(err, &blockedErr))
+	assert.Equal(2, len(blockedErr.Blockers))
+	assert.Equal(blocker1.ID(), blockedErr.Blockers[0].ID())
+	assert.Equal(blocker2.ID(), blockedErr.Blockers[1].ID())
+	assert.Equal("the todo is blocked: 'deploy' is waiting on #2 'review', #3 'test'", err.Error())
+	assert.Equal(closed.Name, storeTodo(assert, database, todo).Status.Name)
+
+	assert.Nil(database.ChangeStatus(ctx, blocker1, open, abandoned))
+
+	err = database.ChangeStatus(ctx, todo, closed, done)
+	assert.True(errors.As(err, &blockedErr))
+	assert.Equal(1, len(blockedErr.Blockers))
+	assert.Equal(blocker2.ID(), blockedErr.Blockers[0].ID())
+
+	assert.Nil(database.ChangeStatus(ctx, blocker2, open, closed))
+	assert.Nil(database.ChangeStatus(ctx, blocker2, closed, done))
+
+	assert.False(storeTodo(assert, database, todo).Blocked())
+	assert.Nil(database.ChangeStatus(ctx, todo, closed, done))
+
+	// a todo that is done can't start waiting on one that isn't finished
+	retro := addTodo(assert, database, "retro", "")
+	assert.ErrorIs(database.AddDependency(ctx, todo, retro), db.ErrDoneTodoBlocked)
+	assert.False(storeTodo(assert, database, todo).Blocked())
+
+	assert.Nil(database.ChangeStatus(ctx, retro, open, abandoned))
+	assert.Nil(database.AddDependency(ctx, todo, retro))
+}
+
+func TestDeleteTodoWithDependencies(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	a := addTodo(assert, database, "a", "")
+	b := addTodo(assert, database, "b", "")
+	c := addTodo(assert, database, "c", "")
+
+	assert.Nil(database.AddDependency(ctx, b, a))
+	assert.Nil(database.AddDependency(ctx, c, b))
+
+	assert.Nil(database.DeleteTodo(ctx, b))
+	assert.Empty(storeTodo(assert, database, c).BlockedBy)
+	assert.False(storeTodo(assert, database, c).Blocked())
+
+	assert.Nil(database.Undo(ctx))
+
+	// the copies in a Snapshot refer to each other
+	snapshot := database.Snapshot()
+	a, _ = snapshot.TodoByID(ctx, a.ID())
+	b, _ = snapshot.TodoByID(ctx, b.ID())
+	c, _ = snapshot.TodoByID(ctx, c.ID())
+	assert.Equal([]*db.Todo{a}, b.BlockedBy)
+	assert.Equal([]*db.Todo{b}, c.BlockedBy)
+
+	assert.Nil(database.Redo(ctx))
+	assert.Empty(storeTodo(assert, database, c).BlockedBy)
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DueDateFormat is the layout used to store, parse and show due dates.
+const DueDateFormat = "2006-01-02"
+
+// ErrInvalidDueDate is returned from ParseDueDate when the text isn't a date like 2021-12-31.
+var ErrInvalidDueDate = errors.New("due dates must look like 2021-12-31")
+
+// ParseDueDate parses a due date in DueDateFormat, returning midnight at the start of that day in local time. Empty
+// text means there is no due date and returns nil.
+func ParseDueDate(text string) (*time.Time, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	due, err := time.ParseInLocation(DueDateFormat, text, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w, not '%s'", ErrInvalidDueDate, text)
+	}
+
+	return &due, nil
+}
+
+// FormatDueDate formats a due date with DueDateFormat, or returns an empty string if there is no due date.
+func FormatDueDate(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+
+	return due.Format(DueDateFormat)
+}
+
+// nullDueDate converts a due date to the value stored in the database.
+func nullDueDate(due *time.Time) sql.NullString {
+	return nullString(FormatDueDate(due))
+}
+
+// dueDateFromDB converts a stored due date, which the driver reads as midnight UTC, to midnight local time.
+func dueDateFromDB(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	due := time.Date(value.Time.Year(), value.Time.Month(), value.Time.Day(), 0, 0, 0, 0, time.Local)
+
+	return &due
+}
+
+func sameDueDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+// SetDueDate sets the day the Todo is due; nil removes the due date. Setting the same due date again is a no-op.
+func (d *Database) SetDueDate(ctx context.Context, todo *Todo, due *time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if sameDueDate(todo.DueDate, due) {
+		return nil
+	}
+
+	oldDue := todo.DueDate
+
+	if err = d.setDueDate(ctx, todo, due); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("changing the due date of todo '%s'", todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.setDueDate(ctx, todo, oldDue)
+		},
+		redo: func(ctx context.Context) error {
+			return d.setDueDate(ctx, todo, due)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) setDueDate(ctx context.Context, todo *Todo, due *time.Time) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx, `UPDATE todo SET due_date=$1 WHERE id=$2`, nullDueDate(due), todo.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error updating due date of todo '%s': %w", todo.Title, err))
+	}
+
+	event := &TodoEvent{
+		Type:       EventDueDateChanged,
+		OldDueDate: todo.DueDate,
+		NewDueDate: due,
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.DueDate = due
+	todo.UpdatedDatetime = &event.Datetime
+
+	return nil
+}
+
+// TodosByDueDate returns the Todos with a due date that are still to be done, i.e. aren't done or abandoned, sorted
+// by due date. Todos due on the same day are sorted by status display order and rank.
+func (d *Database) TodosByDueDate() []*Todo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.todoCopies(d.todosByDueDate())
+}
+
+func (m *model) todosByDueDate() []*Todo {
+	todos := []*Todo{}
+
+	for _, todo := range m.todos {
+		if todo.DueDate == nil || todo.Status.Name == StatusDone || todo.Status.Name == StatusAbandoned {
+			continue
+		}
+
+		todos = append(todos, todo)
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+
+		switch {
+		case !a.DueDate.Equal(*b.DueDate):
+			return a.DueDate.Before(*b.DueDate)
+		case a.Status != b.Status:
+			return a.Status.DisplayOrder < b.Status.DisplayOrder
+		}
+
+		return a.Rank < b.Rank
+	})
+
+	return todos
+}
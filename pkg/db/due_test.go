@@ -0,0 +1,112 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDueDate(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	due, err := db.ParseDueDate("")
+	assert.Nil(err)
+	assert.Nil(due)
+
+	due, err = db.ParseDueDate("2021-12-31")
+	assert.Nil(err)
+	assert.Equal(time.Date(2021, time.December, 31, 0, 0, 0, 0, time.Local), *due)
+	assert.Equal("2021-12-31", db.FormatDueDate(due))
+
+	_, err = db.ParseDueDate("31/12/2021")
+	assert.ErrorIs(err, db.ErrInvalidDueDate)
+
+	assert.Equal("", db.FormatDueDate(nil))
+}
+
+func TestSetDueDate(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_due*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	todo := addDefaultTodo(assert, database)
+	assert.Nil(todo.DueDate)
+
+	due, _ := db.ParseDueDate("2021-12-31")
+	assert.Nil(database.SetDueDate(ctx, todo, due))
+	assert.Equal(due, storeTodo(assert, database, todo).DueDate)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(storeTodo(assert, database, todo).DueDate)
+
+	assert.Nil(database.Redo(ctx))
+	assert.Equal(due, storeTodo(assert, database, todo).DueDate)
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+
+	last := events[len(events)-1]
+	assert.Equal(db.EventDueDateChanged, last.Type)
+	assert.Nil(last.OldDueDate)
+	assert.Equal(*due, *last.NewDueDate)
+
+	// deleted todos keep their due date when restored
+	assert.Nil(database.DeleteTodo(ctx, todo))
+	assert.Nil(database.Undo(ctx))
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	assert.Equal(*due, *database.Snapshot().Todos[0].DueDate)
+	assert.ErrorIs(database.SetDueDate(ctx, nil, due), db.ErrNilTodo)
+}
+
+func TestTodosByDueDate(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	later, _ := db.ParseDueDate("2022-01-02")
+	sooner, _ := db.ParseDueDate("2022-01-01")
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	todo3 := addTodo(assert, database, "todo 3", "")
+	todo4 := addTodo(assert, database, "todo 4", "")
+	addTodo(assert, database, "no due date", "")
+
+	assert.Nil(database.SetDueDate(ctx, todo1, later))
+	assert.Nil(database.SetDueDate(ctx, todo2, later))
+	assert.Nil(database.SetDueDate(ctx, todo3, sooner))
+	assert.Nil(database.SetDueDate(ctx, todo4, sooner))
+
+	// todos due on the same day are in status order; finished todos are left out
+	snapshot := database.Snapshot()
+	assert.Nil(database.ChangeStatus(ctx, todo2, todo2.Status, snapshot.Statuses[db.StatusClosed]))
+	assert.Nil(database.ChangeStatus(ctx, todo4, todo4.Status, snapshot.Statuses[db.StatusAbandoned]))
+
+	assert.Equal([]string{"todo 3", "todo 1", "todo 2"}, todoTitles(database.TodosByDueDate()))
+}
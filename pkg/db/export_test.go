@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MigrateTo applies migrations up to the given version to the sqlite database at filename without loading any data,
+// which allows tests to build fixture databases at every historical schema version.
+func MigrateTo(ctx context.Context, filename string, version int) error {
+	conn, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return fmt.Errorf("error connecting to sqlite db at %s: %w", filename, err)
+	}
+
+	defer conn.Close()
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return migrateTo(ctx, conn, migrations, version)
+}
+
+// SetClock replaces the Database's clock so that tests can control timestamps.
+func (d *Database) SetClock(clock func() time.Time) {
+	d.clock = clock
+}
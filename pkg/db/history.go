@@ -0,0 +1,367 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullStatusID(status *Status) sql.NullInt64 {
+	if status == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(status.id), Valid: true}
+}
+
+// nullRank returns a NULL rank unless the event records a position, i.e. has a status.
+func nullRank(rank int, status *Status) sql.NullInt64 {
+	if status == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(rank), Valid: true}
+}
+
+// recordChange stamps the updated_datetime of the given Todo, writes an event to its history and updates its search
+// index entry as part of the given transaction, so that all are only recorded if the change itself is committed. The
+// event time defaults to the current time; callers set todo.UpdatedDatetime to the event time once the transaction is
+// committed.
+func (d *Database) recordChange(ctx context.Context, txn *sql.Tx, todo *Todo, event *TodoEvent, label *Label) error {
+	if event.Datetime.IsZero() {
+		event.Datetime = d.now()
+	}
+
+	_, err := txn.ExecContext(ctx, `UPDATE todo SET updated_datetime=$1 WHERE id=$2`, event.Datetime, todo.id)
+	if err != nil {
+		return fmt.Errorf("error updating timestamp for todo '%s': %w", todo.Title, err)
+	}
+
+	var labelID sql.NullInt64
+	if label != nil {
+		labelID = sql.NullInt64{Int64: int64(label.ID), Valid: true}
+		event.LabelName = label.Name
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`INSERT INTO todo_event (
+			todo_id, event_type, old_status_id, new_status_id, old_rank, new_rank,
+			old_title, new_title, old_description, new_description, label_id, label_name,
+			old_due_date, new_due_date, old_recurrence, new_recurrence, snoozed_until, detail,
+			created_datetime
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`,
+		todo.id,
+		string(event.Type),
+		nullStatusID(event.OldStatus),
+		nullStatusID(event.NewStatus),
+		nullRank(event.OldRank, event.OldStatus),
+		nullRank(event.NewRank, event.NewStatus),
+		nullString(event.OldTitle),
+		nullString(event.NewTitle),
+		nullString(event.OldDescription),
+		nullString(event.NewDescription),
+		labelID,
+		nullString(event.LabelName),
+		nullDueDate(event.OldDueDate),
+		nullDueDate(event.NewDueDate),
+		nullString(event.OldRecurrence),
+		nullString(event.NewRecurrence),
+		nullTime(event.SnoozedUntil),
+		nullString(event.Detail),
+		event.Datetime,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording %s event for todo '%s': %w", event.Type, todo.Title, err)
+	}
+
+	// keep the search index in step with changes to the text and labels of the todo
+	switch event.Type {
+	case EventCreated, EventEdited, EventLabelAdded, EventLabelRemoved, EventDeleted, EventRestored:
+		return d.indexTodo(ctx, txn, todo)
+	}
+
+	return nil
+}
+
+func (m *model) statusByID(id int) *Status {
+	for _, status := range m.statuses {
+		if status.id == id {
+			return status
+		}
+	}
+
+	return nil
+}
+
+// History returns the recorded events for the given Todo, oldest first.
+func (d *Database) History(ctx context.Context, todo *Todo) ([]*TodoEvent, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	historySQL := `SELECT id, event_type, old_status_id, new_status_id, old_rank, new_rank,
+					old_title, new_title, old_description, new_description, label_name,
+					old_due_date, new_due_date, old_recurrence, new_recurrence, snoozed_until,
+					detail, created_datetime
+				FROM todo_event
+				WHERE todo_id = $1
+				ORDER BY id`
+
+	rows, err := d.conn.QueryContext(ctx, historySQL, todo.id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading history for todo '%s': %w", todo.Title, err)
+	}
+
+	defer rows.Close()
+
+	events := []*TodoEvent{}
+
+	for rows.Next() {
+		var (
+			event                          TodoEvent
+			eventType                      string
+			oldStatusID, newStatusID       sql.NullInt64
+			oldRank, newRank               sql.NullInt64
+			oldTitle, newTitle             sql.NullString
+			oldDescription, newDescription sql.NullString
+			labelName                      sql.NullString
+			oldDueDate, newDueDate         sql.NullTime
+			oldRecurrence, newRecurrence   sql.NullString
+			snoozedUntil                   sql.NullTime
+			detail                         sql.NullString
+		)
+
+		err = rows.Scan(
+			&event.ID,
+			&eventType,
+			&oldStatusID,
+			&newStatusID,
+			&oldRank,
+			&newRank,
+			&oldTitle,
+			&newTitle,
+			&oldDescription,
+			&newDescription,
+			&labelName,
+			&oldDueDate,
+			&newDueDate,
+			&oldRecurrence,
+			&newRecurrence,
+			&snoozedUntil,
+			&detail,
+			&event.Datetime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning todo event: %w", err)
+		}
+
+		event.Type = EventType(eventType)
+
+		if oldStatusID.Valid {
+			event.OldStatus = d.statusByID(int(oldStatusID.Int64))
+		}
+
+		if newStatusID.Valid {
+			event.NewStatus = d.statusByID(int(newStatusID.Int64))
+		}
+
+		event.OldRank = int(oldRank.Int64)
+		event.NewRank = int(newRank.Int64)
+		event.OldTitle = oldTitle.String
+		event.NewTitle = newTitle.String
+		event.OldDescription = oldDescription.String
+		event.NewDescription = newDescription.String
+		event.LabelName = labelName.String
+		event.OldDueDate = dueDateFromDB(oldDueDate)
+		event.NewDueDate = dueDateFromDB(newDueDate)
+		event.OldRecurrence = oldRecurrence.String
+		event.NewRecurrence = newRecurrence.String
+		event.Detail = detail.String
+
+		if snoozedUntil.Valid {
+			event.SnoozedUntil = &snoozedUntil.Time
+		}
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todo events: %w", err)
+	}
+
+	return d.eventCopies(events), nil
+}
+
+// TodosCompletedBetween returns the Todos that are done and were moved to done at or after from and before to,
+// most recently completed first.
+func (d *Database) TodosCompletedBetween(ctx context.Context, from, to time.Time) ([]*CompletedTodo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	completed, err := d.todosMovedBetween(ctx, d.statuses[StatusDone], from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.completedCopies(completed), nil
+}
+
+// TodosAbandonedBetween returns the Todos that are abandoned and were moved to abandoned at or after from and before
+// to, most recently abandoned first.
+func (d *Database) TodosAbandonedBetween(ctx context.Context, from, to time.Time) ([]*CompletedTodo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	completed, err := d.todosMovedBetween(ctx, d.statuses[StatusAbandoned], from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.completedCopies(completed), nil
+}
+
+// todosMovedBetween returns the Todos currently in the given status whose most recent move to that status happened
+// within [from, to). Todos that reached the status before history was recorded fall back to their updated time.
+func (d *Database) todosMovedBetween(
+	ctx context.Context, status *Status, from, to time.Time,
+) ([]*CompletedTodo, error) {
+	movedSQL := `SELECT todo_id, created_datetime
+				FROM todo_event
+				WHERE event_type = $1 AND new_status_id = $2
+				ORDER BY id`
+
+	rows, err := d.conn.QueryContext(ctx, movedSQL, string(EventStatusChanged), status.id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading todos moved to %s: %w", status.Name, err)
+	}
+
+	defer rows.Close()
+
+	// later events overwrite earlier ones, leaving the most recent move for each todo
+	moved := map[int]time.Time{}
+
+	for rows.Next() {
+		var (
+			todoID   int
+			datetime time.Time
+		)
+
+		if err = rows.Scan(&todoID, &datetime); err != nil {
+			return nil, fmt.Errorf("error scanning todo event: %w", err)
+		}
+
+		moved[todoID] = datetime
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todo events: %w", err)
+	}
+
+	return completedBetween(status, moved, from, to), nil
+}
+
+// completedBetween returns the Todos in the status that were last moved there within [from, to), given when each
+// Todo was last moved there by id, most recent first. Todos without a recorded move fall back to their updated time.
+func completedBetween(status *Status, moved map[int]time.Time, from, to time.Time) []*CompletedTodo {
+	completed := []*CompletedTodo{}
+
+	for _, todo := range status.Todos {
+		datetime, ok := moved[todo.id]
+		if !ok {
+			if todo.UpdatedDatetime == nil {
+				continue
+			}
+
+			datetime = *todo.UpdatedDatetime
+		}
+
+		if !datetime.Before(from) && datetime.Before(to) {
+			completed = append(completed, &CompletedTodo{Todo: todo, CompletedDatetime: datetime})
+		}
+	}
+
+	sort.SliceStable(completed, func(i, j int) bool {
+		return completed[i].CompletedDatetime.After(completed[j].CompletedDatetime)
+	})
+
+	return completed
+}
+
+// eventRows holds the todo_event rows of a deleted Todo exactly as they were stored.
+type eventRows struct {
+	columns []string
+	values  [][]interface{}
+}
+
+// deleteEvents deletes the history of a Todo that is being deleted, so that no events are left without their todo,
+// and returns it so that restoreEvents can put it back if the deletion is undone.
+func deleteEvents(ctx context.Context, txn *sql.Tx, todo *Todo) (*eventRows, error) {
+	rows, err := txn.QueryContext(ctx, `SELECT * FROM todo_event WHERE todo_id=$1 ORDER BY id`, todo.id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading history for todo '%s': %w", todo.Title, err)
+	}
+
+	defer rows.Close()
+
+	history := &eventRows{}
+
+	if history.columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("error loading history for todo '%s': %w", todo.Title, err)
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(history.columns))
+		pointers := make([]interface{}, len(values))
+
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("error scanning todo event: %w", err)
+		}
+
+		history.values = append(history.values, values)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todo events: %w", err)
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM todo_event WHERE todo_id=$1`, todo.id); err != nil {
+		return nil, fmt.Errorf("error deleting history of todo '%s': %w", todo.Title, err)
+	}
+
+	return history, nil
+}
+
+// restoreEvents reinserts the history deleted by deleteEvents with the original ids.
+func restoreEvents(ctx context.Context, txn *sql.Tx, history *eventRows) error {
+	if history == nil || len(history.values) == 0 {
+		return nil
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO todo_event (%s) VALUES (%s)`,
+		strings.Join(history.columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(history.columns)), ", "),
+	)
+
+	for _, values := range history.values {
+		if _, err := txn.ExecContext(ctx, insertSQL, values...); err != nil {
+			return fmt.Errorf("error restoring todo event: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo1 := addTodo(assert, database, "todo 1", "first")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	err := database.UpdateTodo(ctx, todo1, "todo one", "the first")
+	assert.Nil(err)
+
+	err = database.MoveDown(ctx, todo1)
+	assert.Nil(err)
+
+	err = database.MoveToBottom(ctx, todo1)
+	assert.Nil(err)
+
+	err = database.MoveToTop(ctx, todo1)
+	assert.Nil(err)
+
+	err = database.MoveUp(ctx, todo2)
+	assert.Nil(err)
+
+	snapshot := database.Snapshot()
+	label := snapshot.Labels[0]
+
+	err = database.AddTodoLabel(ctx, todo1, label)
+	assert.Nil(err)
+
+	err = database.RemoveTodoLabel(ctx, todo1, label)
+	assert.Nil(err)
+
+	open := snapshot.Statuses[db.StatusOpen]
+	closed := snapshot.Statuses[db.StatusClosed]
+
+	err = database.ChangeStatus(ctx, todo1, open, closed)
+	assert.Nil(err)
+
+	events, err := database.History(ctx, todo1)
+	assert.Nil(err)
+	assert.Equal(8, len(events))
+
+	assert.Equal(db.EventCreated, events[0].Type)
+	assert.Equal(open.Name, events[0].NewStatus.Name)
+	assert.Equal(0, events[0].NewRank)
+	assert.Equal("todo 1", events[0].NewTitle)
+	assert.Equal("first", events[0].NewDescription)
+
+	assert.Equal(db.EventEdited, events[1].Type)
+	assert.Equal("todo 1", events[1].OldTitle)
+	assert.Equal("todo one", events[1].NewTitle)
+	assert.Equal("first", events[1].OldDescription)
+	assert.Equal("the first", events[1].NewDescription)
+
+	// moving down records the change for the todo that was moved, not the one it swapped with
+	assert.Equal(db.EventReranked, events[2].Type)
+	assert.Equal(0, events[2].OldRank)
+	assert.Equal(1, events[2].NewRank)
+
+	assert.Equal(db.EventReranked, events[3].Type)
+	assert.Equal(1, events[3].OldRank)
+	assert.Equal(2, events[3].NewRank)
+
+	assert.Equal(db.EventReranked, events[4].Type)
+	assert.Equal(2, events[4].OldRank)
+	assert.Equal(0, events[4].NewRank)
+
+	assert.Equal(db.EventLabelAdded, events[5].Type)
+	assert.Equal(label.Name, events[5].LabelName)
+
+	assert.Equal(db.EventLabelRemoved, events[6].Type)
+	assert.Equal(label.Name, events[6].LabelName)
+
+	assert.Equal(db.EventStatusChanged, events[7].Type)
+	assert.Equal(open.Name, events[7].OldStatus.Name)
+	assert.Equal(closed.Name, events[7].NewStatus.Name)
+	assert.Equal(1, events[7].OldRank)
+	assert.Equal(0, events[7].NewRank)
+
+	for idx := 1; idx < len(events); idx++ {
+		assert.False(events[idx].Datetime.Before(events[idx-1].Datetime))
+	}
+
+	events, err = database.History(ctx, todo2)
+	assert.Nil(err)
+	assert.Equal(2, len(events))
+	assert.Equal(db.EventReranked, events[1].Type)
+	assert.Equal(1, events[1].OldRank)
+	assert.Equal(0, events[1].NewRank)
+}
+
+func TestHistoryNotRecordedOnError(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addDefaultTodo(assert, database)
+	snapshot := database.Snapshot()
+
+	err := database.ChangeStatus(ctx, todo, snapshot.Statuses[db.StatusOpen], snapshot.Statuses[db.StatusDone])
+	assert.ErrorIs(err, db.ErrInvalidTodoMove)
+
+	err = database.AddTodoLabel(ctx, todo, snapshot.Labels[0])
+	assert.Nil(err)
+
+	// adding the label again changes nothing, so there is nothing to record
+	err = database.AddTodoLabel(ctx, todo, snapshot.Labels[0])
+	assert.Nil(err)
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+	assert.Equal(2, len(events))
+	assert.Equal(db.EventCreated, events[0].Type)
+	assert.Equal(db.EventLabelAdded, events[1].Type)
+
+	_, err = database.History(ctx, nil)
+	assert.ErrorIs(err, db.ErrNilTodo)
+}
+
+func TestHistoryOfDeletedTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_history*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	defer conn.Close()
+
+	countEvents := func() (int, int) {
+		var total, orphaned int
+
+		err := conn.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(*) FILTER (WHERE todo_id NOT IN (SELECT id FROM todo))
+			FROM todo_event`).Scan(&total, &orphaned)
+		assert.Nil(err)
+
+		return total, orphaned
+	}
+
+	todo := addDefaultTodo(assert, database)
+	addDefaultTodo(assert, database)
+	assert.Nil(database.MoveToBottom(ctx, todo))
+
+	// the history is deleted along with the todo
+	assert.Nil(database.DeleteTodo(ctx, todo))
+
+	total, orphaned := countEvents()
+	assert.Equal(1, total)
+	assert.Equal(0, orphaned)
+
+	// and restored along with it, including the deletion
+	assert.Nil(database.Undo(ctx))
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+
+	types := []db.EventType{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+
+	assert.Equal([]db.EventType{db.EventCreated, db.EventReranked, db.EventDeleted, db.EventRestored}, types)
+	assert.Equal(db.StatusOpen, events[2].OldStatus.Name)
+	assert.Equal(1, events[2].OldRank)
+
+	assert.Nil(database.Redo(ctx))
+
+	total, orphaned = countEvents()
+	assert.Equal(1, total)
+	assert.Equal(0, orphaned)
+}
+
+func TestTodosCompletedBetween(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	clock := newFakeClock(database)
+
+	snapshot := database.Snapshot()
+	open := snapshot.Statuses[db.StatusOpen]
+	closed := snapshot.Statuses[db.StatusClosed]
+	done := snapshot.Statuses[db.StatusDone]
+	abandoned := snapshot.Statuses[db.StatusAbandoned]
+
+	todos := []*db.Todo{}
+
+	for _, title := range []string{"todo 1", "todo 2", "todo 3", "todo 4"} {
+		todo := addTodo(assert, database, title, "")
+		assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+
+		todos = append(todos, todo)
+	}
+
+	day1 := time.Date(2022, time.January, 11, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+
+	clock.current = day1.Add(10 * time.Hour)
+	assert.Nil(database.ChangeStatus(ctx, todos[0], closed, done))
+
+	clock.current = day2.Add(10 * time.Hour)
+	assert.Nil(database.ChangeStatus(ctx, todos[1], closed, done))
+	assert.Nil(database.ChangeStatus(ctx, todos[2], closed, abandoned))
+
+	// moves that are undone don't count
+	clock.current = day2.Add(11 * time.Hour)
+	assert.Nil(database.ChangeStatus(ctx, todos[3], closed, done))
+	assert.Nil(database.Undo(ctx))
+
+	completed, err := database.TodosCompletedBetween(ctx, day1, day2)
+	assert.Nil(err)
+	assert.Equal(1, len(completed))
+	assert.Equal(todos[0].ID(), completed[0].Todo.ID())
+	assert.Equal(done.Name, completed[0].Todo.Status.Name)
+	assert.Equal(day1.Add(10*time.Hour), completed[0].CompletedDatetime)
+
+	completed, err = database.TodosCompletedBetween(ctx, day1, day3)
+	assert.Nil(err)
+	assert.Equal(2, len(completed))
+	assert.Equal(todos[1].ID(), completed[0].Todo.ID())
+	assert.Equal(todos[0].ID(), completed[1].Todo.ID())
+
+	completed, err = database.TodosCompletedBetween(ctx, day3, day3.Add(24*time.Hour))
+	assert.Nil(err)
+	assert.Equal(0, len(completed))
+
+	abandonedTodos, err := database.TodosAbandonedBetween(ctx, day2, day3)
+	assert.Nil(err)
+	assert.Equal(1, len(abandonedTodos))
+	assert.Equal(todos[2].ID(), abandonedTodos[0].Todo.ID())
+}
@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// querier runs queries on either the connection or a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// storedTodo is where a todo is in the database, which may differ from where it is in memory.
+type storedTodo struct {
+	id       int
+	title    string
+	statusID int
+	rank     int
+}
+
+// readStoredTodos returns every todo in the database, ordered by status and rank.
+func readStoredTodos(ctx context.Context, q querier) ([]*storedTodo, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, title, status_id, rank FROM todo ORDER BY status_id, rank, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error loading todos: %w", err)
+	}
+
+	defer rows.Close()
+
+	stored := []*storedTodo{}
+
+	for rows.Next() {
+		var todo storedTodo
+
+		if err = rows.Scan(&todo.id, &todo.title, &todo.statusID, &todo.rank); err != nil {
+			return nil, fmt.Errorf("error scanning todo: %w", err)
+		}
+
+		stored = append(stored, &todo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todos: %w", err)
+	}
+
+	return stored, nil
+}
+
+// CheckIntegrity looks for inconsistencies that the rest of the Database assumes can't happen, and returns a
+// description of each one it finds:
+//
+// - the ranks of the todos in each status must be 0 to n-1, which RepairRanks can fix
+// - every todo_label must refer to an existing todo and label
+// - the in-memory model must match the database
+//
+// Call ReloadIfChanged first if other processes may have changed the database, or their changes show up as
+// differences from the in-memory model.
+func (d *Database) CheckIntegrity(ctx context.Context) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stored, err := readStoredTodos(ctx, d.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := d.checkStoredRanks(stored)
+
+	dangling, err := d.checkTodoLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labelIDs, err := d.readStoredLabelIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	problems = append(problems, dangling...)
+	problems = append(problems, d.checkModel(stored, labelIDs)...)
+
+	return problems, nil
+}
+
+// checkStoredRanks reports todos whose status doesn't exist or whose rank breaks the sequence 0 to n-1 within their
+// status.
+func (d *Database) checkStoredRanks(stored []*storedTodo) []string {
+	problems := []string{}
+	next := map[int]int{}
+
+	for _, todo := range stored {
+		status := d.statusByID(todo.statusID)
+		if status == nil {
+			problems = append(problems, fmt.Sprintf("todo #%d '%s' has unknown status %d", todo.id, todo.title,
+				todo.statusID))
+
+			continue
+		}
+
+		if todo.rank != next[todo.statusID] {
+			problems = append(problems, fmt.Sprintf("todo #%d '%s' has rank %d in %s but should have rank %d",
+				todo.id, todo.title, todo.rank, status.Name, next[todo.statusID]))
+		}
+
+		next[todo.statusID]++
+	}
+
+	return problems
+}
+
+// checkTodoLabels reports todo_labels that refer to a todo or label that doesn't exist.
+func (d *Database) checkTodoLabels(ctx context.Context) ([]string, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT todo_label.id, todo_label.todo_id, todo_label.label_id, todo.id IS NULL, label.id IS NULL
+		FROM todo_label
+			LEFT JOIN todo ON todo.id = todo_label.todo_id
+			LEFT JOIN label ON label.id = todo_label.label_id
+		WHERE todo.id IS NULL OR label.id IS NULL
+		ORDER BY todo_label.id`)
+	if err != nil {
+		return nil, fmt.Errorf("error loading todo labels: %w", err)
+	}
+
+	defer rows.Close()
+
+	problems := []string{}
+
+	for rows.Next() {
+		var (
+			id, todoID, labelID       int
+			missingTodo, missingLabel bool
+		)
+
+		if err = rows.Scan(&id, &todoID, &labelID, &missingTodo, &missingLabel); err != nil {
+			return nil, fmt.Errorf("error scanning todo label: %w", err)
+		}
+
+		if missingTodo {
+			problems = append(problems, fmt.Sprintf("todo_label %d refers to todo #%d, which doesn't exist", id, todoID))
+		}
+
+		if missingLabel {
+			problems = append(problems, fmt.Sprintf("todo_label %d refers to label %d, which doesn't exist", id, labelID))
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todo labels: %w", err)
+	}
+
+	return problems, nil
+}
+
+// readStoredLabelIDs returns the ids of the existing labels of each todo in the database, by the id of the todo.
+func (d *Database) readStoredLabelIDs(ctx context.Context) (map[int][]int, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT todo_label.todo_id, todo_label.label_id
+		FROM todo_label JOIN label ON label.id = todo_label.label_id
+		ORDER BY todo_label.todo_id, todo_label.label_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error loading todo labels: %w", err)
+	}
+
+	defer rows.Close()
+
+	labelIDs := map[int][]int{}
+
+	for rows.Next() {
+		var todoID, labelID int
+
+		if err = rows.Scan(&todoID, &labelID); err != nil {
+			return nil, fmt.Errorf("error scanning todo label: %w", err)
+		}
+
+		labelIDs[todoID] = append(labelIDs[todoID], labelID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning todo labels: %w", err)
+	}
+
+	return labelIDs, nil
+}
+
+// checkModel reports differences between the in-memory model and the todos and labels in the database.
+func (d *Database) checkModel(stored []*storedTodo, labelIDs map[int][]int) []string {
+	problems := []string{}
+
+	byID := make(map[int]*storedTodo, len(stored))
+	for _, todo := range stored {
+		byID[todo.id] = todo
+	}
+
+	seen := map[int]bool{}
+
+	for _, status := range orderStatuses(d.statuses) {
+		for _, todo := range status.Todos {
+			seen[todo.id] = true
+
+			dbTodo, ok := byID[todo.id]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("todo #%d '%s' is in memory but not in the database",
+					todo.id, todo.Title))
+
+				continue
+			}
+
+			if todo.Status != status || dbTodo.statusID != status.id || dbTodo.rank != todo.Rank {
+				problems = append(problems, fmt.Sprintf(
+					"todo #%d '%s' is in %s at rank %d in memory but in %s at rank %d in the database",
+					todo.id, todo.Title, status.Name, todo.Rank, d.statusName(dbTodo.statusID), dbTodo.rank,
+				))
+			}
+
+			if memoryIDs := labelIDsOf(todo); !sameIDs(memoryIDs, labelIDs[todo.id]) {
+				problems = append(problems, fmt.Sprintf("todo #%d '%s' has labels %v in memory but %v in the database",
+					todo.id, todo.Title, memoryIDs, labelIDs[todo.id]))
+			}
+		}
+	}
+
+	for _, todo := range stored {
+		// todos with an unknown status are never loaded and have already been reported
+		if !seen[todo.id] && d.statusByID(todo.statusID) != nil {
+			problems = append(problems, fmt.Sprintf("todo #%d '%s' is in the database but not in memory",
+				todo.id, todo.title))
+		}
+	}
+
+	return problems
+}
+
+// statusName returns the name of the status with the given id, or the id if there is no such status.
+func (d *Database) statusName(id int) string {
+	if status := d.statusByID(id); status != nil {
+		return status.Name
+	}
+
+	return fmt.Sprintf("unknown status %d", id)
+}
+
+// labelIDsOf returns the sorted ids of the Todo's labels.
+func labelIDsOf(todo *Todo) []int {
+	ids := make([]int, 0, len(todo.Labels))
+	for _, label := range todo.Labels {
+		ids = append(ids, label.ID)
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RepairRanks renumbers the todos in each status 0 to n-1 in one transaction, keeping their order, and returns how
+// many of them changed rank. It then reloads the in-memory model so that it matches the database, so like Reload it
+// leaves the Statuses, Labels and Todos from before stale and clears the undo history.
+func (d *Database) RepairRanks(ctx context.Context) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	stored, err := readStoredTodos(ctx, txn)
+	if err != nil {
+		return 0, rollbackOnError(txn, err)
+	}
+
+	changed := map[int]int{}
+	next := map[int]int{}
+
+	for _, todo := range stored {
+		if rank := next[todo.statusID]; todo.rank != rank {
+			changed[todo.id] = rank
+		}
+
+		next[todo.statusID]++
+	}
+
+	// move the changed todos out of the way first so that intermediate states don't violate the unique index on
+	// status_id + rank; every todo with a negative rank is changed, so -1 - id is free
+	for id := range changed {
+		if _, err = txn.ExecContext(ctx, `UPDATE todo SET rank=-1 - id WHERE id=$1`, id); err != nil {
+			return 0, rollbackOnError(txn, fmt.Errorf("error temp updating todo: %w", err))
+		}
+	}
+
+	for id, rank := range changed {
+		if _, err = txn.ExecContext(ctx, updateRankSQL, rank, id); err != nil {
+			return 0, rollbackOnError(txn, fmt.Errorf("error updating todo: %w", err))
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	if err = d.reload(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(changed), nil
+}
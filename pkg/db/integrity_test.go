@@ -0,0 +1,103 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todos := storeTodos(assert, database, "a", "b", "c")
+	label, err := database.LabelByName(ctx, "urgent")
+	assert.Nil(err)
+
+	assert.Nil(database.AddTodoLabel(ctx, todos[1], label))
+	assert.Nil(database.ChangeStatus(ctx, todos[0], todos[0].Status, database.Snapshot().Statuses[db.StatusClosed]))
+	assert.Nil(database.MoveToTop(ctx, todos[2]))
+	assert.Nil(database.DeleteTodo(ctx, todos[1]))
+	assert.Nil(database.Undo(ctx))
+
+	problems, err := database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Empty(problems)
+}
+
+func TestRepairRanks(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_integrity*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	storeTodos(assert, database, "first", "second", "third")
+	database.Close()
+
+	// leave a gap in the ranks of the open list and a todo_label without a label, as an older version or a script
+	// might have
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `UPDATE todo SET rank=5 WHERE title='third'`)
+	assert.Nil(err)
+	_, err = conn.ExecContext(ctx, `INSERT INTO todo_label (todo_id, label_id) VALUES (1, 99)`)
+	assert.Nil(err)
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	problems, err := database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"todo #3 'third' has rank 5 in open but should have rank 2",
+		"todo_label 1 refers to label 99, which doesn't exist",
+	}, problems)
+
+	// changes that bypass the Database make the in-memory model differ from the database
+	_, err = conn.ExecContext(ctx, `UPDATE todo SET rank=7 WHERE title='third'`)
+	assert.Nil(err)
+
+	problems, err = database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Contains(problems, "todo #3 'third' is in open at rank 5 in memory but in open at rank 7 in the database")
+
+	changed, err := database.RepairRanks(ctx)
+	assert.Nil(err)
+	assert.Equal(1, changed)
+
+	open := database.Snapshot().Statuses[db.StatusOpen]
+	assert.Equal([]string{"first", "second", "third"}, statusTitles(open))
+	assertRanks(assert, database.Snapshot().Statuses)
+
+	// only the problem that RepairRanks doesn't fix remains
+	problems, err = database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Equal([]string{"todo_label 1 refers to label 99, which doesn't exist"}, problems)
+
+	changed, err = database.RepairRanks(ctx)
+	assert.Nil(err)
+	assert.Equal(0, changed)
+
+	fourth := addTodo(assert, database, "fourth", "")
+	assert.Equal(3, fourth.Rank)
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrInvalidColor is returned from SetLabelColor when the color isn't a hex color like "#FF0000".
+	ErrInvalidColor = errors.New("colors must be hex colors like #FF0000")
+	// ErrMergeLabelIntoItself is returned from MergeLabels when both Labels are the same.
+	ErrMergeLabelIntoItself = errors.New("cannot merge a label into itself")
+	// ErrLabelExists is returned from NewLabel and UpdateLabel when another Label already has the name.
+	ErrLabelExists = errors.New("a label with that name already exists")
+
+	colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+)
+
+// LabelColors returns the palette of colors that labels are shown in by default, and that users can choose from.
+func LabelColors() []string {
+	return []string{
+		"#FF0000",
+		"#00FF00",
+		"#0000FF",
+		"#FFFF00",
+		"#FF00FF",
+		"#00FFFF",
+		"#FFFFFF",
+		"#AA0000",
+		"#00AA00",
+		"#0000AA",
+		"#AAAA00",
+		"#AA00AA",
+		"#00AAAA",
+		"#AAAAAA",
+	}
+}
+
+// defaultLabelColor returns the color for a Label that hasn't been given one, alternating through the palette so
+// that todos with common labels are easier to spot.
+func defaultLabelColor(id int) string {
+	colors := LabelColors()
+
+	return colors[id%len(colors)]
+}
+
+// checkLabelName returns ErrLabelExists if a Label other than the given one, which may be nil, has the name.
+func (m *model) checkLabelName(label *Label, name string) error {
+	if other, err := m.labelByName(name); err == nil && other != label {
+		return fmt.Errorf("%w: '%s'", ErrLabelExists, name)
+	}
+
+	return nil
+}
+
+// SetLabelColor sets the color the Label is shown in.
+func (d *Database) SetLabelColor(ctx context.Context, label *Label, color string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	label, err := d.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidColor, color)
+	}
+
+	_, err = d.conn.ExecContext(ctx, `UPDATE label SET color=$1 WHERE id=$2`, color, label.ID)
+	if err != nil {
+		return fmt.Errorf("error updating color of label '%s': %w", label.Name, err)
+	}
+
+	label.Color = color
+
+	return nil
+}
+
+// AllLabels returns every Label, in the order they were loaded or created.
+func (d *Database) AllLabels() []*Label {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return labelCopies(d.labels)
+}
+
+// TodosWithLabel returns the Todos that have the given Label, in the order they were loaded or created.
+func (d *Database) TodosWithLabel(label *Label) []*Todo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.todoCopies(d.todosWithLabel(label))
+}
+
+func (m *model) todosWithLabel(label *Label) []*Todo {
+	todos := []*Todo{}
+
+	for _, todo := range m.todos {
+		if labelIndex(todo.Labels, label) >= 0 {
+			todos = append(todos, todo)
+		}
+	}
+
+	return todos
+}
+
+// MergeLabels replaces the Label from with the Label into on every Todo that has it, and then deletes from. Todos
+// that already have both simply lose from.
+func (d *Database) MergeLabels(ctx context.Context, from, into *Label) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from, err := d.liveLabel(from)
+	if err != nil {
+		return err
+	}
+
+	if into, err = d.liveLabel(into); err != nil {
+		return err
+	}
+
+	if from.ID == into.ID {
+		return ErrMergeLabelIntoItself
+	}
+
+	idx := labelIndex(d.labels, from)
+
+	positions, added, err := d.mergeLabels(ctx, from, into)
+	if err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("merging label '%s' into '%s'", from.Name, into.Name),
+		undo: func(ctx context.Context) error {
+			if err := d.restoreLabel(ctx, from, idx, positions); err != nil {
+				return err
+			}
+
+			for _, todo := range added {
+				if err := d.removeTodoLabel(ctx, todo, into); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		redo: func(ctx context.Context) error {
+			_, _, err := d.mergeLabels(ctx, from, into)
+
+			return err
+		},
+	})
+
+	return nil
+}
+
+// mergeLabels moves the Todos with the Label from to the Label into and deletes from in a single transaction. It
+// returns the position of from in the labels of each affected Todo, and the Todos that into was added to.
+func (d *Database) mergeLabels(ctx context.Context, from, into *Label) (map[*Todo]int, []*Todo, error) {
+	positions := map[*Todo]int{}
+	added := []*Todo{}
+
+	for _, todo := range d.todosWithLabel(from) {
+		positions[todo] = labelIndex(todo.Labels, from)
+
+		if labelIndex(todo.Labels, into) < 0 {
+			added = append(added, todo)
+		}
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	events := map[*Todo]*TodoEvent{}
+
+	for _, todo := range added {
+		_, err = txn.ExecContext(ctx, `INSERT INTO todo_label (todo_id, label_id) VALUES ($1, $2)`, todo.id, into.ID)
+		if err != nil {
+			return nil, nil, rollbackOnError(txn,
+				fmt.Errorf("error adding label '%s' to todo '%s': %w", into.Name, todo.Title, err))
+		}
+
+		if err = d.recordChange(ctx, txn, todo, &TodoEvent{Type: EventLabelAdded}, into); err != nil {
+			return nil, nil, rollbackOnError(txn, err)
+		}
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM todo_label WHERE label_id=$1`, from.ID); err != nil {
+		return nil, nil, rollbackOnError(txn, fmt.Errorf("error removing label '%s' from todos: %w", from.Name, err))
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM label WHERE id=$1`, from.ID); err != nil {
+		return nil, nil, rollbackOnError(txn, fmt.Errorf("error deleting label '%s': %w", from.Name, err))
+	}
+
+	for todo := range positions {
+		events[todo] = &TodoEvent{Type: EventLabelRemoved}
+		if err = d.recordChange(ctx, txn, todo, events[todo], from); err != nil {
+			return nil, nil, rollbackOnError(txn, err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	for todo, pos := range positions {
+		if labelIndex(todo.Labels, into) < 0 {
+			todo.Labels[pos] = into
+		} else {
+			todo.Labels = append(todo.Labels[:pos], todo.Labels[pos+1:]...)
+		}
+
+		todo.UpdatedDatetime = &events[todo].Datetime
+	}
+
+	if idx := labelIndex(d.labels, from); idx >= 0 {
+		d.labels = append(d.labels[:idx], d.labels[idx+1:]...)
+	}
+
+	return positions, added, nil
+}
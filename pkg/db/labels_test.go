@@ -0,0 +1,129 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelColor(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_labels*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	// labels have a default color from the palette until one is chosen
+	label := database.AllLabels()[0]
+	assert.Contains(db.LabelColors(), label.Color)
+
+	created, err := database.NewLabel(ctx, "busywork")
+	assert.Nil(err)
+	assert.Contains(db.LabelColors(), created.Color)
+
+	assert.ErrorIs(database.SetLabelColor(ctx, label, "red"), db.ErrInvalidColor)
+	assert.Nil(database.SetLabelColor(ctx, label, "#123abc"))
+	assert.Equal("#123abc", database.AllLabels()[0].Color)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	labels := database.AllLabels()
+	assert.Equal("#123abc", labels[0].Color)
+	assert.Equal(created.Color, labels[len(labels)-1].Color)
+}
+
+func TestTodosWithLabel(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	addTodo(assert, database, "todo 2", "")
+	todo3 := addTodo(assert, database, "todo 3", "")
+
+	labels := database.AllLabels()
+	assert.Nil(database.AddTodoLabel(ctx, todo3, labels[0]))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, labels[0]))
+
+	assert.Equal([]string{todo1.Title, todo3.Title}, todoTitles(database.TodosWithLabel(labels[0])))
+	assert.Equal([]*db.Todo{}, database.TodosWithLabel(labels[1]))
+}
+
+func TestMergeLabels(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_labels*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	labels := database.AllLabels()
+	task, learning, urgent := labels[0], labels[1], labels[3]
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+
+	// todo 1 has only the label being merged; todo 2 has both
+	assert.Nil(database.AddTodoLabel(ctx, todo1, urgent))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, learning))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, task))
+	assert.Nil(database.AddTodoLabel(ctx, todo2, learning))
+	assert.Nil(database.AddTodoLabel(ctx, todo2, task))
+
+	assert.ErrorIs(database.MergeLabels(ctx, task, task), db.ErrMergeLabelIntoItself)
+
+	assert.Nil(database.MergeLabels(ctx, learning, task))
+
+	assert.Equal([]*db.Label{urgent, task}, storeTodo(assert, database, todo1).Labels)
+	assert.Equal([]*db.Label{task}, storeTodo(assert, database, todo2).Labels)
+	assert.Equal(8, len(database.AllLabels()))
+
+	_, err = database.LabelByName(ctx, "learning")
+	assert.ErrorIs(err, db.ErrLabelNotFound)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal([]*db.Label{urgent, learning, task}, storeTodo(assert, database, todo1).Labels)
+	assert.Equal([]*db.Label{learning, task}, storeTodo(assert, database, todo2).Labels)
+	assert.Equal(learning, database.AllLabels()[1])
+
+	assert.Nil(database.Redo(ctx))
+	assert.Equal([]*db.Label{urgent, task}, storeTodo(assert, database, todo1).Labels)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot := database.Snapshot()
+	open := snapshot.Statuses[db.StatusOpen].Todos
+	assert.Equal(2, len(open[0].Labels))
+	assert.Equal(1, len(open[1].Labels))
+	assert.Equal("task", open[1].Labels[0].Name)
+	assert.Equal(8, len(snapshot.Labels))
+}
@@ -0,0 +1,1269 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps everything in memory and persists nothing, e.g. as a fast fake in tests. It starts
+// out like a new Database, with the same statuses, transitions and labels, and enforces the same rules. Like a
+// Database, its methods may be called from any goroutine and return copies.
+type MemoryStore struct {
+	clock func() time.Time
+	// mu guards the model like the mutex of a Database.
+	mu sync.RWMutex
+
+	model
+
+	// lastTodoID, lastLabelID, lastItemID and lastEventID are the ids most recently given out, which are never reused.
+	lastTodoID  int
+	lastLabelID int
+	lastItemID  int
+	lastEventID int
+	// history holds the events recorded for each Todo, oldest first, by the id of the Todo.
+	history map[int][]*TodoEvent
+}
+
+// NewMemoryStore returns an empty MemoryStore with the default statuses and labels.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		clock:   time.Now,
+		model:   newModel(),
+		history: map[int][]*TodoEvent{},
+	}
+
+	// these match the statuses, transitions and labels that the migrations create
+	statuses := []*Status{
+		{Name: StatusOpen, Key: "o", Color: "#00AAAA"},
+		{Name: StatusClosed, Key: "c", Color: "#FFFF00", WIPLimit: DefaultMaxClosedTodos},
+		{Name: StatusOnHold, Key: "h", Color: "#AAAAAA"},
+		{Name: StatusDone, Key: "d", Color: "#00FF00"},
+		{Name: StatusAbandoned, Key: "a", Color: "#AA0000"},
+	}
+
+	for i, status := range statuses {
+		status.id = i + 1
+		status.DisplayOrder = i + 1
+		status.Todos = []*Todo{}
+		s.statuses[status.Name] = status
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			forbidden := (from.Name == StatusClosed && to.Name == StatusOpen) ||
+				((from.Name == StatusOpen || from.Name == StatusOnHold) && to.Name == StatusDone)
+
+			if from != to && !forbidden {
+				s.allowTransition(from.id, to.id, true)
+			}
+		}
+	}
+
+	for _, name := range []string{
+		"task", "learning", "human_interaction", "urgent", "platform_learning", "personal_growth",
+		"environment_setup", "planning/design", "onboarding",
+	} {
+		s.lastLabelID++
+		s.labels = append(s.labels, &Label{ID: s.lastLabelID, Name: name, Color: defaultLabelColor(s.lastLabelID)})
+	}
+
+	return s
+}
+
+// now returns the current time according to the MemoryStore's clock.
+func (s *MemoryStore) now() time.Time {
+	return s.clock()
+}
+
+// record adds an event to the history of the Todo and stamps its UpdatedDatetime, like Database.recordChange.
+func (s *MemoryStore) record(todo *Todo, event *TodoEvent, label *Label) {
+	if event.Datetime.IsZero() {
+		event.Datetime = s.now()
+	}
+
+	if label != nil {
+		event.LabelName = label.Name
+	}
+
+	s.lastEventID++
+	event.ID = s.lastEventID
+	s.history[todo.id] = append(s.history[todo.id], event)
+
+	todo.UpdatedDatetime = &event.Datetime
+}
+
+// pushChange records an operation that was just performed, like Database.pushUndo. Nothing in a MemoryStore can
+// fail, so undo and redo only apply their changes.
+func (s *MemoryStore) pushChange(description string, undo, redo func()) {
+	s.pushUndo(&operation{
+		description: description,
+		undo: func(context.Context) error {
+			undo()
+
+			return nil
+		},
+		redo: func(context.Context) error {
+			redo()
+
+			return nil
+		},
+	})
+}
+
+// pushMove records the undo operation for a change of status or rank, after the change has been applied.
+func (s *MemoryStore) pushMove(description string, todo *Todo, old placement) {
+	newPlacement := placementOf(todo)
+
+	s.pushChange(description,
+		func() { s.placeTodo(todo, old) },
+		func() { s.placeTodo(todo, newPlacement) },
+	)
+}
+
+// placeTodo moves a Todo to the given placement without validating the move, like Database.placeTodo.
+func (s *MemoryStore) placeTodo(todo *Todo, to placement) {
+	status, rank := to.status, to.rank
+	oldStatus := todo.Status
+	orders := map[*Status][]*Todo{}
+
+	target := without(oldStatus.Todos, todo)
+	if status != oldStatus {
+		orders[oldStatus] = target
+		target = status.Todos
+	}
+
+	if rank < 0 || rank > len(target) {
+		rank = len(target)
+	}
+
+	orders[status] = insertAt(target, todo, rank)
+
+	event := &TodoEvent{
+		Type:      EventReranked,
+		OldStatus: oldStatus,
+		NewStatus: status,
+		OldRank:   todo.Rank,
+		NewRank:   rank,
+	}
+	if status != oldStatus {
+		event.Type = EventStatusChanged
+	}
+
+	s.record(todo, event, nil)
+
+	applyOrder(orders)
+
+	todo.SnoozedUntil = to.snoozedUntil
+	todo.Woken = to.woken
+}
+
+// Snapshot returns a copy of the in-memory model.
+func (s *MemoryStore) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot()
+}
+
+// OrderedStatuses returns all statuses in display order.
+func (s *MemoryStore) OrderedStatuses() []*Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot().OrderedStatuses()
+}
+
+// StatusByName returns the status with the given name.
+func (s *MemoryStore) StatusByName(_ context.Context, name string) (*Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, err := s.statusByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.statusCopy(status), nil
+}
+
+// StatusLimit returns the maximum number of Todos allowed in the status, or 0 if there is no limit.
+func (s *MemoryStore) StatusLimit(status *Status) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, err := s.liveStatus(status)
+	if err != nil {
+		return 0
+	}
+
+	return s.statusLimit(status)
+}
+
+// MaxClosedTodos returns the maximum number of todos allowed in the closed list.
+func (s *MemoryStore) MaxClosedTodos() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.statusLimit(s.statuses[StatusClosed])
+}
+
+// MaxClosedTodosOverridden is always false: a MemoryStore has no settings to override.
+func (s *MemoryStore) MaxClosedTodosOverridden() bool {
+	return false
+}
+
+// SetMaxClosedTodos sets the maximum number of todos allowed in the closed list.
+func (s *MemoryStore) SetMaxClosedTodos(_ context.Context, limit int) error {
+	if _, err := parseMaxClosedTodos(strconv.Itoa(limit)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses[StatusClosed].WIPLimit = limit
+
+	return nil
+}
+
+// AllLabels returns every Label, in the order they were created.
+func (s *MemoryStore) AllLabels() []*Label {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return labelCopies(s.labels)
+}
+
+// LabelByName returns the Label with the given name.
+func (s *MemoryStore) LabelByName(_ context.Context, name string) (*Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	label, err := s.labelByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return labelCopies([]*Label{label})[0], nil
+}
+
+// NewLabel creates a new label with the given name.
+func (s *MemoryStore) NewLabel(_ context.Context, name string) (*Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkLabelName(nil, name); err != nil {
+		return nil, err
+	}
+
+	s.lastLabelID++
+	label := &Label{ID: s.lastLabelID, Name: name, Color: defaultLabelColor(s.lastLabelID)}
+	s.labels = append(s.labels, label)
+
+	return labelCopies([]*Label{label})[0], nil
+}
+
+// UpdateLabel updates the label name.
+func (s *MemoryStore) UpdateLabel(_ context.Context, label *Label, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label, err := s.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	if err = s.checkLabelName(label, name); err != nil {
+		return err
+	}
+
+	label.Name = name
+
+	return nil
+}
+
+// SetLabelColor sets the color the Label is shown in.
+func (s *MemoryStore) SetLabelColor(_ context.Context, label *Label, color string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label, err := s.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidColor, color)
+	}
+
+	label.Color = color
+
+	return nil
+}
+
+// DeleteLabel deletes a Label and removes it from every Todo that has it.
+func (s *MemoryStore) DeleteLabel(_ context.Context, label *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label, err := s.liveLabel(label)
+	if err != nil {
+		return err
+	}
+
+	idx := labelIndex(s.labels, label)
+	positions := s.deleteLabel(label)
+
+	s.pushChange(fmt.Sprintf("deleting label '%s'", label.Name),
+		func() { s.restoreLabel(label, idx, positions) },
+		func() { s.deleteLabel(label) },
+	)
+
+	return nil
+}
+
+// deleteLabel deletes a Label and removes it from every Todo that has it, like Database.deleteLabel. It returns the
+// position of the Label in the labels of each affected Todo so that it can be restored with restoreLabel.
+func (s *MemoryStore) deleteLabel(label *Label) map[*Todo]int {
+	positions := map[*Todo]int{}
+
+	for _, todo := range s.todosWithLabel(label) {
+		idx := labelIndex(todo.Labels, label)
+		positions[todo] = idx
+
+		todo.Labels = append(todo.Labels[:idx:idx], todo.Labels[idx+1:]...)
+		s.record(todo, &TodoEvent{Type: EventLabelRemoved}, label)
+	}
+
+	if idx := labelIndex(s.labels, label); idx >= 0 {
+		s.labels = append(s.labels[:idx:idx], s.labels[idx+1:]...)
+	}
+
+	return positions
+}
+
+// restoreLabel adds a Label removed by deleteLabel back at the given position in the list of labels, and to each
+// Todo at the given position.
+func (s *MemoryStore) restoreLabel(label *Label, idx int, positions map[*Todo]int) {
+	for _, todo := range s.todos {
+		if pos, ok := positions[todo]; ok {
+			s.addTodoLabel(todo, label, pos)
+		}
+	}
+
+	if idx < 0 || idx > len(s.labels) {
+		idx = len(s.labels)
+	}
+
+	labels := make([]*Label, 0, len(s.labels)+1)
+	labels = append(labels, s.labels[:idx]...)
+	labels = append(labels, label)
+	s.labels = append(labels, s.labels[idx:]...)
+}
+
+// MergeLabels replaces the Label from with the Label into on every Todo that has it, and then deletes from. Todos
+// that already have both simply lose from.
+func (s *MemoryStore) MergeLabels(_ context.Context, from, into *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, err := s.liveLabel(from)
+	if err != nil {
+		return err
+	}
+
+	if into, err = s.liveLabel(into); err != nil {
+		return err
+	}
+
+	if from.ID == into.ID {
+		return ErrMergeLabelIntoItself
+	}
+
+	idx := labelIndex(s.labels, from)
+	positions, added := s.mergeLabels(from, into)
+
+	s.pushChange(fmt.Sprintf("merging label '%s' into '%s'", from.Name, into.Name),
+		func() {
+			s.restoreLabel(from, idx, positions)
+
+			for _, todo := range added {
+				s.removeTodoLabel(todo, into)
+			}
+		},
+		func() { s.mergeLabels(from, into) },
+	)
+
+	return nil
+}
+
+// mergeLabels moves the Todos with the Label from to the Label into and deletes from, like Database.mergeLabels. It
+// returns the position of from in the labels of each affected Todo, and the Todos that into was added to.
+func (s *MemoryStore) mergeLabels(from, into *Label) (map[*Todo]int, []*Todo) {
+	positions := map[*Todo]int{}
+	added := []*Todo{}
+
+	for _, todo := range s.todosWithLabel(from) {
+		pos := labelIndex(todo.Labels, from)
+		positions[todo] = pos
+
+		if labelIndex(todo.Labels, into) < 0 {
+			added = append(added, todo)
+			todo.Labels = append(append(todo.Labels[:pos:pos], into), todo.Labels[pos+1:]...)
+			s.record(todo, &TodoEvent{Type: EventLabelAdded}, into)
+		} else {
+			todo.Labels = append(todo.Labels[:pos:pos], todo.Labels[pos+1:]...)
+		}
+
+		s.record(todo, &TodoEvent{Type: EventLabelRemoved}, from)
+	}
+
+	if idx := labelIndex(s.labels, from); idx >= 0 {
+		s.labels = append(s.labels[:idx:idx], s.labels[idx+1:]...)
+	}
+
+	return positions, added
+}
+
+// TodosWithLabel returns the Todos that have the given Label, in the order they were created.
+func (s *MemoryStore) TodosWithLabel(label *Label) []*Todo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.todoCopies(s.todosWithLabel(label))
+}
+
+// TodoByID returns the Todo with the given id.
+func (s *MemoryStore) TodoByID(_ context.Context, id int) (*Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, err := s.todoByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.todoCopy(todo), nil
+}
+
+// NewTodo creates a new Todo at the end of the open list.
+func (s *MemoryStore) NewTodo(_ context.Context, title, description string) (*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(title) == 0 {
+		return nil, ErrEmptyTitle
+	}
+
+	todo := &Todo{Title: title, Description: description, Labels: []*Label{}}
+	open := s.statuses[StatusOpen]
+
+	s.addTodo(todo, open)
+
+	rank := todo.Rank
+
+	s.pushChange(fmt.Sprintf("creating todo '%s'", title),
+		func() { s.deleteTodo(todo) },
+		func() { s.restoreTodo(todo, open, rank) },
+	)
+
+	return s.todoCopy(todo), nil
+}
+
+// addTodo gives the Todo an id, adds it to the end of the status and records its creation.
+func (s *MemoryStore) addTodo(todo *Todo, status *Status) {
+	now := s.now()
+
+	s.lastTodoID++
+	todo.id = s.lastTodoID
+	todo.Status = status
+	todo.Rank = len(status.Todos)
+	todo.CreatedDatetime = &now
+
+	status.Todos = append(status.Todos, todo)
+	s.todos = append(s.todos, todo)
+
+	s.record(todo, &TodoEvent{
+		Type:           EventCreated,
+		NewStatus:      status,
+		NewRank:        todo.Rank,
+		NewTitle:       todo.Title,
+		NewDescription: todo.Description,
+		NewDueDate:     todo.DueDate,
+		NewRecurrence:  FormatRecurrence(todo.Recurrence),
+		Datetime:       now,
+	}, nil)
+}
+
+// UpdateTodo updates the Todo with the given title and description.
+func (s *MemoryStore) UpdateTodo(_ context.Context, todo *Todo, title, description string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if len(title) == 0 {
+		return ErrEmptyTitle
+	}
+
+	oldTitle, oldDescription := todo.Title, todo.Description
+
+	s.updateTodo(todo, title, description)
+
+	s.pushChange(fmt.Sprintf("editing todo '%s'", title),
+		func() { s.updateTodo(todo, oldTitle, oldDescription) },
+		func() { s.updateTodo(todo, title, description) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) updateTodo(todo *Todo, title, description string) {
+	s.record(todo, &TodoEvent{
+		Type:           EventEdited,
+		OldTitle:       todo.Title,
+		NewTitle:       title,
+		OldDescription: todo.Description,
+		NewDescription: description,
+	}, nil)
+
+	todo.Title = title
+	todo.Description = description
+}
+
+// DeleteTodo deletes a Todo and moves up the Todos below it in its status.
+func (s *MemoryStore) DeleteTodo(_ context.Context, todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	status, rank := todo.Status, todo.Rank
+
+	s.deleteTodo(todo)
+
+	s.pushChange(fmt.Sprintf("deleting todo '%s'", todo.Title),
+		func() { s.restoreTodo(todo, status, rank) },
+		func() { s.deleteTodo(todo) },
+	)
+
+	return nil
+}
+
+// deleteTodo removes a Todo and compacts the ranks of the remaining Todos in its status. The Todo object is left
+// intact so that it can be restored with restoreTodo.
+func (s *MemoryStore) deleteTodo(todo *Todo) {
+	s.record(todo, &TodoEvent{Type: EventDeleted, OldStatus: todo.Status, OldRank: todo.Rank}, nil)
+
+	todo.dependents = s.dependentsOf(todo)
+	for _, dependent := range todo.dependents {
+		dependent.BlockedBy = without(dependent.BlockedBy, todo)
+	}
+
+	applyOrder(map[*Status][]*Todo{todo.Status: without(todo.Status.Todos, todo)})
+	s.todos = without(s.todos, todo)
+}
+
+// restoreTodo adds a Todo removed by deleteTodo back at the given rank in the given status.
+func (s *MemoryStore) restoreTodo(todo *Todo, status *Status, rank int) {
+	todo.BlockedBy = s.liveTodos(todo.BlockedBy)
+	todo.dependents = s.liveTodos(todo.dependents)
+
+	applyOrder(map[*Status][]*Todo{status: insertAt(status.Todos, todo, rank)})
+	s.todos = append(s.todos, todo)
+
+	for _, dependent := range todo.dependents {
+		dependent.BlockedBy = append(dependent.BlockedBy, todo)
+	}
+
+	todo.dependents = nil
+
+	s.record(todo, &TodoEvent{Type: EventRestored, NewStatus: status, NewRank: todo.Rank}, nil)
+}
+
+// ChangeStatus moves a Todo from one status to another. Completing a recurring Todo adds its next occurrence to the
+// open list.
+func (s *MemoryStore) ChangeStatus(_ context.Context, todo *Todo, oldStatus, newStatus *Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, oldStatus, newStatus, err := s.liveStatusChange(todo, oldStatus, newStatus)
+	if err != nil {
+		return err
+	}
+
+	if err = s.validateStatusChange(todo, oldStatus, newStatus); err != nil {
+		return err
+	}
+
+	old := placementOf(todo)
+
+	s.record(todo, &TodoEvent{
+		Type:      EventStatusChanged,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		OldRank:   old.rank,
+		NewRank:   len(newStatus.Todos),
+	}, nil)
+
+	s.localStatusChange(todo, oldStatus, newStatus)
+
+	description := fmt.Sprintf("moving todo '%s' to %s", todo.Title, newStatus.Name)
+
+	if newStatus.Name != StatusDone || todo.Recurrence == nil {
+		s.pushMove(description, todo, old)
+
+		return nil
+	}
+
+	s.pushRecur(description, todo, s.recur(todo), old)
+
+	return nil
+}
+
+// pushRecur records the undo operation for completing a recurring Todo, which both moves the Todo and creates the
+// next occurrence.
+func (s *MemoryStore) pushRecur(description string, todo, next *Todo, old placement) {
+	newPlacement := placementOf(todo)
+	nextStatus, nextRank := next.Status, next.Rank
+
+	s.pushChange(description,
+		func() {
+			s.deleteTodo(next)
+			todo.Recurrence = next.Recurrence
+			s.placeTodo(todo, old)
+		},
+		func() {
+			s.placeTodo(todo, newPlacement)
+			s.restoreTodo(next, nextStatus, nextRank)
+			todo.Recurrence = nil
+		},
+	)
+}
+
+// recur adds the next occurrence of a recurring Todo that was just completed to the end of the open list, like
+// Database.recur, and returns it.
+func (s *MemoryStore) recur(todo *Todo) *Todo {
+	due := todo.Recurrence.Next(todo.DueDate, s.now())
+
+	next := &Todo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Labels:      append([]*Label{}, todo.Labels...),
+		DueDate:     &due,
+		Recurrence:  todo.Recurrence,
+	}
+
+	for _, item := range todo.Checklist {
+		s.lastItemID++
+		next.Checklist = append(next.Checklist, &ChecklistItem{id: s.lastItemID, Text: item.Text})
+	}
+
+	s.addTodo(next, s.statuses[StatusOpen])
+
+	todo.Recurrence = nil
+
+	return next
+}
+
+// MoveUp moves a Todo one position up in its status.
+func (s *MemoryStore) MoveUp(_ context.Context, todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if todo.Rank == 0 {
+		return ErrCantMoveFirstTodoUp
+	}
+
+	s.rerank(fmt.Sprintf("moving todo '%s' up", todo.Title), todo, todo.Rank-1)
+
+	return nil
+}
+
+// MoveDown moves a Todo one position down in its status.
+func (s *MemoryStore) MoveDown(_ context.Context, todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if todo.Rank >= len(todo.Status.Todos)-1 {
+		return ErrCantMoveLastTodoDown
+	}
+
+	s.rerank(fmt.Sprintf("moving todo '%s' down", todo.Title), todo, todo.Rank+1)
+
+	return nil
+}
+
+// MoveToTop moves a Todo to the top of its status.
+func (s *MemoryStore) MoveToTop(_ context.Context, todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if todo.Rank == 0 {
+		return ErrCantMoveFirstTodoUp
+	}
+
+	s.rerank(fmt.Sprintf("moving todo '%s' to the top", todo.Title), todo, 0)
+
+	return nil
+}
+
+// MoveToBottom moves a Todo to the bottom of its status.
+func (s *MemoryStore) MoveToBottom(_ context.Context, todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if todo.Rank >= len(todo.Status.Todos)-1 {
+		return ErrCantMoveLastTodoDown
+	}
+
+	s.rerank(fmt.Sprintf("moving todo '%s' to the bottom", todo.Title), todo, len(todo.Status.Todos)-1)
+
+	return nil
+}
+
+// MoveToRank moves a Todo to the given rank in its status.
+func (s *MemoryStore) MoveToRank(_ context.Context, todo *Todo, rank int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if rank < 0 || rank >= len(todo.Status.Todos) {
+		return fmt.Errorf("%w: %s has no rank %d", ErrInvalidTodoMove, todo.Status.Name, rank)
+	}
+
+	if rank != todo.Rank {
+		s.rerank(fmt.Sprintf("moving todo '%s' to rank %d", todo.Title, rank), todo, rank)
+	}
+
+	return nil
+}
+
+// rerank moves the Todo to the given rank within its status, shifting the Todos in between.
+func (s *MemoryStore) rerank(description string, todo *Todo, rank int) {
+	old := placementOf(todo)
+	to := old
+	to.rank = rank
+
+	s.placeTodo(todo, to)
+	s.pushMove(description, todo, old)
+}
+
+// AddTodoLabel adds a Label to a Todo. Adding a Label that the Todo already has does nothing.
+func (s *MemoryStore) AddTodoLabel(_ context.Context, todo *Todo, label *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, label, err := s.liveTodoLabel(todo, label)
+	if err != nil {
+		return err
+	}
+
+	if labelIndex(todo.Labels, label) >= 0 {
+		return nil
+	}
+
+	idx := len(todo.Labels)
+
+	s.addTodoLabel(todo, label, idx)
+
+	s.pushChange(fmt.Sprintf("adding label '%s' to todo '%s'", label.Name, todo.Title),
+		func() { s.removeTodoLabel(todo, label) },
+		func() { s.addTodoLabel(todo, label, idx) },
+	)
+
+	return nil
+}
+
+// addTodoLabel adds a Label to a Todo at the given position in its list of labels.
+func (s *MemoryStore) addTodoLabel(todo *Todo, label *Label, idx int) {
+	labels := make([]*Label, 0, len(todo.Labels)+1)
+	labels = append(labels, todo.Labels[:idx]...)
+	labels = append(labels, label)
+	todo.Labels = append(labels, todo.Labels[idx:]...)
+
+	s.record(todo, &TodoEvent{Type: EventLabelAdded}, label)
+}
+
+// RemoveTodoLabel removes a Label from a Todo. Removing a Label that the Todo doesn't have does nothing.
+func (s *MemoryStore) RemoveTodoLabel(_ context.Context, todo *Todo, label *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, label, err := s.liveTodoLabel(todo, label)
+	if err != nil {
+		return err
+	}
+
+	idx := labelIndex(todo.Labels, label)
+	if idx < 0 {
+		return nil
+	}
+
+	s.removeTodoLabel(todo, label)
+
+	s.pushChange(fmt.Sprintf("removing label '%s' from todo '%s'", label.Name, todo.Title),
+		func() { s.addTodoLabel(todo, label, idx) },
+		func() { s.removeTodoLabel(todo, label) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) removeTodoLabel(todo *Todo, label *Label) {
+	if idx := labelIndex(todo.Labels, label); idx >= 0 {
+		todo.Labels = append(todo.Labels[:idx:idx], todo.Labels[idx+1:]...)
+	}
+
+	s.record(todo, &TodoEvent{Type: EventLabelRemoved}, label)
+}
+
+// SetDueDate sets the day the Todo is due; nil removes the due date.
+func (s *MemoryStore) SetDueDate(_ context.Context, todo *Todo, due *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if sameDueDate(todo.DueDate, due) {
+		return nil
+	}
+
+	oldDue := todo.DueDate
+
+	s.setDueDate(todo, due)
+
+	s.pushChange(fmt.Sprintf("changing the due date of todo '%s'", todo.Title),
+		func() { s.setDueDate(todo, oldDue) },
+		func() { s.setDueDate(todo, due) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) setDueDate(todo *Todo, due *time.Time) {
+	s.record(todo, &TodoEvent{Type: EventDueDateChanged, OldDueDate: todo.DueDate, NewDueDate: due}, nil)
+	todo.DueDate = due
+}
+
+// SetRecurrence sets the rule for creating the next occurrence of the Todo when it is done; nil stops it recurring.
+func (s *MemoryStore) SetRecurrence(_ context.Context, todo *Todo, recurrence *Recurrence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if FormatRecurrence(todo.Recurrence) == FormatRecurrence(recurrence) {
+		return nil
+	}
+
+	oldRecurrence := todo.Recurrence
+
+	s.setRecurrence(todo, recurrence)
+
+	s.pushChange(fmt.Sprintf("changing the recurrence of todo '%s'", todo.Title),
+		func() { s.setRecurrence(todo, oldRecurrence) },
+		func() { s.setRecurrence(todo, recurrence) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) setRecurrence(todo *Todo, recurrence *Recurrence) {
+	s.record(todo, &TodoEvent{
+		Type:          EventRecurrenceChanged,
+		OldRecurrence: FormatRecurrence(todo.Recurrence),
+		NewRecurrence: FormatRecurrence(recurrence),
+	}, nil)
+
+	todo.Recurrence = recurrence
+}
+
+// Snooze moves the Todo to the end of the on_hold list until the given time, when WakeSnoozed returns it to the top
+// of the open list. Snoozing a Todo that is already on hold only changes when it wakes up.
+func (s *MemoryStore) Snooze(_ context.Context, todo *Todo, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if !until.After(s.now()) {
+		return ErrSnoozeInPast
+	}
+
+	onHold := s.statuses[StatusOnHold]
+	oldStatus, oldRank, oldUntil := todo.Status, todo.Rank, todo.SnoozedUntil
+	rank := todo.Rank
+
+	if todo.Status != onHold {
+		if err := s.validateStatusChange(todo, todo.Status, onHold); err != nil {
+			return err
+		}
+
+		rank = len(onHold.Todos)
+	}
+
+	s.setSnooze(todo, onHold, rank, &until)
+
+	s.pushChange(fmt.Sprintf("snoozing todo '%s'", todo.Title),
+		func() { s.setSnooze(todo, oldStatus, oldRank, oldUntil) },
+		func() { s.setSnooze(todo, onHold, rank, &until) },
+	)
+
+	return nil
+}
+
+// setSnooze moves the Todo to the given rank in the given status and sets when it wakes up, without validating the
+// move, like Database.setSnooze.
+func (s *MemoryStore) setSnooze(todo *Todo, status *Status, rank int, until *time.Time) {
+	oldStatus := todo.Status
+	orders := map[*Status][]*Todo{}
+
+	target := without(oldStatus.Todos, todo)
+	if status != oldStatus {
+		orders[oldStatus] = target
+		target = status.Todos
+	}
+
+	orders[status] = insertAt(target, todo, rank)
+
+	event := &TodoEvent{
+		Type:         EventSnoozed,
+		OldStatus:    oldStatus,
+		NewStatus:    status,
+		OldRank:      todo.Rank,
+		NewRank:      rank,
+		SnoozedUntil: until,
+	}
+
+	if until == nil {
+		event.Type = EventStatusChanged
+	}
+
+	s.record(todo, event, nil)
+
+	applyOrder(orders)
+
+	todo.SnoozedUntil = until
+	todo.Woken = false
+}
+
+// WakeSnoozed moves every snoozed Todo whose wake-up time has passed from on_hold to the top of the open list, in
+// the order they woke up, and flags them as Woken. It returns the Todos that woke up; this can't be undone.
+func (s *MemoryStore) WakeSnoozed(_ context.Context) ([]*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	onHold, open := s.statuses[StatusOnHold], s.statuses[StatusOpen]
+	now := s.now()
+
+	woken := []*Todo{}
+
+	for _, todo := range onHold.Todos {
+		if todo.SnoozedUntil != nil && !todo.SnoozedUntil.After(now) {
+			woken = append(woken, todo)
+		}
+	}
+
+	sort.SliceStable(woken, func(i, j int) bool {
+		return woken[i].SnoozedUntil.Before(*woken[j].SnoozedUntil)
+	})
+
+	remaining := onHold.Todos
+	for _, todo := range woken {
+		remaining = without(remaining, todo)
+	}
+
+	for rank, todo := range woken {
+		s.record(todo, &TodoEvent{
+			Type:      EventWoken,
+			OldStatus: onHold,
+			NewStatus: open,
+			OldRank:   todo.Rank,
+			NewRank:   rank,
+			Datetime:  now,
+		}, nil)
+
+		todo.SnoozedUntil = nil
+		todo.Woken = true
+	}
+
+	applyOrder(map[*Status][]*Todo{
+		onHold: remaining,
+		open:   append(append([]*Todo{}, woken...), open.Todos...),
+	})
+
+	return s.todoCopies(woken), nil
+}
+
+// AddChecklistItem adds an unchecked item with the given text to the end of the Todo's checklist.
+func (s *MemoryStore) AddChecklistItem(_ context.Context, todo *Todo, text string) (*ChecklistItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, ErrEmptyChecklistItem
+	}
+
+	s.lastItemID++
+	item := &ChecklistItem{id: s.lastItemID, Text: text}
+
+	idx := len(todo.Checklist)
+
+	s.restoreChecklistItem(todo, item, idx)
+
+	s.pushChange(fmt.Sprintf("adding '%s' to the checklist of todo '%s'", text, todo.Title),
+		func() { s.deleteChecklistItem(todo, item) },
+		func() { s.restoreChecklistItem(todo, item, idx) },
+	)
+
+	itemCopy := *item
+
+	return &itemCopy, nil
+}
+
+// restoreChecklistItem inserts the item at the given position in the Todo's checklist.
+func (s *MemoryStore) restoreChecklistItem(todo *Todo, item *ChecklistItem, idx int) {
+	todo.Checklist = append(append(append([]*ChecklistItem{}, todo.Checklist[:idx]...), item), todo.Checklist[idx:]...)
+	s.record(todo, &TodoEvent{Type: EventChecklistChanged, Detail: fmt.Sprintf("added '%s'", item.Text)}, nil)
+}
+
+// SetChecklistItemDone checks or unchecks the item in the Todo's checklist.
+func (s *MemoryStore) SetChecklistItemDone(_ context.Context, todo *Todo, item *ChecklistItem, done bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, item, err := s.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	if item.Done == done {
+		return nil
+	}
+
+	s.setChecklistItemDone(todo, item, done)
+
+	s.pushChange(fmt.Sprintf("checking '%s' in the checklist of todo '%s'", item.Text, todo.Title),
+		func() { s.setChecklistItemDone(todo, item, !done) },
+		func() { s.setChecklistItemDone(todo, item, done) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) setChecklistItemDone(todo *Todo, item *ChecklistItem, done bool) {
+	detail := fmt.Sprintf("checked '%s'", item.Text)
+	if !done {
+		detail = fmt.Sprintf("unchecked '%s'", item.Text)
+	}
+
+	item.Done = done
+	s.record(todo, &TodoEvent{Type: EventChecklistChanged, Detail: detail}, nil)
+}
+
+// MoveChecklistItem moves the item to the given position in the Todo's checklist, which starts at 0. Positions past
+// the end of the checklist move the item to the end.
+func (s *MemoryStore) MoveChecklistItem(_ context.Context, todo *Todo, item *ChecklistItem, idx int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, item, err := s.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	oldIdx := checklistIndex(todo.Checklist, item)
+
+	if idx < 0 || idx >= len(todo.Checklist) {
+		idx = len(todo.Checklist) - 1
+	}
+
+	if idx == oldIdx {
+		return nil
+	}
+
+	s.moveChecklistItem(todo, item, idx)
+
+	s.pushChange(fmt.Sprintf("moving '%s' in the checklist of todo '%s'", item.Text, todo.Title),
+		func() { s.moveChecklistItem(todo, item, oldIdx) },
+		func() { s.moveChecklistItem(todo, item, idx) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) moveChecklistItem(todo *Todo, item *ChecklistItem, idx int) {
+	oldIdx := checklistIndex(todo.Checklist, item)
+	items := append(append([]*ChecklistItem{}, todo.Checklist[:oldIdx]...), todo.Checklist[oldIdx+1:]...)
+	todo.Checklist = append(items[:idx], append([]*ChecklistItem{item}, items[idx:]...)...)
+
+	s.record(todo, &TodoEvent{
+		Type:   EventChecklistChanged,
+		Detail: fmt.Sprintf("moved '%s' to position %d", item.Text, idx+1),
+	}, nil)
+}
+
+// DeleteChecklistItem removes the item from the Todo's checklist.
+func (s *MemoryStore) DeleteChecklistItem(_ context.Context, todo *Todo, item *ChecklistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, item, err := s.liveChecklistItem(todo, item)
+	if err != nil {
+		return err
+	}
+
+	idx := checklistIndex(todo.Checklist, item)
+
+	s.deleteChecklistItem(todo, item)
+
+	s.pushChange(fmt.Sprintf("deleting '%s' from the checklist of todo '%s'", item.Text, todo.Title),
+		func() { s.restoreChecklistItem(todo, item, idx) },
+		func() { s.deleteChecklistItem(todo, item) },
+	)
+
+	return nil
+}
+
+func (s *MemoryStore) deleteChecklistItem(todo *Todo, item *ChecklistItem) {
+	idx := checklistIndex(todo.Checklist, item)
+	todo.Checklist = append(todo.Checklist[:idx:idx], todo.Checklist[idx+1:]...)
+	s.record(todo, &TodoEvent{Type: EventChecklistChanged, Detail: fmt.Sprintf("deleted '%s'", item.Text)}, nil)
+}
+
+// TodosByDueDate returns the Todos with a due date that are still to be done, sorted by due date.
+func (s *MemoryStore) TodosByDueDate() []*Todo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.todoCopies(s.todosByDueDate())
+}
+
+// Search returns the Todos in any status whose title, description or labels have a word starting with each word of
+// the query, best matches first, as Database does without full-text search.
+func (s *MemoryStore) Search(_ context.Context, query string) ([]*Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []*Todo{}, nil
+	}
+
+	return s.todoCopies(s.searchInMemory(terms)), nil
+}
+
+// History returns the recorded events for the given Todo, oldest first.
+func (s *MemoryStore) History(_ context.Context, todo *Todo) ([]*TodoEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, err := s.liveTodo(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.eventCopies(s.history[todo.id]), nil
+}
+
+// TodosCompletedBetween returns the Todos that are done and were moved to done at or after from and before to,
+// most recently completed first.
+func (s *MemoryStore) TodosCompletedBetween(_ context.Context, from, to time.Time) ([]*CompletedTodo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.completedCopies(s.todosMovedBetween(s.statuses[StatusDone], from, to)), nil
+}
+
+// TodosAbandonedBetween returns the Todos that are abandoned and were moved to abandoned at or after from and before
+// to, most recently abandoned first.
+func (s *MemoryStore) TodosAbandonedBetween(_ context.Context, from, to time.Time) ([]*CompletedTodo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.completedCopies(s.todosMovedBetween(s.statuses[StatusAbandoned], from, to)), nil
+}
+
+func (s *MemoryStore) todosMovedBetween(status *Status, from, to time.Time) []*CompletedTodo {
+	moved := map[int]time.Time{}
+
+	for id, events := range s.history {
+		for _, event := range events {
+			if event.Type == EventStatusChanged && event.NewStatus == status {
+				moved[id] = event.Datetime
+			}
+		}
+	}
+
+	return completedBetween(status, moved, from, to)
+}
+
+// Undo reverts the most recent operation that hasn't already been undone.
+func (s *MemoryStore) Undo(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.undo(ctx)
+}
+
+// Redo reapplies the most recently undone operation.
+func (s *MemoryStore) Redo(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.redo(ctx)
+}
+
+// ReloadIfChanged never reloads, since nothing else can change a MemoryStore.
+func (s *MemoryStore) ReloadIfChanged(_ context.Context) (bool, error) {
+	return false, nil
+}
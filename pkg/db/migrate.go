@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles contains the ordered schema migrations. Each file is named NNNN_description.sql, where NNNN is the
+// schema version that the file upgrades the database to. Migrations must never be edited once released; add a new
+// file instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const migrationDir = "migrations"
+
+var (
+	// ErrDatabaseTooNew is returned from NewDatabase when the database was last written by a newer version of the app
+	// that applied migrations this binary doesn't know about.
+	ErrDatabaseTooNew = errors.New("the database schema is newer than this version of the app supports")
+	// ErrInvalidMigration is returned when the embedded migration files are misnamed or out of sequence.
+	ErrInvalidMigration = errors.New("invalid migration")
+)
+
+// migration is a single versioned schema change.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations reads the embedded migration files in version order and verifies that versions start at 1 and
+// have no gaps.
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir(migrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	migrations := []migration{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		idx := strings.Index(name, "_")
+		if idx < 1 {
+			return nil, fmt.Errorf("%w: file name '%s' has no version prefix", ErrInvalidMigration, name)
+		}
+
+		version, err := strconv.Atoi(name[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("%w: file name '%s' has no version prefix", ErrInvalidMigration, name)
+		}
+
+		contents, err := migrationFiles.ReadFile(path.Join(migrationDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, migration{version: version, name: name, sql: string(contents)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	for idx, m := range migrations {
+		if m.version != idx+1 {
+			return nil, fmt.Errorf("%w: expected version %d but found %s", ErrInvalidMigration, idx+1, m.name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// LatestSchemaVersion returns the schema version that this version of the app migrates databases to.
+func LatestSchemaVersion() int {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0
+	}
+
+	return len(migrations)
+}
+
+// SchemaVersion returns the schema version of the connected database.
+func (d *Database) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+
+	err := d.conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// migrate upgrades the database to the latest schema version.
+func (d *Database) migrate(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return migrateTo(ctx, d.conn, migrations, len(migrations))
+}
+
+// migrateTo applies all migrations up to and including the target version in a single transaction, so a failed
+// migration leaves the database untouched. Databases created before versioning was introduced have no
+// schema_version table and are treated as version 0; the first migration is idempotent so that it is safe to apply
+// on top of them.
+func migrateTo(ctx context.Context, conn *sql.DB, migrations []migration, target int) error {
+	txn, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening migration transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied_datetime DATETIME NOT NULL
+		)`)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error creating schema_version table: %w", err))
+	}
+
+	var current int
+
+	err = txn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error reading schema version: %w", err))
+	}
+
+	if current > len(migrations) {
+		return rollbackOnError(txn, fmt.Errorf(
+			"%w (database version %d, latest known version %d)", ErrDatabaseTooNew, current, len(migrations),
+		))
+	}
+
+	for _, m := range migrations[current:target] {
+		if _, err = txn.ExecContext(ctx, m.sql); err != nil {
+			return rollbackOnError(txn, fmt.Errorf("error running migration %s: %w", m.name, err))
+		}
+
+		_, err = txn.ExecContext(ctx,
+			`INSERT INTO schema_version (version, applied_datetime) VALUES ($1, $2)`,
+			m.version, time.Now(),
+		)
+		if err != nil {
+			return rollbackOnError(txn, fmt.Errorf("error recording migration %s: %w", m.name, err))
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing migrations: %w", err)
+	}
+
+	return nil
+}
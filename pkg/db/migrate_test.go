@@ -0,0 +1,168 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func execFile(assert *assert.Assertions, filename, sqlFile string) {
+	contents, err := os.ReadFile(sqlFile)
+	assert.Nil(err)
+
+	conn, err := sql.Open("sqlite3", filename)
+	assert.Nil(err)
+
+	defer conn.Close()
+
+	_, err = conn.Exec(string(contents))
+	assert.Nil(err)
+}
+
+// assertFixtureData confirms that the contents of testdata/fixture_data.sql survived the upgrade.
+func assertFixtureData(assert *assert.Assertions, database *db.Database) {
+	assert.Equal(4, len(database.Snapshot().Todos))
+	assert.Equal(10, len(database.Snapshot().Labels))
+
+	open := database.Snapshot().Statuses[db.StatusOpen].Todos
+	assert.Equal(2, len(open))
+	assert.Equal("fixture open 1", open[0].Title)
+	assert.Equal("the first open todo", open[0].Description)
+	assert.Equal("fixture open 2", open[1].Title)
+	assert.Equal(1, open[1].Rank)
+
+	assert.Equal(2, len(open[0].Labels))
+	assert.Equal("task", open[0].Labels[0].Name)
+	assert.Equal("fixture_label", open[0].Labels[1].Name)
+
+	closed := database.Snapshot().Statuses[db.StatusClosed].Todos
+	assert.Equal(1, len(closed))
+	assert.Equal("fixture closed", closed[0].Title)
+	assert.Equal("urgent", closed[0].Labels[0].Name)
+
+	assert.Equal(1, len(database.Snapshot().Statuses[db.StatusDone].Todos))
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_migrate_legacy*")
+	assert.Nil(err)
+
+	// databases created before schema versioning have the original tables but no schema_version table
+	execFile(assert, tempFile.Name(), "testdata/legacy_base.sql")
+	execFile(assert, tempFile.Name(), "testdata/fixture_data.sql")
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	version, err := database.SchemaVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(db.LatestSchemaVersion(), version)
+
+	assertFixtureData(assert, database)
+
+	// legacy databases never set updated_datetime, so it is backfilled from created_datetime
+	for _, todo := range database.Snapshot().Todos {
+		assert.NotNil(todo.UpdatedDatetime, todo.Title)
+		assert.False(todo.UpdatedDatetime.Before(*todo.CreatedDatetime), todo.Title)
+	}
+}
+
+func TestMigrateFromEveryVersion(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	assert.Greater(db.LatestSchemaVersion(), 0)
+
+	for version := 1; version <= db.LatestSchemaVersion(); version++ {
+		tempFile, err := os.CreateTemp("/tmp", fmt.Sprintf("test_migrate_v%d_*", version))
+		assert.Nil(err)
+
+		err = db.MigrateTo(ctx, tempFile.Name(), version)
+		assert.Nil(err, "version %d", version)
+
+		execFile(assert, tempFile.Name(), "testdata/fixture_data.sql")
+
+		database, err := db.NewDatabase(ctx, tempFile.Name())
+		assert.Nil(err, "version %d", version)
+
+		current, err := database.SchemaVersion(ctx)
+		assert.Nil(err)
+		assert.Equal(db.LatestSchemaVersion(), current, "version %d", version)
+
+		assertFixtureData(assert, database)
+
+		database.Close()
+	}
+}
+
+func TestMigrateIdempotent(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_migrate_idempotent*")
+	assert.Nil(err)
+
+	err = db.MigrateTo(ctx, tempFile.Name(), db.LatestSchemaVersion())
+	assert.Nil(err)
+
+	// migrating to the version the database is already at is a no-op
+	err = db.MigrateTo(ctx, tempFile.Name(), db.LatestSchemaVersion())
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	version, err := database.SchemaVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(db.LatestSchemaVersion(), version)
+}
+
+func TestNewDatabaseTooNew(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_migrate_too_new*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+	database.Close()
+
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	_, err = conn.Exec(
+		`INSERT INTO schema_version (version, applied_datetime) VALUES ($1, CURRENT_TIMESTAMP)`,
+		db.LatestSchemaVersion()+1,
+	)
+	assert.Nil(err)
+	conn.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(database)
+	assert.ErrorIs(err, db.ErrDatabaseTooNew)
+}
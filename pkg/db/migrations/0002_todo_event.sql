@@ -0,0 +1,22 @@
+CREATE TABLE todo_event (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id INTEGER NOT NULL,
+	event_type VARCHAR(20) NOT NULL,
+	old_status_id SMALLINT,
+	new_status_id SMALLINT,
+	old_rank INT,
+	new_rank INT,
+	old_title VARCHAR(255),
+	new_title VARCHAR(255),
+	old_description VARCHAR(1023),
+	new_description VARCHAR(1023),
+	label_id INTEGER,
+	label_name VARCHAR(20),
+	created_datetime DATETIME NOT NULL,
+	FOREIGN KEY (todo_id) REFERENCES todo(id),
+	FOREIGN KEY (old_status_id) REFERENCES status(id),
+	FOREIGN KEY (new_status_id) REFERENCES status(id)
+);
+
+CREATE INDEX idx_todo_event_todo_id
+	ON todo_event (todo_id, id);
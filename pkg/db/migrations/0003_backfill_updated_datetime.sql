@@ -0,0 +1,2 @@
+-- updated_datetime was not maintained before this version; treat untouched todos as last updated when created.
+UPDATE todo SET updated_datetime = created_datetime WHERE updated_datetime IS NULL;
@@ -0,0 +1,2 @@
+-- Labels without a color are shown in a default color chosen by id; see defaultLabelColor.
+ALTER TABLE label ADD COLUMN color VARCHAR(7);
@@ -0,0 +1,5 @@
+-- Per-database settings, stored as text and parsed by the app. Missing settings take their default values.
+CREATE TABLE settings (
+	key VARCHAR(50) PRIMARY KEY,
+	value VARCHAR(255) NOT NULL
+);
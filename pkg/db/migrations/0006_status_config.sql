@@ -0,0 +1,38 @@
+-- Statuses are configurable: each has a display order, a key that shows it in the UI, a color, and an optional limit
+-- on the number of todos (0 for no limit). The closed list limit moves here from the settings table.
+ALTER TABLE status ADD COLUMN display_order INT NOT NULL DEFAULT 0;
+ALTER TABLE status ADD COLUMN key VARCHAR(1);
+ALTER TABLE status ADD COLUMN color VARCHAR(7) NOT NULL DEFAULT '#FFFFFF';
+ALTER TABLE status ADD COLUMN wip_limit INT NOT NULL DEFAULT 0;
+
+UPDATE status SET display_order = 1, key = 'o', color = '#00AAAA' WHERE name = 'open';
+UPDATE status SET display_order = 2, key = 'c', color = '#FFFF00' WHERE name = 'closed';
+UPDATE status SET display_order = 3, key = 'h', color = '#AAAAAA' WHERE name = 'on_hold';
+UPDATE status SET display_order = 4, key = 'd', color = '#00FF00' WHERE name = 'done';
+UPDATE status SET display_order = 5, key = 'a', color = '#AA0000' WHERE name = 'abandoned';
+
+UPDATE status SET wip_limit = COALESCE(
+	(SELECT CAST(value AS INTEGER) FROM settings WHERE key = 'max_closed_todos'),
+	5
+) WHERE name = 'closed';
+
+DELETE FROM settings WHERE key = 'max_closed_todos';
+
+CREATE UNIQUE INDEX unq_status_key ON status (key);
+
+-- Each row allows todos to move from one status to another.
+CREATE TABLE status_transition (
+	from_status_id SMALLINT NOT NULL,
+	to_status_id SMALLINT NOT NULL,
+	PRIMARY KEY (from_status_id, to_status_id),
+	FOREIGN KEY (from_status_id) REFERENCES status(id),
+	FOREIGN KEY (to_status_id) REFERENCES status(id)
+);
+
+-- Allow every move except the ones that were previously forbidden: closed todos can't go back to open, and open or
+-- on hold todos can't be done without being closed first.
+INSERT INTO status_transition (from_status_id, to_status_id)
+	SELECT f.id, t.id FROM status f, status t
+	WHERE f.id != t.id
+		AND NOT (f.name = 'closed' AND t.name = 'open')
+		AND NOT (f.name IN ('open', 'on_hold') AND t.name = 'done');
@@ -0,0 +1,5 @@
+-- due dates are whole days, stored as YYYY-MM-DD
+ALTER TABLE todo ADD COLUMN due_date DATE;
+
+ALTER TABLE todo_event ADD COLUMN old_due_date DATE;
+ALTER TABLE todo_event ADD COLUMN new_due_date DATE;
@@ -0,0 +1,5 @@
+-- recurrence rules are stored in the text form accepted by ParseRecurrence, e.g. "weekly mon,thu"
+ALTER TABLE todo ADD COLUMN recurrence VARCHAR(50);
+
+ALTER TABLE todo_event ADD COLUMN old_recurrence VARCHAR(50);
+ALTER TABLE todo_event ADD COLUMN new_recurrence VARCHAR(50);
@@ -0,0 +1,5 @@
+-- snoozed todos wait in on_hold until snoozed_until, then return to open with woken set until they next move
+ALTER TABLE todo ADD COLUMN snoozed_until DATETIME;
+ALTER TABLE todo ADD COLUMN woken BOOLEAN NOT NULL DEFAULT 0;
+
+ALTER TABLE todo_event ADD COLUMN snoozed_until DATETIME;
@@ -0,0 +1,14 @@
+CREATE TABLE checklist_item (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id INTEGER NOT NULL,
+	text VARCHAR(255) NOT NULL,
+	done BOOLEAN NOT NULL DEFAULT 0,
+	rank INT NOT NULL,
+	FOREIGN KEY (todo_id) REFERENCES todo(id)
+);
+
+CREATE INDEX idx_checklist_item_todo_id
+	ON checklist_item (todo_id, rank);
+
+-- detail describes changes that don't have columns of their own, e.g. checklist changes
+ALTER TABLE todo_event ADD COLUMN detail VARCHAR(1023);
@@ -0,0 +1,12 @@
+-- todo_id is blocked by blocker_id: it can't be done until the blocker is done or abandoned
+CREATE TABLE todo_dependency (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id INTEGER NOT NULL,
+	blocker_id INTEGER NOT NULL,
+	FOREIGN KEY (todo_id) REFERENCES todo(id),
+	FOREIGN KEY (blocker_id) REFERENCES todo(id),
+	UNIQUE(todo_id, blocker_id)
+);
+
+CREATE INDEX idx_todo_dependency_blocker_id
+	ON todo_dependency (blocker_id);
@@ -0,0 +1,2 @@
+-- deleting a todo used to leave its events behind; they can't be restored after a restart, so remove them
+DELETE FROM todo_event WHERE todo_id NOT IN (SELECT id FROM todo);
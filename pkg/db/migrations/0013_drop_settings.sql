@@ -0,0 +1,2 @@
+-- the settings table only ever held max_closed_todos, which 0006 moved to the wip_limit of the closed status
+DROP TABLE settings;
@@ -22,20 +22,118 @@ type Todo struct {
 	// highest rank in that list.
 	Rank   int
 	Status *Status
-	// TODO (medium): populate timestamps!
+	// CreatedDatetime is set when the Todo is created, and UpdatedDatetime is set whenever it changes, including
+	// changes in status, rank or labels.
 	CreatedDatetime *time.Time
 	UpdatedDatetime *time.Time
+	// DueDate is midnight local time at the start of the day the Todo is due, or nil if it has no due date.
+	DueDate *time.Time
+	// Recurrence is the rule for creating the next occurrence of the Todo when it is done, or nil if it doesn't recur.
+	Recurrence *Recurrence
+	// SnoozedUntil is when a snoozed Todo returns from on_hold to open, or nil if it isn't snoozed. Woken is set when
+	// that happens, until the Todo next changes status.
+	SnoozedUntil *time.Time
+	Woken        bool
+	// Checklist lists the steps of the Todo in order.
+	Checklist []*ChecklistItem
+	// BlockedBy lists the Todos that must be done or abandoned before this one can be done.
+	BlockedBy []*Todo
+	// dependents holds the Todos that a deleted Todo blocked, so that restoring it blocks them again.
+	dependents []*Todo
+	// history holds the events of a deleted Todo, which are deleted along with it, so that restoring it restores them.
+	history *eventRows
+}
+
+// ID returns the unique identifier of the Todo, which never changes.
+func (t *Todo) ID() int {
+	return t.id
+}
+
+// ChecklistItem is a step in the checklist of a Todo.
+type ChecklistItem struct {
+	id   int
+	Text string
+	Done bool
+}
+
+// ID returns the unique identifier of the ChecklistItem.
+func (c *ChecklistItem) ID() int {
+	return c.id
 }
 
 // Label contains labels that can be applied to todos.
 type Label struct {
 	ID   int
 	Name string
+	// Color is the hex color the Label is shown in, e.g. "#FF0000".
+	Color string
 }
 
 // Status represents a status entry and contains pointers to associated Todos.
 type Status struct {
-	id    int
-	Name  string
-	Todos []*Todo
+	id   int
+	Name string
+	// Key is the lowercase letter that shows the status in the UI; the uppercase letter moves a Todo to it.
+	Key string
+	// Color is the hex color the status name is shown in, e.g. "#FFFF00".
+	Color string
+	// DisplayOrder orders statuses in the UI, lowest first.
+	DisplayOrder int
+	// WIPLimit is the maximum number of Todos allowed in the status, or 0 for no limit.
+	WIPLimit int
+	Todos    []*Todo
+}
+
+// EventType identifies the kind of change recorded by a TodoEvent.
+type EventType string
+
+// These constants refer to the kinds of changes recorded in a Todo's history.
+const (
+	EventCreated           EventType = "created"
+	EventEdited            EventType = "edited"
+	EventStatusChanged     EventType = "status_changed"
+	EventReranked          EventType = "reranked"
+	EventLabelAdded        EventType = "label_added"
+	EventLabelRemoved      EventType = "label_removed"
+	EventDeleted           EventType = "deleted"
+	EventRestored          EventType = "restored"
+	EventDueDateChanged    EventType = "due_date_changed"
+	EventRecurrenceChanged EventType = "recurrence_changed"
+	EventSnoozed           EventType = "snoozed"
+	EventWoken             EventType = "woken"
+	EventChecklistChanged  EventType = "checklist_changed"
+	EventDependencyAdded   EventType = "dependency_added"
+	EventDependencyRemoved EventType = "dependency_removed"
+)
+
+// TodoEvent is an entry in the audit history of a Todo. Only the fields relevant to the Type are populated: status
+// and rank fields for status changes, reranks, creation, deletion and restoration; title and description fields for
+// edits and creation; LabelName for label changes; due date and recurrence fields for changes to those; and
+// SnoozedUntil for snoozes. Detail describes changes without fields of their own, such as checklist and dependency
+// changes.
+type TodoEvent struct {
+	ID             int
+	Type           EventType
+	OldStatus      *Status
+	NewStatus      *Status
+	OldRank        int
+	NewRank        int
+	OldTitle       string
+	NewTitle       string
+	OldDescription string
+	NewDescription string
+	LabelName      string
+	OldDueDate     *time.Time
+	NewDueDate     *time.Time
+	OldRecurrence  string
+	NewRecurrence  string
+	SnoozedUntil   *time.Time
+	Detail         string
+	Datetime       time.Time
+}
+
+// CompletedTodo is a Todo that was moved to done or abandoned, along with when that happened.
+type CompletedTodo struct {
+	Todo              *Todo
+	CompletedDatetime time.Time
 }
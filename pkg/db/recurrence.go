@@ -0,0 +1,420 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurrenceKind identifies how a recurring Todo is rescheduled when it is done.
+type RecurrenceKind string
+
+// These constants refer to the supported kinds of recurrence.
+const (
+	RecurDaily           RecurrenceKind = "daily"
+	RecurWeekly          RecurrenceKind = "weekly"
+	RecurMonthly         RecurrenceKind = "monthly"
+	RecurAfterCompletion RecurrenceKind = "every"
+)
+
+// maxDayOfMonth is the highest day that monthly recurrences can fall on.
+const maxDayOfMonth = 31
+
+// ErrInvalidRecurrence is returned from ParseRecurrence when the text isn't a recurrence rule.
+var ErrInvalidRecurrence = errors.New(
+	"recurrence rules look like 'daily', 'weekly mon,thu', 'monthly 15' or 'every 3 days'")
+
+// Recurrence is a rule for creating the next occurrence of a Todo when it is done.
+type Recurrence struct {
+	Kind RecurrenceKind
+	// Weekdays are the days of the week that weekly Todos are due on.
+	Weekdays []time.Weekday
+	// Day is the day of the month that monthly Todos are due on; shorter months use their last day instead.
+	Day int
+	// Days is the number of days after completion that Todos recurring after completion are due.
+	Days int
+}
+
+// ParseRecurrence parses a recurrence rule in the form returned by Recurrence.String: "daily", "weekly" followed by
+// a comma-separated list of weekdays, "monthly" followed by a day of the month, or "every N days". Empty text means
+// the Todo doesn't recur and returns nil.
+func ParseRecurrence(text string) (*Recurrence, error) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	invalid := fmt.Errorf("%w, not '%s'", ErrInvalidRecurrence, text)
+
+	switch RecurrenceKind(fields[0]) {
+	case RecurDaily:
+		if len(fields) != 1 {
+			return nil, invalid
+		}
+
+		return &Recurrence{Kind: RecurDaily}, nil
+	case RecurWeekly:
+		if len(fields) < 2 {
+			return nil, invalid
+		}
+
+		// allow spaces after the commas
+		weekdays, err := parseWeekdays(strings.Join(fields[1:], ""))
+		if err != nil {
+			return nil, invalid
+		}
+
+		return &Recurrence{Kind: RecurWeekly, Weekdays: weekdays}, nil
+	case RecurMonthly:
+		if len(fields) != 2 {
+			return nil, invalid
+		}
+
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || day < 1 || day > maxDayOfMonth {
+			return nil, invalid
+		}
+
+		return &Recurrence{Kind: RecurMonthly, Day: day}, nil
+	case RecurAfterCompletion:
+		if len(fields) != 3 || (fields[2] != "days" && fields[2] != "day") {
+			return nil, invalid
+		}
+
+		days, err := strconv.Atoi(fields[1])
+		if err != nil || days < 1 {
+			return nil, invalid
+		}
+
+		return &Recurrence{Kind: RecurAfterCompletion, Days: days}, nil
+	}
+
+	return nil, invalid
+}
+
+// parseWeekdays parses a comma-separated list of weekdays, e.g. "mon,thursday", into a list in week order.
+func parseWeekdays(text string) ([]time.Weekday, error) {
+	found := map[time.Weekday]bool{}
+
+	for _, name := range strings.Split(text, ",") {
+		matched := false
+
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			full := strings.ToLower(day.String())
+			if name == full || name == full[:3] {
+				found[day] = true
+				matched = true
+			}
+		}
+
+		if !matched {
+			return nil, ErrInvalidRecurrence
+		}
+	}
+
+	weekdays := []time.Weekday{}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if found[day] {
+			weekdays = append(weekdays, day)
+		}
+	}
+
+	return weekdays, nil
+}
+
+// String returns the recurrence rule in the form accepted by ParseRecurrence.
+func (r *Recurrence) String() string {
+	switch r.Kind {
+	case RecurDaily:
+		return string(RecurDaily)
+	case RecurWeekly:
+		names := make([]string, 0, len(r.Weekdays))
+		for _, day := range r.Weekdays {
+			names = append(names, strings.ToLower(day.String()[:3]))
+		}
+
+		return fmt.Sprintf("%s %s", RecurWeekly, strings.Join(names, ","))
+	case RecurMonthly:
+		return fmt.Sprintf("%s %d", RecurMonthly, r.Day)
+	case RecurAfterCompletion:
+		if r.Days == 1 {
+			return fmt.Sprintf("%s 1 day", RecurAfterCompletion)
+		}
+
+		return fmt.Sprintf("%s %d days", RecurAfterCompletion, r.Days)
+	}
+
+	return ""
+}
+
+// Next returns the due date of the occurrence after one that was due on the given date, or had no due date, and
+// was completed at the given time. Rules other than RecurAfterCompletion pick the first matching day after both the
+// due date and the day of completion, so completing a Todo late doesn't create an occurrence that is already due.
+func (r *Recurrence) Next(due *time.Time, completed time.Time) time.Time {
+	base := time.Date(completed.Year(), completed.Month(), completed.Day(), 0, 0, 0, 0, time.Local)
+
+	if r.Kind == RecurAfterCompletion {
+		return base.AddDate(0, 0, r.Days)
+	}
+
+	if due != nil && due.After(base) {
+		base = *due
+	}
+
+	switch r.Kind {
+	case RecurWeekly:
+		for next := base.AddDate(0, 0, 1); ; next = next.AddDate(0, 0, 1) {
+			for _, day := range r.Weekdays {
+				if next.Weekday() == day {
+					return next
+				}
+			}
+		}
+	case RecurMonthly:
+		for month := 0; ; month++ {
+			// the zeroth day of the following month is the last day of this one
+			last := time.Date(base.Year(), base.Month()+time.Month(month)+1, 0, 0, 0, 0, 0, time.Local).Day()
+
+			day := r.Day
+			if day > last {
+				day = last
+			}
+
+			next := time.Date(base.Year(), base.Month()+time.Month(month), day, 0, 0, 0, 0, time.Local)
+			if next.After(base) {
+				return next
+			}
+		}
+	}
+
+	return base.AddDate(0, 0, 1)
+}
+
+// FormatRecurrence returns the recurrence rule in the form accepted by ParseRecurrence, or an empty string if there
+// is no rule.
+func FormatRecurrence(recurrence *Recurrence) string {
+	if recurrence == nil {
+		return ""
+	}
+
+	return recurrence.String()
+}
+
+// recurrenceFromDB parses a stored recurrence rule.
+func recurrenceFromDB(value sql.NullString) (*Recurrence, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	return ParseRecurrence(value.String)
+}
+
+// SetRecurrence sets the rule for creating the next occurrence of the Todo when it is done; nil stops it recurring.
+func (d *Database) SetRecurrence(ctx context.Context, todo *Todo, recurrence *Recurrence) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	oldRecurrence := todo.Recurrence
+
+	if FormatRecurrence(oldRecurrence) == FormatRecurrence(recurrence) {
+		return nil
+	}
+
+	if err = d.setRecurrence(ctx, todo, recurrence); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("changing the recurrence of todo '%s'", todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.setRecurrence(ctx, todo, oldRecurrence)
+		},
+		redo: func(ctx context.Context) error {
+			return d.setRecurrence(ctx, todo, recurrence)
+		},
+	})
+
+	return nil
+}
+
+func (d *Database) setRecurrence(ctx context.Context, todo *Todo, recurrence *Recurrence) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`UPDATE todo SET recurrence=$1 WHERE id=$2`, nullString(FormatRecurrence(recurrence)), todo.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error updating recurrence of todo '%s': %w", todo.Title, err))
+	}
+
+	event := &TodoEvent{
+		Type:          EventRecurrenceChanged,
+		OldRecurrence: FormatRecurrence(todo.Recurrence),
+		NewRecurrence: FormatRecurrence(recurrence),
+	}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.Recurrence = recurrence
+	todo.UpdatedDatetime = &event.Datetime
+
+	return nil
+}
+
+// recur creates the next occurrence of a recurring Todo that was just completed at the end of the open list, with
+// the same title, description and labels. The recurrence rule moves to the new Todo so that completing the original
+// again doesn't create another copy.
+func (d *Database) recur(ctx context.Context, todo *Todo) (*Todo, error) {
+	open := d.statuses[StatusOpen]
+	now := d.now()
+	due := todo.Recurrence.Next(todo.DueDate, now)
+
+	next := &Todo{
+		Title:           todo.Title,
+		Description:     todo.Description,
+		Labels:          append([]*Label{}, todo.Labels...),
+		Rank:            len(open.Todos),
+		Status:          open,
+		CreatedDatetime: &now,
+		UpdatedDatetime: &now,
+		DueDate:         &due,
+		Recurrence:      todo.Recurrence,
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	result, err := txn.ExecContext(ctx,
+		`INSERT INTO todo (title, description, status_id, rank, created_datetime, updated_datetime, due_date, recurrence)
+		     VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		next.Title, next.Description, open.id, next.Rank, now, now,
+		nullDueDate(next.DueDate), FormatRecurrence(next.Recurrence),
+	)
+	if err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error adding next occurrence of todo '%s': %w", todo.Title, err))
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error getting id of next occurrence of '%s': %w", todo.Title, err))
+	}
+
+	next.id = int(id)
+
+	for _, label := range next.Labels {
+		_, err = txn.ExecContext(ctx, `INSERT INTO todo_label (todo_id, label_id) VALUES ($1, $2)`, next.id, label.ID)
+		if err != nil {
+			return nil, rollbackOnError(txn, fmt.Errorf("error adding label '%s' to todo '%s': %w", label.Name, todo.Title, err))
+		}
+	}
+
+	// the next occurrence starts with all of the steps unchecked
+	for _, item := range todo.Checklist {
+		next.Checklist = append(next.Checklist, &ChecklistItem{Text: item.Text})
+	}
+
+	if err = insertChecklistItems(ctx, txn, next, next.Checklist); err != nil {
+		return nil, rollbackOnError(txn, err)
+	}
+
+	event := &TodoEvent{
+		Type:           EventCreated,
+		NewStatus:      open,
+		NewRank:        next.Rank,
+		NewTitle:       next.Title,
+		NewDescription: next.Description,
+		NewDueDate:     next.DueDate,
+		NewRecurrence:  FormatRecurrence(next.Recurrence),
+		Datetime:       now,
+	}
+	if err = d.recordChange(ctx, txn, next, event, nil); err != nil {
+		return nil, rollbackOnError(txn, err)
+	}
+
+	if _, err = txn.ExecContext(ctx, `UPDATE todo SET recurrence=NULL WHERE id=$1`, todo.id); err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error updating recurrence of todo '%s': %w", todo.Title, err))
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.Recurrence = nil
+
+	open.Todos = append(open.Todos, next)
+	d.todos = append(d.todos, next)
+
+	return next, nil
+}
+
+// unrecur reverses recur, deleting the next occurrence and returning the recurrence rule to the original Todo.
+func (d *Database) unrecur(ctx context.Context, todo, next *Todo) error {
+	if err := d.deleteTodo(ctx, next); err != nil {
+		return err
+	}
+
+	_, err := d.conn.ExecContext(ctx,
+		`UPDATE todo SET recurrence=$1 WHERE id=$2`, FormatRecurrence(next.Recurrence), todo.id)
+	if err != nil {
+		return fmt.Errorf("error updating recurrence of todo '%s': %w", todo.Title, err)
+	}
+
+	todo.Recurrence = next.Recurrence
+
+	return nil
+}
+
+// pushRecur records the undo operation for completing a recurring Todo, which both moves the Todo and creates the
+// next occurrence.
+func (d *Database) pushRecur(description string, todo, next *Todo, old placement) {
+	newPlacement := placementOf(todo)
+	nextStatus, nextRank := next.Status, next.Rank
+
+	d.pushUndo(&operation{
+		description: description,
+		undo: func(ctx context.Context) error {
+			if err := d.unrecur(ctx, todo, next); err != nil {
+				return err
+			}
+
+			return d.placeTodo(ctx, todo, old)
+		},
+		redo: func(ctx context.Context) error {
+			if err := d.placeTodo(ctx, todo, newPlacement); err != nil {
+				return err
+			}
+
+			if err := d.restoreTodo(ctx, next, nextStatus, nextRank); err != nil {
+				return err
+			}
+
+			_, err := d.conn.ExecContext(ctx, `UPDATE todo SET recurrence=NULL WHERE id=$1`, todo.id)
+			if err != nil {
+				return fmt.Errorf("error updating recurrence of todo '%s': %w", todo.Title, err)
+			}
+
+			todo.Recurrence = nil
+
+			return nil
+		},
+	})
+}
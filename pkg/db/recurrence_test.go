@@ -0,0 +1,190 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestParseRecurrence(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	recurrence, err := db.ParseRecurrence("")
+	assert.Nil(err)
+	assert.Nil(recurrence)
+
+	for text, expected := range map[string]string{
+		"daily":                  "daily",
+		"Weekly thursday, MON":   "weekly mon,thu",
+		"monthly 31":             "monthly 31",
+		"every 1 day":            "every 1 day",
+		"every 14 days":          "every 14 days",
+		"weekly sat,sun,sat":     "weekly sun,sat",
+		"  monthly   1  ":        "monthly 1",
+		"weekly mon,tue,wed,fri": "weekly mon,tue,wed,fri",
+	} {
+		recurrence, err = db.ParseRecurrence(text)
+		assert.Nil(err, text)
+		assert.Equal(expected, db.FormatRecurrence(recurrence), text)
+	}
+
+	for _, text := range []string{"hourly", "daily 2", "weekly", "weekly funday", "monthly 0", "monthly 32", "every day",
+		"every 0 days", "every 3 weeks"} {
+		_, err = db.ParseRecurrence(text)
+		assert.ErrorIs(err, db.ErrInvalidRecurrence, text)
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	// 2022-01-10 is a Monday
+	monday := date(2022, time.January, 10)
+	completed := monday.Add(9 * time.Hour)
+	wednesday := date(2022, time.January, 12)
+
+	tests := []struct {
+		rule     string
+		due      *time.Time
+		expected time.Time
+	}{
+		{"daily", nil, date(2022, time.January, 11)},
+		{"daily", &wednesday, date(2022, time.January, 13)},
+		// completing a todo late doesn't create one that is already due
+		{"daily", &monday, date(2022, time.January, 11)},
+		{"weekly mon,thu", nil, date(2022, time.January, 13)},
+		{"weekly mon", nil, date(2022, time.January, 17)},
+		{"weekly mon", &wednesday, date(2022, time.January, 17)},
+		{"monthly 10", nil, date(2022, time.February, 10)},
+		{"monthly 15", nil, date(2022, time.January, 15)},
+		// shorter months use their last day
+		{"monthly 31", &wednesday, date(2022, time.January, 31)},
+		{"every 3 days", &wednesday, date(2022, time.January, 13)},
+	}
+
+	for _, test := range tests {
+		recurrence, err := db.ParseRecurrence(test.rule)
+		assert.Nil(err)
+		assert.Equal(test.expected, recurrence.Next(test.due, completed), test.rule)
+	}
+
+	endOfJanuary := date(2022, time.January, 31)
+	recurrence, _ := db.ParseRecurrence("monthly 31")
+	assert.Equal(date(2022, time.February, 28), recurrence.Next(&endOfJanuary, completed))
+}
+
+func TestCompleteRecurringTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_recurrence*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	newFakeClock(database)
+
+	snapshot := database.Snapshot()
+	open, closed, done := snapshot.Statuses[db.StatusOpen], snapshot.Statuses[db.StatusClosed],
+		snapshot.Statuses[db.StatusDone]
+
+	todo := addTodo(assert, database, "review dependabot PRs", "merge the easy ones")
+	assert.Nil(database.AddTodoLabel(ctx, todo, snapshot.Labels[0]))
+
+	due := date(2022, time.January, 10)
+	recurrence, _ := db.ParseRecurrence("weekly mon")
+	assert.Nil(database.SetDueDate(ctx, todo, &due))
+	assert.Nil(database.SetRecurrence(ctx, todo, recurrence))
+
+	assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+	assert.Nil(database.ChangeStatus(ctx, todo, closed, done))
+
+	todo = storeTodo(assert, database, todo)
+	assert.Nil(todo.Recurrence)
+	assert.Equal(1, len(storeStatus(assert, database, db.StatusOpen).Todos))
+
+	next := storeStatus(assert, database, db.StatusOpen).Todos[0]
+	assert.NotEqual(todo.ID(), next.ID())
+	assert.Equal(todo.Title, next.Title)
+	assert.Equal(todo.Description, next.Description)
+	assert.Equal(todo.Labels, next.Labels)
+	assert.Equal(date(2022, time.January, 17), *next.DueDate)
+	assert.Equal("weekly mon", db.FormatRecurrence(next.Recurrence))
+
+	// completing the original again doesn't create another copy
+	assert.Nil(database.ChangeStatus(ctx, todo, done, closed))
+	assert.Nil(database.ChangeStatus(ctx, todo, closed, done))
+	assert.Equal(1, len(storeStatus(assert, database, db.StatusOpen).Todos))
+
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(0, len(storeStatus(assert, database, db.StatusOpen).Todos))
+
+	todo = storeTodo(assert, database, todo)
+	assert.Equal(closed.Name, todo.Status.Name)
+	assert.Equal("weekly mon", db.FormatRecurrence(todo.Recurrence))
+
+	assert.Nil(database.Redo(ctx))
+	assert.Equal([]*db.Todo{next}, storeStatus(assert, database, db.StatusOpen).Todos)
+	assert.Nil(storeTodo(assert, database, todo).Recurrence)
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	snapshot = database.Snapshot()
+	reloaded := snapshot.Statuses[db.StatusOpen].Todos[0]
+	assert.Equal(next.ID(), reloaded.ID())
+	assert.Equal("weekly mon", db.FormatRecurrence(reloaded.Recurrence))
+	assert.Equal(1, len(reloaded.Labels))
+	assert.Nil(snapshot.Statuses[db.StatusDone].Todos[0].Recurrence)
+}
+
+func TestSetRecurrence(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addDefaultTodo(assert, database)
+
+	recurrence, _ := db.ParseRecurrence("every 2 days")
+	assert.Nil(database.SetRecurrence(ctx, todo, recurrence))
+
+	events, err := database.History(ctx, todo)
+	assert.Nil(err)
+
+	last := events[len(events)-1]
+	assert.Equal(db.EventRecurrenceChanged, last.Type)
+	assert.Equal("", last.OldRecurrence)
+	assert.Equal("every 2 days", last.NewRecurrence)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(storeTodo(assert, database, todo).Recurrence)
+
+	assert.ErrorIs(database.SetRecurrence(ctx, nil, recurrence), db.ErrNilTodo)
+}
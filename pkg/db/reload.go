@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// readDataVersion returns sqlite's data_version, which changes whenever another connection, e.g. another tt process,
+// commits a change to the database. Changes made through the Database's own connection don't change it.
+func (d *Database) readDataVersion(ctx context.Context) (int64, error) {
+	var version int64
+
+	if err := d.conn.QueryRowContext(ctx, `PRAGMA data_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading data version: %w", err)
+	}
+
+	return version, nil
+}
+
+// Reload replaces the in-memory model with the data in the database. The Statuses, Labels and Todos from before the
+// reload are stale afterwards, so callers should look them up again by name or id to read them; the methods that
+// change them do so themselves. The undo history is cleared because it refers to the stale model.
+func (d *Database) Reload(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.reload(ctx)
+}
+
+// ReloadIfChanged reloads the in-memory model if another process has changed the database since it was loaded, and
+// reports whether it did. Call it periodically to avoid changing the database based on stale data.
+func (d *Database) ReloadIfChanged(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	version, err := d.readDataVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if version == d.dataVersion {
+		return false, nil
+	}
+
+	if err = d.reload(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *Database) reload(ctx context.Context) error {
+	// read the version first: if another change is committed while loading, the next check reloads again
+	version, err := d.readDataVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	// load into a fresh model so that the current one stays intact if loading fails
+	fresh := &Database{conn: d.conn, clock: d.clock, model: newModel()}
+
+	if err = fresh.loadData(ctx); err != nil {
+		return err
+	}
+
+	d.statuses, d.labels, d.todos, d.transitions = fresh.statuses, fresh.labels, fresh.todos, fresh.transitions
+	d.dataVersion = version
+	d.undoStack, d.redoStack = nil, nil
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadIfChanged(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_reload*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	first := addTodo(assert, database, "first", "")
+	addTodo(assert, database, "second", "")
+
+	// changes made through the database itself don't need a reload
+	reloaded, err := database.ReloadIfChanged(ctx)
+	assert.Nil(err)
+	assert.False(reloaded)
+
+	// another process adds a todo at the top of the open list
+	other, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer other.Close()
+
+	third, err := other.NewTodo(ctx, "third", "")
+	assert.Nil(err)
+	assert.Nil(other.MoveToTop(ctx, third))
+
+	reloaded, err = database.ReloadIfChanged(ctx)
+	assert.Nil(err)
+	assert.True(reloaded)
+
+	snapshot := database.Snapshot()
+	assert.Equal([]string{"third", "first", "second"}, statusTitles(snapshot.Statuses[db.StatusOpen]))
+	assertRanks(assert, snapshot.Statuses)
+
+	// the old copies don't show the changes, so they have to be looked up again
+	assert.Equal(0, first.Rank)
+	first, err = database.TodoByID(ctx, first.ID())
+	assert.Nil(err)
+	assert.Equal(1, first.Rank)
+	assert.Nil(database.MoveDown(ctx, first))
+
+	open := storeStatus(assert, database, db.StatusOpen)
+	assert.Equal([]string{"third", "second", "first"}, statusTitles(open))
+
+	// the undo history referred to the stale todos
+	assert.Nil(database.Undo(ctx))
+	assert.ErrorIs(database.Undo(ctx), db.ErrNothingToUndo)
+
+	reloaded, err = database.ReloadIfChanged(ctx)
+	assert.Nil(err)
+	assert.False(reloaded)
+
+	// and the other process sees the changes in turn
+	reloaded, err = other.ReloadIfChanged(ctx)
+	assert.Nil(err)
+	assert.True(reloaded)
+	open = storeStatus(assert, database, db.StatusOpen)
+	assert.Equal(statusTitles(open), statusTitles(storeStatus(assert, other, db.StatusOpen)))
+}
+
+func TestStaleTodosAfterReload(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_reload*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	a := addTodo(assert, database, "a", "")
+	b := addTodo(assert, database, "b", "")
+	c := addTodo(assert, database, "c", "")
+
+	label, err := database.NewLabel(ctx, "errand")
+	assert.Nil(err)
+
+	// another process deletes b and moves c to the top
+	other, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer other.Close()
+
+	otherB, err := other.TodoByID(ctx, b.ID())
+	assert.Nil(err)
+	assert.Nil(other.DeleteTodo(ctx, otherB))
+
+	otherC, err := other.TodoByID(ctx, c.ID())
+	assert.Nil(err)
+	assert.Nil(other.MoveToTop(ctx, otherC))
+
+	reloaded, err := database.ReloadIfChanged(ctx)
+	assert.Nil(err)
+	assert.True(reloaded)
+
+	// changes to the deleted todo fail without recording anything
+	assert.ErrorIs(database.UpdateTodo(ctx, b, "b2", ""), db.ErrTodoNotFound)
+	assert.ErrorIs(database.AddTodoLabel(ctx, b, label), db.ErrTodoNotFound)
+	assert.ErrorIs(database.MoveUp(ctx, b), db.ErrTodoNotFound)
+	assert.ErrorIs(database.DeleteTodo(ctx, b), db.ErrTodoNotFound)
+
+	problems, err := database.CheckIntegrity(ctx)
+	assert.Nil(err)
+	assert.Empty(problems)
+
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	defer conn.Close()
+
+	var orphaned int
+
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM todo_event WHERE todo_id NOT IN (SELECT id FROM todo)`).
+		Scan(&orphaned)
+	assert.Nil(err)
+	assert.Zero(orphaned)
+
+	// the stale a still has rank 0, but the live one is below c and can move up
+	assert.Equal(0, a.Rank)
+	assert.Nil(database.MoveUp(ctx, a))
+	assert.Equal([]string{"a", "c"}, statusTitles(database.Snapshot().Statuses[db.StatusOpen]))
+	assertRanks(assert, database.Snapshot().Statuses)
+
+	// copies from a Snapshot work the same way
+	snapshotC, err := database.Snapshot().TodoByID(ctx, c.ID())
+	assert.Nil(err)
+	assert.Nil(database.MoveToTop(ctx, snapshotC))
+	assert.Nil(database.AddTodoLabel(ctx, snapshotC, label))
+	assert.Equal([]string{"c", "a"}, statusTitles(database.Snapshot().Statuses[db.StatusOpen]))
+
+	live, err := database.TodoByID(ctx, c.ID())
+	assert.Nil(err)
+	assert.Equal("errand", live.Labels[0].Name)
+}
@@ -0,0 +1,275 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// These weights rank matches in titles above matches in labels, and both above matches in descriptions.
+const (
+	titleSearchWeight       = 10
+	labelSearchWeight       = 5
+	descriptionSearchWeight = 1
+)
+
+// maxSearchResults limits the number of Todos returned by Search.
+const maxSearchResults = 100
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// initSearch sets up the full-text search index if sqlite was built with FTS5 (the sqlite_fts5 build tag).
+//
+// The index is derived data: it is dropped and rebuilt from the todo table every time the database is opened, and
+// nothing else reads it. That is why it isn't part of the migrations: builds without FTS5 can't create it, so they
+// search the todos in memory instead, and the rebuild catches up with any changes they made. Its tokenizer keeps
+// diacritics so that it matches the same Todos as searchInMemory.
+func (d *Database) initSearch(ctx context.Context) error {
+	var fts bool
+
+	err := d.conn.QueryRowContext(ctx, `SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts)
+	if err != nil {
+		return fmt.Errorf("error checking for full-text search: %w", err)
+	}
+
+	if !fts {
+		log.Info().Msg("sqlite was built without FTS5; searching todos in memory")
+
+		return nil
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if _, err = txn.ExecContext(ctx, `DROP TABLE IF EXISTS todo_search`); err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error dropping search index: %w", err))
+	}
+
+	_, err = txn.ExecContext(ctx, `CREATE VIRTUAL TABLE todo_search USING fts5(
+		title, description, labels, tokenize="unicode61 remove_diacritics 0"
+	)`)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error creating search index: %w", err))
+	}
+
+	if _, err = txn.ExecContext(ctx, indexTodosSQL); err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error building search index: %w", err))
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	d.fts = true
+
+	return nil
+}
+
+// indexTodosSQL adds the todos to the search index with the names of their labels.
+const indexTodosSQL = `INSERT INTO todo_search (rowid, title, description, labels)
+	SELECT t.id, t.title, t.description, COALESCE((
+		SELECT GROUP_CONCAT(l.name, ' ') FROM todo_label tl JOIN label l ON l.id = tl.label_id WHERE tl.todo_id = t.id
+	), '')
+	FROM todo t`
+
+// indexTodo updates the search index entry of a Todo from the todo table, so it must be called after the Todo
+// changes in the same transaction. The entry is removed if the Todo has been deleted.
+func (d *Database) indexTodo(ctx context.Context, exec execer, todo *Todo) error {
+	if !d.fts {
+		return nil
+	}
+
+	if _, err := exec.ExecContext(ctx, `DELETE FROM todo_search WHERE rowid=$1`, todo.id); err != nil {
+		return fmt.Errorf("error removing todo '%s' from the search index: %w", todo.Title, err)
+	}
+
+	if _, err := exec.ExecContext(ctx, indexTodosSQL+` WHERE t.id=$1`, todo.id); err != nil {
+		return fmt.Errorf("error adding todo '%s' to the search index: %w", todo.Title, err)
+	}
+
+	return nil
+}
+
+// labelNames joins the names of the labels with spaces.
+func labelNames(labels []*Label) string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// searchTerms splits a query into lowercase words.
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// searchTokens splits text into lowercase words the way the FTS5 unicode61 tokenizer does: every character that is
+// neither a letter nor a number separates words.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matchesPhrase reports whether the words of the phrase appear one after another in tokens, with the last one as a
+// prefix, like the "phrase"* queries built by ftsQuery.
+func matchesPhrase(tokens, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+
+	last := len(phrase) - 1
+
+	for start := 0; start+last < len(tokens); start++ {
+		matched := strings.HasPrefix(tokens[start+last], phrase[last])
+
+		for i := 0; matched && i < last; i++ {
+			matched = tokens[start+i] == phrase[i]
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ftsQuery turns the words of a query into an FTS5 query that matches Todos containing all of them, treating each
+// word as a prefix so that results appear while the user is typing.
+func ftsQuery(terms []string) string {
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(phrases, " ")
+}
+
+// Search returns the Todos in any status whose title, description or labels have a word starting with each word of
+// the query, best matches first. Matches in titles rank highest, then matches in labels, then in descriptions.
+func (d *Database) Search(ctx context.Context, query string) ([]*Todo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []*Todo{}, nil
+	}
+
+	if !d.fts {
+		return d.todoCopies(d.searchInMemory(terms)), nil
+	}
+
+	rows, err := d.conn.QueryContext(ctx,
+		`SELECT rowid FROM todo_search WHERE todo_search MATCH $1 ORDER BY bm25(todo_search, $2, $3, $4) LIMIT $5`,
+		ftsQuery(terms), titleSearchWeight, descriptionSearchWeight, labelSearchWeight, maxSearchResults,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error searching for '%s': %w", query, err)
+	}
+
+	defer rows.Close()
+
+	todos := make(map[int]*Todo, len(d.todos))
+	for _, todo := range d.todos {
+		todos[todo.id] = todo
+	}
+
+	results := []*Todo{}
+
+	for rows.Next() {
+		var id int
+
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning search result: %w", err)
+		}
+
+		if todo, ok := todos[id]; ok {
+			results = append(results, todo)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning search results: %w", err)
+	}
+
+	return d.todoCopies(results), nil
+}
+
+// searchInMemory is used by Search when full-text search isn't available. It matches the same Todos as the full-text
+// index, words that start with each term rather than any substring, and scores them with the same weights.
+func (m *model) searchInMemory(terms []string) []*Todo {
+	scores := map[*Todo]int{}
+	results := []*Todo{}
+
+	for _, todo := range m.todos {
+		title := searchTokens(todo.Title)
+		description := searchTokens(todo.Description)
+		labels := searchTokens(labelNames(todo.Labels))
+
+		score := 0
+
+		for _, term := range terms {
+			phrase := searchTokens(term)
+			termScore := 0
+
+			if matchesPhrase(title, phrase) {
+				termScore += titleSearchWeight
+			}
+
+			if matchesPhrase(labels, phrase) {
+				termScore += labelSearchWeight
+			}
+
+			if matchesPhrase(description, phrase) {
+				termScore += descriptionSearchWeight
+			}
+
+			if termScore == 0 {
+				score = 0
+
+				break
+			}
+
+			score += termScore
+		}
+
+		if score > 0 {
+			scores[todo] = score
+			results = append(results, todo)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+
+		if a.Status.DisplayOrder != b.Status.DisplayOrder {
+			return a.Status.DisplayOrder < b.Status.DisplayOrder
+		}
+
+		return a.Rank < b.Rank
+	})
+
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+
+	return results
+}
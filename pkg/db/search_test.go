@@ -0,0 +1,62 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTitles(assert *assert.Assertions, database *db.Database, query string) []string {
+	todos, err := database.Search(context.Background(), query)
+	assert.Nil(err, query)
+
+	titles := []string{}
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+
+	return titles
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	quarterly := addTodo(assert, database, "quarterly report", "")
+	email := addTodo(assert, database, "email", "send the report to finance")
+	offsite := addTodo(assert, database, "plan offsite", "")
+
+	label, err := database.NewLabel(ctx, "reporting")
+	assert.Nil(err)
+	assert.Nil(database.AddTodoLabel(ctx, offsite, label))
+
+	// titles rank above labels, which rank above descriptions
+	assert.Equal([]string{"quarterly report", "plan offsite", "email"}, searchTitles(assert, database, "report"))
+	assert.Equal([]string{"email"}, searchTitles(assert, database, "Report FINANCE"))
+	assert.Equal([]string{"quarterly report"}, searchTitles(assert, database, "quart"))
+	assert.Empty(searchTitles(assert, database, "  "))
+	assert.Empty(searchTitles(assert, database, `"unbalanced`))
+
+	// todos are found whatever their status
+	assert.Nil(database.ChangeStatus(ctx, email, email.Status, database.Snapshot().Statuses[db.StatusAbandoned]))
+	assert.Nil(database.UpdateTodo(ctx, email, "finance", email.Description))
+	assert.Equal([]string{"finance"}, searchTitles(assert, database, "finance"))
+
+	assert.Nil(database.UpdateLabel(ctx, label, "away"))
+	assert.Equal([]string{"plan offsite"}, searchTitles(assert, database, "away"))
+	assert.Equal([]string{"quarterly report", "finance"}, searchTitles(assert, database, "report"))
+
+	assert.Nil(database.DeleteTodo(ctx, quarterly))
+	assert.Equal([]string{"finance"}, searchTitles(assert, database, "report"))
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal([]string{"quarterly report", "finance"}, searchTitles(assert, database, "report"))
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SettingMaxClosedTodos is the name of the setting that limits the size of the closed list.
+const SettingMaxClosedTodos = "max_closed_todos"
+
+// ErrInvalidSetting is returned when a setting is given a value it doesn't support.
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// parseMaxClosedTodos parses a limit on the number of closed todos, which must be a positive integer.
+func parseMaxClosedTodos(value string) (int, error) {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer, not '%s'", ErrInvalidSetting, SettingMaxClosedTodos, value)
+	}
+
+	return limit, nil
+}
+
+// MaxClosedTodos returns the maximum number of todos allowed in the closed list, which is the WIPLimit of the
+// closed status unless it has been overridden with OverrideMaxClosedTodos.
+func (d *Database) MaxClosedTodos() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.statusLimit(d.statuses[StatusClosed])
+}
+
+// SetMaxClosedTodos persists the maximum number of todos allowed in the closed list. Unlike other statuses, the
+// closed list must always have a limit. The limit may be lowered below the number of todos that are already closed;
+// they stay closed, but no more can be closed until enough of them are moved out.
+func (d *Database) SetMaxClosedTodos(ctx context.Context, limit int) error {
+	if _, err := parseMaxClosedTodos(strconv.Itoa(limit)); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.setWIPLimit(ctx, d.statuses[StatusClosed], limit)
+}
+
+// OverrideMaxClosedTodos replaces the maximum number of closed todos for the lifetime of this Database without
+// persisting it, e.g. when it is set by an environment variable. The value is parsed like a persisted setting.
+func (d *Database) OverrideMaxClosedTodos(value string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	limit, err := parseMaxClosedTodos(value)
+	if err != nil {
+		return err
+	}
+
+	d.maxClosedOverride = limit
+
+	return nil
+}
+
+// MaxClosedTodosOverridden indicates whether the limit on closed todos was set with OverrideMaxClosedTodos, in which
+// case changes made with SetMaxClosedTodos won't take effect until the next session.
+func (d *Database) MaxClosedTodosOverridden() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.maxClosedOverride > 0
+}
@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxClosedTodos(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_settings*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	assert.Equal(db.DefaultMaxClosedTodos, database.MaxClosedTodos())
+
+	assert.ErrorIs(database.SetMaxClosedTodos(ctx, 0), db.ErrInvalidSetting)
+	assert.Equal(db.DefaultMaxClosedTodos, database.MaxClosedTodos())
+
+	assert.Nil(database.SetMaxClosedTodos(ctx, 7))
+	assert.Equal(7, database.MaxClosedTodos())
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	assert.Equal(7, database.MaxClosedTodos())
+}
+
+func TestMaxClosedTodosLowered(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	open, closed := storeStatus(assert, database, db.StatusOpen), storeStatus(assert, database, db.StatusClosed)
+
+	for i := 0; i < 4; i++ {
+		todo := addTodo(assert, database, fmt.Sprintf("todo %d", i), "")
+		assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+	}
+
+	// lowering the limit below the number of closed todos leaves them where they are
+	assert.Nil(database.SetMaxClosedTodos(ctx, 2))
+	assert.Equal(4, len(storeStatus(assert, database, db.StatusClosed).Todos))
+
+	todo := addTodo(assert, database, "one too many", "")
+	err := database.ChangeStatus(ctx, todo, open, closed)
+	assert.ErrorIs(err, db.ErrMaxClosedTodos)
+	assert.Contains(err.Error(), "there are already 4 closed todos and the limit is 2")
+
+	onHold := storeStatus(assert, database, db.StatusOnHold)
+
+	for _, closedTodo := range storeStatus(assert, database, db.StatusClosed).Todos[:3] {
+		assert.Nil(database.ChangeStatus(ctx, closedTodo, closed, onHold))
+	}
+
+	assert.Nil(database.ChangeStatus(ctx, todo, open, closed))
+	assert.Equal(2, len(storeStatus(assert, database, db.StatusClosed).Todos))
+}
+
+func TestOverrideMaxClosedTodos(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	assert.ErrorIs(database.OverrideMaxClosedTodos("three"), db.ErrInvalidSetting)
+	assert.ErrorIs(database.OverrideMaxClosedTodos("-1"), db.ErrInvalidSetting)
+	assert.False(database.MaxClosedTodosOverridden())
+
+	assert.Nil(database.OverrideMaxClosedTodos("3"))
+	assert.True(database.MaxClosedTodosOverridden())
+	assert.Equal(3, database.MaxClosedTodos())
+
+	// the persisted value is still saved, but the override wins
+	assert.Nil(database.SetMaxClosedTodos(ctx, 7))
+	assert.Equal(3, database.MaxClosedTodos())
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Snapshot is a consistent copy of the statuses, labels and todos in a Store at one point in time. Nothing changes it
+// afterwards, so it may be read from any goroutine while the Store keeps changing.
+//
+// The Statuses, Labels and Todos in a Snapshot are copies that only refer to each other. They may be passed to the
+// methods of the Store that change them, which look up the live ones by id.
+type Snapshot struct {
+	Statuses map[string]*Status
+	Labels   []*Label
+	// Todos are in the order they were loaded or created.
+	Todos []*Todo
+}
+
+// Snapshot returns a copy of the in-memory model.
+func (d *Database) Snapshot() *Snapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.snapshot()
+}
+
+func (m *model) snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Statuses: make(map[string]*Status, len(m.statuses)),
+		Labels:   make([]*Label, 0, len(m.labels)),
+		Todos:    make([]*Todo, 0, len(m.todos)),
+	}
+
+	labels := make(map[*Label]*Label, len(m.labels))
+
+	for _, label := range m.labels {
+		labelCopy := *label
+		labels[label] = &labelCopy
+		snapshot.Labels = append(snapshot.Labels, &labelCopy)
+	}
+
+	statuses := make(map[*Status]*Status, len(m.statuses))
+
+	for name, status := range m.statuses {
+		statusCopy := *status
+		statusCopy.Todos = make([]*Todo, 0, len(status.Todos))
+		statuses[status] = &statusCopy
+		snapshot.Statuses[name] = &statusCopy
+	}
+
+	todos := make(map[*Todo]*Todo, len(m.todos))
+
+	for _, todo := range m.todos {
+		todos[todo] = copyTodo(todo, statuses, labels)
+		snapshot.Todos = append(snapshot.Todos, todos[todo])
+	}
+
+	for _, todo := range m.todos {
+		todoCopy := todos[todo]
+		todoCopy.BlockedBy = make([]*Todo, 0, len(todo.BlockedBy))
+
+		for _, blocker := range todo.BlockedBy {
+			todoCopy.BlockedBy = append(todoCopy.BlockedBy, todos[blocker])
+		}
+	}
+
+	for status, statusCopy := range statuses {
+		for _, todo := range status.Todos {
+			statusCopy.Todos = append(statusCopy.Todos, todos[todo])
+		}
+	}
+
+	return snapshot
+}
+
+// todoCopies returns the copies of the live Todos in a new Snapshot. The methods that find or create Todos return these
+// rather than the live ones, which only the methods that change them may touch.
+func (m *model) todoCopies(todos []*Todo) []*Todo {
+	snapshot := m.snapshot()
+
+	byID := make(map[int]*Todo, len(snapshot.Todos))
+	for _, todo := range snapshot.Todos {
+		byID[todo.id] = todo
+	}
+
+	copies := make([]*Todo, 0, len(todos))
+	for _, todo := range todos {
+		copies = append(copies, byID[todo.id])
+	}
+
+	return copies
+}
+
+// todoCopy returns the copy of a live Todo in a new Snapshot, like todoCopies.
+func (m *model) todoCopy(todo *Todo) *Todo {
+	return m.todoCopies([]*Todo{todo})[0]
+}
+
+// statusCopy returns the copy of a live status in a new Snapshot, like todoCopies.
+func (m *model) statusCopy(status *Status) *Status {
+	return m.snapshot().Statuses[status.Name]
+}
+
+// labelCopies returns copies of the Labels, which don't refer to anything else.
+func labelCopies(labels []*Label) []*Label {
+	copies := make([]*Label, 0, len(labels))
+
+	for _, label := range labels {
+		labelCopy := *label
+		copies = append(copies, &labelCopy)
+	}
+
+	return copies
+}
+
+// eventCopies returns copies of the TodoEvents that refer to the copies of their statuses in a new Snapshot.
+func (m *model) eventCopies(events []*TodoEvent) []*TodoEvent {
+	statuses := m.snapshot().Statuses
+	copies := make([]*TodoEvent, 0, len(events))
+
+	for _, event := range events {
+		eventCopy := *event
+		eventCopy.OldDueDate = copyTime(event.OldDueDate)
+		eventCopy.NewDueDate = copyTime(event.NewDueDate)
+		eventCopy.SnoozedUntil = copyTime(event.SnoozedUntil)
+
+		if event.OldStatus != nil {
+			eventCopy.OldStatus = statuses[event.OldStatus.Name]
+		}
+
+		if event.NewStatus != nil {
+			eventCopy.NewStatus = statuses[event.NewStatus.Name]
+		}
+
+		copies = append(copies, &eventCopy)
+	}
+
+	return copies
+}
+
+// completedCopies returns copies of the CompletedTodos that refer to the copies of their Todos, like todoCopies.
+func (m *model) completedCopies(completed []*CompletedTodo) []*CompletedTodo {
+	todos := make([]*Todo, 0, len(completed))
+	for _, c := range completed {
+		todos = append(todos, c.Todo)
+	}
+
+	copies := make([]*CompletedTodo, 0, len(completed))
+	for i, todo := range m.todoCopies(todos) {
+		copies = append(copies, &CompletedTodo{Todo: todo, CompletedDatetime: completed[i].CompletedDatetime})
+	}
+
+	return copies
+}
+
+// copyTodo copies a Todo and everything it owns, pointing it to the copies of its status and labels. Its BlockedBy
+// list is left for the caller to fill in once every Todo has been copied.
+func copyTodo(todo *Todo, statuses map[*Status]*Status, labels map[*Label]*Label) *Todo {
+	todoCopy := *todo
+	todoCopy.Status = statuses[todo.Status]
+	todoCopy.Labels = make([]*Label, 0, len(todo.Labels))
+	todoCopy.Checklist = make([]*ChecklistItem, 0, len(todo.Checklist))
+	todoCopy.BlockedBy = nil
+	todoCopy.dependents = nil
+	todoCopy.CreatedDatetime = copyTime(todo.CreatedDatetime)
+	todoCopy.UpdatedDatetime = copyTime(todo.UpdatedDatetime)
+	todoCopy.DueDate = copyTime(todo.DueDate)
+	todoCopy.SnoozedUntil = copyTime(todo.SnoozedUntil)
+
+	for _, label := range todo.Labels {
+		todoCopy.Labels = append(todoCopy.Labels, labels[label])
+	}
+
+	for _, item := range todo.Checklist {
+		itemCopy := *item
+		todoCopy.Checklist = append(todoCopy.Checklist, &itemCopy)
+	}
+
+	if todo.Recurrence != nil {
+		recurrence := *todo.Recurrence
+		recurrence.Weekdays = append([]time.Weekday{}, todo.Recurrence.Weekdays...)
+		todoCopy.Recurrence = &recurrence
+	}
+
+	return &todoCopy
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	tCopy := *t
+
+	return &tCopy
+}
+
+// OrderedStatuses returns all statuses in display order.
+func (s *Snapshot) OrderedStatuses() []*Status {
+	return orderStatuses(s.Statuses)
+}
+
+// StatusByName returns the status with the given name.
+func (s *Snapshot) StatusByName(_ context.Context, name string) (*Status, error) {
+	status, ok := s.Statuses[name]
+	if !ok {
+		return nil, fmt.Errorf("%w named '%s'", ErrStatusNotFound, name)
+	}
+
+	return status, nil
+}
+
+// LabelByName returns the Label with the given name.
+func (s *Snapshot) LabelByName(_ context.Context, name string) (*Label, error) {
+	for _, label := range s.Labels {
+		if label.Name == name {
+			return label, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w named '%s'", ErrLabelNotFound, name)
+}
+
+// TodoByID returns the Todo with the given id.
+func (s *Snapshot) TodoByID(_ context.Context, id int) (*Todo, error) {
+	for _, todo := range s.Todos {
+		if todo.id == id {
+			return todo, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w with id %d", ErrTodoNotFound, id)
+}
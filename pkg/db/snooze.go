@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSnoozeInPast is returned from Snooze when the wake-up time has already passed.
+	ErrSnoozeInPast = errors.New("todos must be snoozed until a time in the future")
+	// ErrInvalidSnooze is returned from ParseSnoozeUntil when the text isn't a duration, weekday or date.
+	ErrInvalidSnooze = errors.New(
+		"snooze for a duration like 2h, 3d or 1w, until tomorrow or a weekday like next monday, or until a date")
+)
+
+const (
+	daysPerWeek = 7
+	snoozeDay   = 24 * time.Hour
+	snoozeWeek  = daysPerWeek * snoozeDay
+)
+
+// snoozeUnits maps the suffixes accepted by ParseSnoozeUntil to the length of time they stand for.
+var snoozeUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": snoozeDay,
+	"w": snoozeWeek,
+}
+
+// ParseSnoozeUntil returns the time that a Todo snoozed at now with the given text should wake up. The text is one
+// of: a duration such as 30m, 2h, 3d or 1w; "tomorrow"; a weekday, optionally preceded by "next", which means the
+// next such day after today; or a date in DueDateFormat. Days start at midnight local time.
+func ParseSnoozeUntil(text string, now time.Time) (time.Time, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	invalid := fmt.Errorf("%w, not '%s'", ErrInvalidSnooze, text)
+
+	if text == "" {
+		return time.Time{}, invalid
+	}
+
+	if unit, ok := snoozeUnits[text[len(text)-1:]]; ok {
+		if count, err := strconv.Atoi(text[:len(text)-1]); err == nil {
+			if count < 1 {
+				return time.Time{}, invalid
+			}
+
+			return now.Add(time.Duration(count) * unit), nil
+		}
+	}
+
+	if text == "tomorrow" {
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	name := strings.TrimPrefix(text, "next ")
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name != full && name != full[:3] {
+			continue
+		}
+
+		days := (int(day) - int(today.Weekday()) + daysPerWeek) % daysPerWeek
+		if days == 0 {
+			days = daysPerWeek
+		}
+
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if until, err := time.ParseInLocation(DueDateFormat, text, time.Local); err == nil {
+		return until, nil
+	}
+
+	return time.Time{}, invalid
+}
+
+// Snooze moves the Todo to the end of the on_hold list until the given time, when WakeSnoozed returns it to the top
+// of the open list. Snoozing a Todo that is already on hold only changes when it wakes up.
+func (d *Database) Snooze(ctx context.Context, todo *Todo, until time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	todo, err := d.liveTodo(todo)
+	if err != nil {
+		return err
+	}
+
+	if !until.After(d.now()) {
+		return ErrSnoozeInPast
+	}
+
+	onHold := d.statuses[StatusOnHold]
+	oldStatus, oldRank, oldUntil := todo.Status, todo.Rank, todo.SnoozedUntil
+	rank := todo.Rank
+
+	if todo.Status != onHold {
+		if err := d.validateStatusChange(todo, todo.Status, onHold); err != nil {
+			return err
+		}
+
+		rank = len(onHold.Todos)
+	}
+
+	if err = d.setSnooze(ctx, todo, onHold, rank, &until); err != nil {
+		return err
+	}
+
+	d.pushUndo(&operation{
+		description: fmt.Sprintf("snoozing todo '%s'", todo.Title),
+		undo: func(ctx context.Context) error {
+			return d.setSnooze(ctx, todo, oldStatus, oldRank, oldUntil)
+		},
+		redo: func(ctx context.Context) error {
+			return d.setSnooze(ctx, todo, onHold, rank, &until)
+		},
+	})
+
+	return nil
+}
+
+// setSnooze moves the Todo to the given rank in the given status and sets when it wakes up, without validating the
+// move. A nil until leaves the Todo without a wake-up time.
+func (d *Database) setSnooze(ctx context.Context, todo *Todo, status *Status, rank int, until *time.Time) error {
+	oldStatus := todo.Status
+	orders := map[*Status][]*Todo{}
+
+	target := without(oldStatus.Todos, todo)
+	if status != oldStatus {
+		orders[oldStatus] = target
+		target = status.Todos
+	}
+
+	orders[status] = insertAt(target, todo, rank)
+
+	event := &TodoEvent{
+		Type:         EventSnoozed,
+		OldStatus:    oldStatus,
+		NewStatus:    status,
+		OldRank:      todo.Rank,
+		NewRank:      rank,
+		SnoozedUntil: until,
+	}
+
+	if until == nil {
+		event.Type = EventStatusChanged
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if err = persistOrder(ctx, txn, orders); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	_, err = txn.ExecContext(ctx, `UPDATE todo SET snoozed_until=$1, woken=0 WHERE id=$2`, nullTime(until), todo.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error snoozing todo '%s': %w", todo.Title, err))
+	}
+
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	applyOrder(orders)
+
+	todo.SnoozedUntil = until
+	todo.Woken = false
+	todo.UpdatedDatetime = &event.Datetime
+
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// WakeSnoozed moves every snoozed Todo whose wake-up time has passed from on_hold to the top of the open list, in
+// the order they woke up, and flags them as Woken until they next change status. It returns the Todos that woke up.
+// Waking up can't be undone, since it would only happen again.
+func (d *Database) WakeSnoozed(ctx context.Context) ([]*Todo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	onHold, open := d.statuses[StatusOnHold], d.statuses[StatusOpen]
+	now := d.now()
+
+	woken := []*Todo{}
+
+	for _, todo := range onHold.Todos {
+		if todo.SnoozedUntil != nil && !todo.SnoozedUntil.After(now) {
+			woken = append(woken, todo)
+		}
+	}
+
+	if len(woken) == 0 {
+		return woken, nil
+	}
+
+	sort.SliceStable(woken, func(i, j int) bool {
+		return woken[i].SnoozedUntil.Before(*woken[j].SnoozedUntil)
+	})
+
+	remaining := onHold.Todos
+	for _, todo := range woken {
+		remaining = without(remaining, todo)
+	}
+
+	orders := map[*Status][]*Todo{
+		onHold: remaining,
+		open:   append(append([]*Todo{}, woken...), open.Todos...),
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if err = persistOrder(ctx, txn, orders); err != nil {
+		return nil, rollbackOnError(txn, err)
+	}
+
+	events := map[*Todo]*TodoEvent{}
+
+	for rank, todo := range woken {
+		_, err = txn.ExecContext(ctx, `UPDATE todo SET snoozed_until=NULL, woken=1 WHERE id=$1`, todo.id)
+		if err != nil {
+			return nil, rollbackOnError(txn, fmt.Errorf("error waking todo '%s': %w", todo.Title, err))
+		}
+
+		events[todo] = &TodoEvent{
+			Type:      EventWoken,
+			OldStatus: onHold,
+			NewStatus: open,
+			OldRank:   todo.Rank,
+			NewRank:   rank,
+			Datetime:  now,
+		}
+		if err = d.recordChange(ctx, txn, todo, events[todo], nil); err != nil {
+			return nil, rollbackOnError(txn, err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	applyOrder(orders)
+
+	for _, todo := range woken {
+		todo.SnoozedUntil = nil
+		todo.Woken = true
+		todo.UpdatedDatetime = &events[todo].Datetime
+	}
+
+	return d.todoCopies(woken), nil
+}
@@ -0,0 +1,169 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnoozeUntil(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	// 2022-01-10 is a Monday
+	now := time.Date(2022, time.January, 10, 9, 0, 0, 0, time.Local)
+
+	for text, expected := range map[string]time.Time{
+		"30m":         now.Add(30 * time.Minute),
+		"2h":          now.Add(2 * time.Hour),
+		"2d":          now.Add(2 * 24 * time.Hour),
+		"1w":          now.Add(7 * 24 * time.Hour),
+		"tomorrow":    date(2022, time.January, 11),
+		"friday":      date(2022, time.January, 14),
+		"Next Monday": date(2022, time.January, 17),
+		"next sun":    date(2022, time.January, 16),
+		"2022-02-01":  date(2022, time.February, 1),
+	} {
+		until, err := db.ParseSnoozeUntil(text, now)
+		assert.Nil(err, text)
+		assert.Equal(expected, until, text)
+	}
+
+	for _, text := range []string{"", "soon", "0d", "-2h", "2y", "next month", "2022-13-01"} {
+		_, err := db.ParseSnoozeUntil(text, now)
+		assert.ErrorIs(err, db.ErrInvalidSnooze, text)
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	clock := newFakeClock(database)
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	assert.ErrorIs(database.Snooze(ctx, todo1, clock.current), db.ErrSnoozeInPast)
+	assert.ErrorIs(database.Snooze(ctx, nil, clock.current.Add(time.Hour)), db.ErrNilTodo)
+
+	later := clock.current.Add(2 * time.Hour)
+	sooner := clock.current.Add(time.Hour)
+
+	assert.Nil(database.Snooze(ctx, todo1, later))
+	assert.Nil(database.Snooze(ctx, todo2, sooner))
+	assert.Equal([]string{"todo 1", "todo 2"}, storeTitles(assert, database, db.StatusOnHold))
+	assert.Equal(later, *storeTodo(assert, database, todo1).SnoozedUntil)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal([]string{"todo 2", "todo 3"}, storeTitles(assert, database, db.StatusOpen))
+	assert.Nil(storeTodo(assert, database, todo2).SnoozedUntil)
+
+	assert.Nil(database.Redo(ctx))
+	assert.Equal(sooner, *storeTodo(assert, database, todo2).SnoozedUntil)
+
+	// nothing wakes up early
+	woken, err := database.WakeSnoozed(ctx)
+	assert.Nil(err)
+	assert.Equal(0, len(woken))
+
+	clock.current = later
+
+	woken, err = database.WakeSnoozed(ctx)
+	assert.Nil(err)
+	assert.Equal([]string{"todo 2", "todo 1"}, todoTitles(woken))
+	assert.Equal([]string{"todo 2", "todo 1", "todo 3"}, storeTitles(assert, database, db.StatusOpen))
+	assert.Equal(0, len(storeStatus(assert, database, db.StatusOnHold).Todos))
+
+	todo1 = storeTodo(assert, database, todo1)
+	assert.True(todo1.Woken)
+	assert.Nil(todo1.SnoozedUntil)
+
+	events, err := database.History(ctx, todo1)
+	assert.Nil(err)
+	assert.Equal(db.EventWoken, events[len(events)-1].Type)
+	assert.Equal(db.EventSnoozed, events[len(events)-2].Type)
+
+	// the flag is cleared once the todo moves on
+	assert.Nil(database.ChangeStatus(ctx, todo1, todo1.Status, storeStatus(assert, database, db.StatusClosed)))
+	assert.False(storeTodo(assert, database, todo1).Woken)
+}
+
+func TestWakeSnoozedOnLoad(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_snooze*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	clock := newFakeClock(database)
+
+	addTodo(assert, database, "todo 1", "")
+	todo := addTodo(assert, database, "todo 2", "")
+
+	// the fake clock is well in the past, so the snooze has expired by the time the database is reopened
+	assert.Nil(database.Snooze(ctx, todo, clock.current.Add(time.Hour)))
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	open := database.Snapshot().Statuses[db.StatusOpen].Todos
+	assert.Equal(2, len(open))
+	assert.Equal("todo 2", open[0].Title)
+	assert.True(open[0].Woken)
+	assert.Equal(0, len(database.Snapshot().Statuses[db.StatusOnHold].Todos))
+}
+
+func TestUndoMoveOutOfOnHold(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_snooze*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	todo := addDefaultTodo(assert, database)
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	onHold := database.Snapshot().Statuses[db.StatusOnHold]
+
+	assert.Nil(database.Snooze(ctx, todo, until))
+	assert.Nil(database.ChangeStatus(ctx, todo, onHold, database.Snapshot().Statuses[db.StatusClosed]))
+	assert.Nil(database.Undo(ctx))
+	database.Close()
+
+	// the restored snooze is persisted, not just in memory
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	restored := database.Snapshot().Statuses[db.StatusOnHold].Todos
+	assert.Equal(1, len(restored))
+	assert.True(until.Equal(*restored[0].SnoozedUntil))
+	assert.False(restored[0].Woken)
+}
@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxStatusNameLength matches the size of the status.name column.
+const maxStatusNameLength = 20
+
+// ReservedStatusKeys are the letters that the UI already binds to other actions, either as they are or with Shift.
+// A status uses its key for both, so statuses can't have these keys.
+const ReservedStatusKeys = "befijklnqrstuwxz"
+
+var (
+	// ErrWIPLimitReached is returned from ChangeStatus when the status a Todo is moved to already holds as many Todos
+	// as its WIPLimit allows. Moves to the closed list return ErrMaxClosedTodos instead.
+	ErrWIPLimitReached = errors.New("the status is full")
+	// ErrInvalidStatus is returned when a status is created with an invalid name, key, color or limit.
+	ErrInvalidStatus = errors.New("invalid status")
+	// ErrStatusNotFound is returned from StatusByName when no status has the given name.
+	ErrStatusNotFound = errors.New("no status found")
+
+	keyPattern = regexp.MustCompile(`^[a-z]$`)
+)
+
+func (d *Database) loadTransitions(ctx context.Context) error {
+	rows, err := d.conn.QueryContext(ctx, `SELECT from_status_id, to_status_id FROM status_transition`)
+	if err != nil {
+		return fmt.Errorf("error loading status transitions: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var fromID, toID int
+
+		if err = rows.Scan(&fromID, &toID); err != nil {
+			return fmt.Errorf("error scanning status transition: %w", err)
+		}
+
+		d.allowTransition(fromID, toID, true)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error scanning status transitions: %w", err)
+	}
+
+	return nil
+}
+
+func (m *model) allowTransition(fromID, toID int, allowed bool) {
+	if m.transitions[fromID] == nil {
+		m.transitions[fromID] = map[int]bool{}
+	}
+
+	if allowed {
+		m.transitions[fromID][toID] = true
+	} else {
+		delete(m.transitions[fromID], toID)
+	}
+}
+
+// OrderedStatuses returns all statuses in display order.
+func (d *Database) OrderedStatuses() []*Status {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.snapshot().OrderedStatuses()
+}
+
+// StatusByName returns the status with the given name.
+func (d *Database) StatusByName(_ context.Context, name string) (*Status, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	status, err := d.statusByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.statusCopy(status), nil
+}
+
+func (m *model) statusByName(name string) (*Status, error) {
+	status, ok := m.statuses[name]
+	if !ok {
+		return nil, fmt.Errorf("%w named '%s'", ErrStatusNotFound, name)
+	}
+
+	return status, nil
+}
+
+// orderStatuses returns the statuses in display order.
+func orderStatuses(byName map[string]*Status) []*Status {
+	statuses := make([]*Status, 0, len(byName))
+	for _, status := range byName {
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].DisplayOrder == statuses[j].DisplayOrder {
+			return statuses[i].id < statuses[j].id
+		}
+
+		return statuses[i].DisplayOrder < statuses[j].DisplayOrder
+	})
+
+	return statuses
+}
+
+// CanTransition indicates whether Todos may move from one status to the other.
+func (d *Database) CanTransition(from, to *Status) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.canTransition(from, to)
+}
+
+func (m *model) canTransition(from, to *Status) bool {
+	return m.transitions[from.id][to.id]
+}
+
+// StatusLimit returns the maximum number of Todos allowed in the status, or 0 if there is no limit. The limit of the
+// closed list may have been overridden with OverrideMaxClosedTodos.
+func (d *Database) StatusLimit(status *Status) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	status, err := d.liveStatus(status)
+	if err != nil {
+		return 0
+	}
+
+	return d.statusLimit(status)
+}
+
+func (m *model) statusLimit(status *Status) int {
+	if status.Name == StatusClosed && m.maxClosedOverride > 0 {
+		return m.maxClosedOverride
+	}
+
+	return status.WIPLimit
+}
+
+func (d *Database) validateNewStatus(name, key, color string, wipLimit int) error {
+	if name == "" || len(name) > maxStatusNameLength {
+		return fmt.Errorf("%w: names must have 1 to %d characters", ErrInvalidStatus, maxStatusNameLength)
+	}
+
+	if _, ok := d.statuses[name]; ok {
+		return fmt.Errorf("%w: there is already a status named '%s'", ErrInvalidStatus, name)
+	}
+
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: keys must be a single lowercase letter, not '%s'", ErrInvalidStatus, key)
+	}
+
+	if strings.Contains(ReservedStatusKeys, key) {
+		return fmt.Errorf("%w: the key '%s' is used for other actions in the UI; the keys %s are reserved",
+			ErrInvalidStatus, key, ReservedStatusKeys)
+	}
+
+	for _, status := range d.statuses {
+		if status.Key == key {
+			return fmt.Errorf("%w: the key '%s' is already used by %s", ErrInvalidStatus, key, status.Name)
+		}
+	}
+
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, ErrInvalidColor)
+	}
+
+	if wipLimit < 0 {
+		return fmt.Errorf("%w: the limit must not be negative", ErrInvalidStatus)
+	}
+
+	return nil
+}
+
+// NewStatus creates a status that is shown after the existing statuses. Todos may move between the new status and
+// every existing status until transitions are removed with SetTransition.
+func (d *Database) NewStatus(ctx context.Context, name, key, color string, wipLimit int) (*Status, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.validateNewStatus(name, key, color, wipLimit); err != nil {
+		return nil, err
+	}
+
+	displayOrder := 1
+	for _, status := range d.statuses {
+		if status.DisplayOrder >= displayOrder {
+			displayOrder = status.DisplayOrder + 1
+		}
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	result, err := txn.ExecContext(ctx,
+		`INSERT INTO status (name, display_order, key, color, wip_limit) VALUES ($1, $2, $3, $4, $5)`,
+		name, displayOrder, key, color, wipLimit,
+	)
+	if err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error adding status %s: %w", name, err))
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error getting id of new status %s: %w", name, err))
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`INSERT INTO status_transition (from_status_id, to_status_id)
+			SELECT $1, id FROM status WHERE id != $1
+			UNION ALL
+			SELECT id, $1 FROM status WHERE id != $1`,
+		id,
+	)
+	if err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error adding transitions for status %s: %w", name, err))
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	status := &Status{
+		id:           int(id),
+		Name:         name,
+		Key:          key,
+		Color:        color,
+		DisplayOrder: displayOrder,
+		WIPLimit:     wipLimit,
+		Todos:        []*Todo{},
+	}
+
+	for _, other := range d.statuses {
+		d.allowTransition(status.id, other.id, true)
+		d.allowTransition(other.id, status.id, true)
+	}
+
+	d.statuses[name] = status
+
+	return d.statusCopy(status), nil
+}
+
+// SetTransition allows or forbids Todos to move from one status to the other. Todos that are already in a status
+// stay there.
+func (d *Database) SetTransition(ctx context.Context, from, to *Status, allowed bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from, err := d.liveStatus(from)
+	if err != nil {
+		return err
+	}
+
+	if to, err = d.liveStatus(to); err != nil {
+		return err
+	}
+
+	if from.id == to.id {
+		return ErrInvalidTodoMoveNoStatusChange
+	}
+
+	if allowed {
+		_, err = d.conn.ExecContext(ctx,
+			`INSERT OR IGNORE INTO status_transition (from_status_id, to_status_id) VALUES ($1, $2)`,
+			from.id, to.id,
+		)
+	} else {
+		_, err = d.conn.ExecContext(ctx,
+			`DELETE FROM status_transition WHERE from_status_id = $1 AND to_status_id = $2`,
+			from.id, to.id,
+		)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error updating transition from %s to %s: %w", from.Name, to.Name, err)
+	}
+
+	d.allowTransition(from.id, to.id, allowed)
+
+	return nil
+}
+
+// SetWIPLimit sets the maximum number of Todos allowed in the status; 0 removes the limit. The limit may be lowered
+// below the number of Todos that are already in the status; they stay, but no more can be added until enough of
+// them are moved out.
+func (d *Database) SetWIPLimit(ctx context.Context, status *Status, limit int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status, err := d.liveStatus(status)
+	if err != nil {
+		return err
+	}
+
+	return d.setWIPLimit(ctx, status, limit)
+}
+
+func (d *Database) setWIPLimit(ctx context.Context, status *Status, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("%w: the limit must not be negative", ErrInvalidStatus)
+	}
+
+	_, err := d.conn.ExecContext(ctx, `UPDATE status SET wip_limit=$1 WHERE id=$2`, limit, status.id)
+	if err != nil {
+		return fmt.Errorf("error updating limit for %s: %w", status.Name, err)
+	}
+
+	status.WIPLimit = limit
+
+	return nil
+}
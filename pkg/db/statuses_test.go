@@ -0,0 +1,216 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedStatuses(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	names := []string{}
+	keys := []string{}
+
+	for _, status := range database.OrderedStatuses() {
+		names = append(names, status.Name)
+		keys = append(keys, status.Key)
+	}
+
+	assert.Equal([]string{"open", "closed", "on_hold", "done", "abandoned"}, names)
+	assert.Equal([]string{"o", "c", "h", "d", "a"}, keys)
+}
+
+func TestStatusByName(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	status, err := database.StatusByName(context.Background(), db.StatusOnHold)
+	assert.Nil(err)
+	assert.Equal(database.Snapshot().Statuses[db.StatusOnHold], status)
+
+	status, err = database.StatusByName(context.Background(), "someday")
+	assert.Nil(status)
+	assert.ErrorIs(err, db.ErrStatusNotFound)
+}
+
+func TestNewStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_statuses*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	_, err = database.NewStatus(ctx, "", "r", "#FF00FF", 0)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+	_, err = database.NewStatus(ctx, db.StatusOpen, "r", "#FF00FF", 0)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+	_, err = database.NewStatus(ctx, "review", "o", "#FF00FF", 0)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+	_, err = database.NewStatus(ctx, "review", "R", "#FF00FF", 0)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+	_, err = database.NewStatus(ctx, "review", "v", "pink", 0)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+	_, err = database.NewStatus(ctx, "review", "v", "#FF00FF", -1)
+	assert.ErrorIs(err, db.ErrInvalidStatus)
+
+	// keys that the UI uses for other actions, like r for review and Shift-R for redo, are reserved
+	for _, key := range db.ReservedStatusKeys {
+		_, err = database.NewStatus(ctx, "review", string(key), "#FF00FF", 0)
+		assert.ErrorIs(err, db.ErrInvalidStatus, string(key))
+	}
+
+	review, err := database.NewStatus(ctx, "review", "v", "#FF00FF", 1)
+	assert.Nil(err)
+	assert.Equal(review, database.OrderedStatuses()[5])
+
+	// new statuses can be reached from every other status
+	todo := addDefaultTodo(assert, database)
+	assert.Nil(database.ChangeStatus(ctx, todo, todo.Status, review))
+	assert.Nil(database.ChangeStatus(ctx, todo, review, database.Snapshot().Statuses[db.StatusDone]))
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	review = database.Snapshot().Statuses["review"]
+	assert.Equal("v", review.Key)
+	assert.Equal("#FF00FF", review.Color)
+	assert.Equal(1, review.WIPLimit)
+	assert.Equal(6, review.DisplayOrder)
+	assert.True(database.CanTransition(database.Snapshot().Statuses[db.StatusOpen], review))
+}
+
+func TestSetTransition(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_statuses*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	open, onHold := database.Snapshot().Statuses[db.StatusOpen], database.Snapshot().Statuses[db.StatusOnHold]
+	done := database.Snapshot().Statuses[db.StatusDone]
+
+	todo := addDefaultTodo(assert, database)
+
+	assert.False(database.CanTransition(open, done))
+	assert.ErrorIs(database.ChangeStatus(ctx, todo, open, done), db.ErrInvalidTodoMove)
+
+	assert.Nil(database.SetTransition(ctx, open, done, true))
+	assert.Nil(database.SetTransition(ctx, open, onHold, false))
+	assert.ErrorIs(database.SetTransition(ctx, open, open, true), db.ErrInvalidTodoMoveNoStatusChange)
+
+	assert.ErrorIs(database.ChangeStatus(ctx, todo, open, onHold), db.ErrInvalidTodoMove)
+	assert.Nil(database.ChangeStatus(ctx, todo, open, done))
+
+	database.Close()
+
+	database, err = db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	open, onHold = database.Snapshot().Statuses[db.StatusOpen], database.Snapshot().Statuses[db.StatusOnHold]
+	done = database.Snapshot().Statuses[db.StatusDone]
+	assert.True(database.CanTransition(open, done))
+	assert.False(database.CanTransition(open, onHold))
+	assert.True(database.CanTransition(onHold, open))
+}
+
+func TestWIPLimit(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	open, onHold := database.Snapshot().Statuses[db.StatusOpen], database.Snapshot().Statuses[db.StatusOnHold]
+
+	assert.ErrorIs(database.SetWIPLimit(ctx, onHold, -1), db.ErrInvalidStatus)
+	assert.Nil(database.SetWIPLimit(ctx, onHold, 1))
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+
+	assert.Nil(database.ChangeStatus(ctx, todo1, open, onHold))
+
+	err := database.ChangeStatus(ctx, todo2, open, onHold)
+	assert.ErrorIs(err, db.ErrWIPLimitReached)
+	assert.Contains(err.Error(), "there are already 1 todos in on_hold and the limit is 1")
+
+	// 0 removes the limit
+	assert.Nil(database.SetWIPLimit(ctx, onHold, 0))
+	assert.Nil(database.ChangeStatus(ctx, todo2, open, onHold))
+	assert.Equal(0, database.StatusLimit(onHold))
+
+	// the closed limit is the limit of the closed status
+	assert.Nil(database.SetMaxClosedTodos(ctx, 3))
+	assert.Equal(3, database.Snapshot().Statuses[db.StatusClosed].WIPLimit)
+}
+
+func TestMigrateMaxClosedTodosSetting(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_statuses*")
+	assert.Nil(err)
+
+	// before statuses had limits, the closed limit was stored in the settings table
+	settingsVersion := 5
+	assert.Nil(db.MigrateTo(ctx, tempFile.Name(), settingsVersion))
+
+	conn, err := sql.Open("sqlite3", tempFile.Name())
+	assert.Nil(err)
+
+	defer conn.Close()
+
+	_, err = conn.Exec(`INSERT INTO settings (key, value) VALUES ('max_closed_todos', '3')`)
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	assert.Equal(3, database.MaxClosedTodos())
+
+	// nothing else was ever stored in the settings table, so it is gone
+	var tables int
+
+	assert.Nil(conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='settings'`).Scan(&tables))
+	assert.Equal(0, tables)
+}
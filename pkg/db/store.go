@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Store holds the statuses, labels and todos that the UI works with, and changes them while enforcing the rules of
+// the system: ranks stay contiguous within each status, todos only move between statuses as the transitions and
+// limits allow, and every change can be undone. Database keeps them in sqlite and MemoryStore in memory only.
+//
+// The methods of a Store hold its lock, so they may be called from any goroutine. The statuses, Labels, Todos and
+// checklist items they return are copies, like those in a Snapshot, which never change afterwards: the Store keeps
+// the live ones to itself. Reading them again, e.g. with TodoByID, returns a new copy with the changes since.
+//
+// The methods that change a Todo, status, Label or checklist item look it up again by id while holding the lock, so
+// they act on the live one when given a copy, even one from before a reload. If it has been deleted in the meantime,
+// they return ErrTodoNotFound, ErrLabelNotFound or ErrChecklistItemNotFound and change nothing.
+type Store interface {
+	// Snapshot returns a copy of the statuses, labels and todos that may be read from any goroutine.
+	Snapshot() *Snapshot
+
+	// OrderedStatuses returns all the statuses in display order.
+	OrderedStatuses() []*Status
+	StatusByName(ctx context.Context, name string) (*Status, error)
+	StatusLimit(status *Status) int
+	MaxClosedTodos() int
+	MaxClosedTodosOverridden() bool
+	SetMaxClosedTodos(ctx context.Context, limit int) error
+
+	// AllLabels returns every Label, in the order they were loaded or created.
+	AllLabels() []*Label
+	LabelByName(ctx context.Context, name string) (*Label, error)
+	NewLabel(ctx context.Context, name string) (*Label, error)
+	UpdateLabel(ctx context.Context, label *Label, name string) error
+	SetLabelColor(ctx context.Context, label *Label, color string) error
+	DeleteLabel(ctx context.Context, label *Label) error
+	MergeLabels(ctx context.Context, from, into *Label) error
+	TodosWithLabel(label *Label) []*Todo
+
+	TodoByID(ctx context.Context, id int) (*Todo, error)
+	NewTodo(ctx context.Context, title, description string) (*Todo, error)
+	UpdateTodo(ctx context.Context, todo *Todo, title, description string) error
+	DeleteTodo(ctx context.Context, todo *Todo) error
+	ChangeStatus(ctx context.Context, todo *Todo, oldStatus, newStatus *Status) error
+	MoveUp(ctx context.Context, todo *Todo) error
+	MoveDown(ctx context.Context, todo *Todo) error
+	MoveToTop(ctx context.Context, todo *Todo) error
+	MoveToBottom(ctx context.Context, todo *Todo) error
+	MoveToRank(ctx context.Context, todo *Todo, rank int) error
+	// AddTodoLabel and RemoveTodoLabel do nothing, and record nothing to undo, when the Todo already has the Label or
+	// doesn't have it. A nil Todo or Label is ErrNilTodo or ErrNilLabel.
+	AddTodoLabel(ctx context.Context, todo *Todo, label *Label) error
+	RemoveTodoLabel(ctx context.Context, todo *Todo, label *Label) error
+	SetDueDate(ctx context.Context, todo *Todo, due *time.Time) error
+	SetRecurrence(ctx context.Context, todo *Todo, recurrence *Recurrence) error
+	Snooze(ctx context.Context, todo *Todo, until time.Time) error
+	WakeSnoozed(ctx context.Context) ([]*Todo, error)
+
+	AddChecklistItem(ctx context.Context, todo *Todo, text string) (*ChecklistItem, error)
+	SetChecklistItemDone(ctx context.Context, todo *Todo, item *ChecklistItem, done bool) error
+	MoveChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem, idx int) error
+	DeleteChecklistItem(ctx context.Context, todo *Todo, item *ChecklistItem) error
+
+	TodosByDueDate() []*Todo
+	Search(ctx context.Context, query string) ([]*Todo, error)
+	History(ctx context.Context, todo *Todo) ([]*TodoEvent, error)
+	TodosCompletedBetween(ctx context.Context, from, to time.Time) ([]*CompletedTodo, error)
+	TodosAbandonedBetween(ctx context.Context, from, to time.Time) ([]*CompletedTodo, error)
+
+	Undo(ctx context.Context) error
+	Redo(ctx context.Context) error
+	// ReloadIfChanged reloads the Store if something else has changed its data, and reports whether it did.
+	ReloadIfChanged(ctx context.Context) (bool, error)
+}
+
+// model is the in-memory state shared by the Store implementations, along with the rules that only depend on it.
+// Its methods expect the caller to hold the lock of the Store.
+type model struct {
+	// statuses, labels and todos are the live in-memory model. They never leave the Store: its methods return copies.
+	statuses map[string]*Status
+	labels   []*Label
+	todos    []*Todo
+
+	// transitions contains the ids of the statuses that Todos may move to, by the id of the status they move from.
+	transitions map[int]map[int]bool
+	// maxClosedOverride replaces the persisted limit on closed todos for this session when it is positive.
+	maxClosedOverride int
+
+	// undoStack and redoStack contain the operations performed during this session, so that they can be undone and
+	// redone. They aren't persisted.
+	undoStack []*operation
+	redoStack []*operation
+}
+
+func newModel() model {
+	return model{
+		statuses:    map[string]*Status{},
+		labels:      []*Label{},
+		todos:       []*Todo{},
+		transitions: map[int]map[int]bool{},
+	}
+}
+
+// liveTodo returns the Todo in the model with the id of the given one. Callers only ever hold copies, which may be
+// from before a reload, so the methods that change Todos look them up again while holding the lock.
+func (m *model) liveTodo(todo *Todo) (*Todo, error) {
+	if todo == nil {
+		return nil, ErrNilTodo
+	}
+
+	for _, live := range m.todos {
+		if live.id == todo.id {
+			return live, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: #%d '%s' has been deleted", ErrTodoNotFound, todo.id, todo.Title)
+}
+
+// liveStatus returns the status in the model with the id of the given one, like liveTodo.
+func (m *model) liveStatus(status *Status) (*Status, error) {
+	if status == nil {
+		return nil, fmt.Errorf("%w: no status given", ErrStatusNotFound)
+	}
+
+	if live := m.statusByID(status.id); live != nil {
+		return live, nil
+	}
+
+	return nil, fmt.Errorf("%w named '%s'", ErrStatusNotFound, status.Name)
+}
+
+// liveLabel returns the Label in the model with the id of the given one, like liveTodo.
+func (m *model) liveLabel(label *Label) (*Label, error) {
+	if label == nil {
+		return nil, ErrNilLabel
+	}
+
+	for _, live := range m.labels {
+		if live.ID == label.ID {
+			return live, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: '%s' has been deleted", ErrLabelNotFound, label.Name)
+}
+
+// liveTodoLabel looks up a Todo and a Label with liveTodo and liveLabel.
+func (m *model) liveTodoLabel(todo *Todo, label *Label) (*Todo, *Label, error) {
+	todo, err := m.liveTodo(todo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	label, err = m.liveLabel(label)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return todo, label, nil
+}
+
+// liveStatusChange looks up the arguments of ChangeStatus with liveTodo and liveStatus.
+func (m *model) liveStatusChange(todo *Todo, oldStatus, newStatus *Status) (*Todo, *Status, *Status, error) {
+	todo, err := m.liveTodo(todo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if oldStatus, err = m.liveStatus(oldStatus); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if newStatus, err = m.liveStatus(newStatus); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return todo, oldStatus, newStatus, nil
+}
@@ -0,0 +1,577 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStores runs the test against every Store implementation, so that they all enforce the same rules.
+func testStores(t *testing.T, test func(assert *assert.Assertions, store db.Store)) {
+	t.Helper()
+
+	t.Run("sqlite", func(t *testing.T) {
+		t.Parallel()
+
+		assert := assert.New(t)
+		database := getDB(assert)
+
+		defer database.Close()
+
+		test(assert, database)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+
+		test(assert.New(t), db.NewMemoryStore())
+	})
+}
+
+func storeStatus(assert *assert.Assertions, store db.Store, name string) *db.Status {
+	status, err := store.StatusByName(context.Background(), name)
+	assert.Nil(err)
+
+	return status
+}
+
+func storeLabel(assert *assert.Assertions, store db.Store, name string) *db.Label {
+	label, err := store.LabelByName(context.Background(), name)
+	assert.Nil(err)
+
+	return label
+}
+
+// storeTodo returns a new copy of the Todo: the copies that the Store returned earlier don't show later changes.
+func storeTodo(assert *assert.Assertions, store db.Store, todo *db.Todo) *db.Todo {
+	todo, err := store.TodoByID(context.Background(), todo.ID())
+	assert.Nil(err)
+
+	return todo
+}
+
+func storeTodos(assert *assert.Assertions, store db.Store, titles ...string) []*db.Todo {
+	todos := make([]*db.Todo, 0, len(titles))
+
+	for _, title := range titles {
+		todo, err := store.NewTodo(context.Background(), title, "")
+		assert.Nil(err)
+
+		todos = append(todos, todo)
+	}
+
+	return todos
+}
+
+// storeTitles returns the titles of the Todos in the status with the given name, in rank order.
+func storeTitles(assert *assert.Assertions, store db.Store, name string) []string {
+	return statusTitles(storeStatus(assert, store, name))
+}
+
+func assertStoreRanks(assert *assert.Assertions, store db.Store) {
+	statuses := map[string]*db.Status{}
+	for _, status := range store.OrderedStatuses() {
+		statuses[status.Name] = status
+	}
+
+	assertRanks(assert, statuses)
+}
+
+func TestStoreDefaults(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		names := []string{}
+		for _, status := range store.OrderedStatuses() {
+			names = append(names, status.Name)
+			assert.Empty(status.Todos)
+		}
+
+		assert.Equal(
+			[]string{db.StatusOpen, db.StatusClosed, db.StatusOnHold, db.StatusDone, db.StatusAbandoned}, names,
+		)
+		assert.Equal(db.DefaultMaxClosedTodos, store.MaxClosedTodos())
+		assert.Len(store.AllLabels(), 9)
+
+		label, err := store.LabelByName(context.Background(), "urgent")
+		assert.Nil(err)
+		assert.Equal(4, label.ID)
+
+		_, err = store.StatusByName(context.Background(), "nope")
+		assert.ErrorIs(err, db.ErrStatusNotFound)
+	})
+}
+
+func TestStoreRanks(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b", "c", "d")
+
+		assert.ErrorIs(store.MoveUp(ctx, todos[0]), db.ErrCantMoveFirstTodoUp)
+		assert.ErrorIs(store.MoveToTop(ctx, todos[0]), db.ErrCantMoveFirstTodoUp)
+		assert.ErrorIs(store.MoveDown(ctx, todos[3]), db.ErrCantMoveLastTodoDown)
+		assert.ErrorIs(store.MoveToBottom(ctx, todos[3]), db.ErrCantMoveLastTodoDown)
+
+		assert.Nil(store.MoveUp(ctx, todos[2]))
+		assert.Equal([]string{"a", "c", "b", "d"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.MoveDown(ctx, todos[0]))
+		assert.Equal([]string{"c", "a", "b", "d"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.MoveToTop(ctx, todos[3]))
+		assert.Equal([]string{"d", "c", "a", "b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.MoveToBottom(ctx, todos[2]))
+		assert.Equal([]string{"d", "a", "b", "c"}, storeTitles(assert, store, db.StatusOpen))
+		assertStoreRanks(assert, store)
+
+		assert.Nil(store.MoveToRank(ctx, todos[2], 1))
+		assert.Equal([]string{"d", "c", "a", "b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.MoveToRank(ctx, todos[3], 2))
+		assert.Equal([]string{"c", "a", "d", "b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.MoveToRank(ctx, todos[3], 2))
+		assert.ErrorIs(store.MoveToRank(ctx, todos[3], 4), db.ErrInvalidTodoMove)
+		assertStoreRanks(assert, store)
+
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]string{"d", "c", "a", "b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]string{"d", "a", "b", "c"}, storeTitles(assert, store, db.StatusOpen))
+		assertStoreRanks(assert, store)
+
+		assert.Nil(store.DeleteTodo(ctx, todos[0]))
+		assert.Equal([]string{"d", "b", "c"}, storeTitles(assert, store, db.StatusOpen))
+		assertStoreRanks(assert, store)
+
+		_, err := store.TodoByID(ctx, todos[0].ID())
+		assert.ErrorIs(err, db.ErrTodoNotFound)
+
+		assert.ErrorIs(store.MoveUp(ctx, nil), db.ErrNilTodo)
+		assert.ErrorIs(store.DeleteTodo(ctx, nil), db.ErrNilTodo)
+
+		_, err = store.NewTodo(ctx, "", "")
+		assert.ErrorIs(err, db.ErrEmptyTitle)
+	})
+}
+
+func TestStoreStatusRules(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b", "c")
+		open := storeStatus(assert, store, db.StatusOpen)
+		closed := storeStatus(assert, store, db.StatusClosed)
+		done := storeStatus(assert, store, db.StatusDone)
+
+		assert.ErrorIs(store.ChangeStatus(ctx, todos[0], open, open), db.ErrInvalidTodoMoveNoStatusChange)
+		assert.ErrorIs(store.ChangeStatus(ctx, todos[0], open, done), db.ErrInvalidTodoMove)
+		assert.ErrorIs(store.ChangeStatus(ctx, todos[0], closed, done), db.ErrInvalidTodoMove)
+
+		assert.Nil(store.SetMaxClosedTodos(ctx, 1))
+		assert.Equal(1, store.MaxClosedTodos())
+
+		assert.Nil(store.ChangeStatus(ctx, todos[1], open, closed))
+		assert.ErrorIs(store.ChangeStatus(ctx, todos[0], open, closed), db.ErrMaxClosedTodos)
+		assert.ErrorIs(store.ChangeStatus(ctx, todos[1], closed, open), db.ErrInvalidTodoMove)
+
+		assert.Nil(store.ChangeStatus(ctx, todos[1], closed, done))
+		assert.Equal([]string{"a", "c"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Equal([]string{"b"}, storeTitles(assert, store, db.StatusDone))
+		assertStoreRanks(assert, store)
+
+		assert.Nil(store.ChangeStatus(ctx, todos[2], open, closed))
+		assert.Equal(closed.Name, storeTodo(assert, store, todos[2]).Status.Name)
+		assert.Equal(0, storeTodo(assert, store, todos[2]).Rank)
+	})
+}
+
+func TestStoreRecurrence(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todo := storeTodos(assert, store, "water the plants")[0]
+		open := storeStatus(assert, store, db.StatusOpen)
+		closed := storeStatus(assert, store, db.StatusClosed)
+
+		recurrence, err := db.ParseRecurrence("daily")
+		assert.Nil(err)
+		assert.Nil(store.SetRecurrence(ctx, todo, recurrence))
+
+		_, err = store.AddChecklistItem(ctx, todo, "the ferns")
+		assert.Nil(err)
+
+		assert.Nil(store.ChangeStatus(ctx, todo, open, closed))
+		assert.Nil(store.ChangeStatus(ctx, todo, closed, storeStatus(assert, store, db.StatusDone)))
+		assert.Nil(storeTodo(assert, store, todo).Recurrence)
+
+		open = storeStatus(assert, store, db.StatusOpen)
+		assert.Len(open.Todos, 1)
+
+		next := open.Todos[0]
+		assert.Equal("water the plants", next.Title)
+		assert.Equal("daily", db.FormatRecurrence(next.Recurrence))
+		assert.NotNil(next.DueDate)
+		assert.Len(next.Checklist, 1)
+		assert.False(next.Checklist[0].Done)
+		assert.Contains(store.TodosByDueDate(), next)
+	})
+}
+
+func TestStoreSnooze(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b")
+		open := storeStatus(assert, store, db.StatusOpen)
+		onHold := storeStatus(assert, store, db.StatusOnHold)
+
+		assert.ErrorIs(store.Snooze(ctx, todos[0], time.Now().Add(-time.Hour)), db.ErrSnoozeInPast)
+
+		until := time.Now().Add(time.Hour)
+		assert.Nil(store.Snooze(ctx, todos[0], until))
+		assert.Equal([]string{"b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Equal([]string{"a"}, storeTitles(assert, store, db.StatusOnHold))
+		assert.True(until.Equal(*storeTodo(assert, store, todos[0]).SnoozedUntil))
+		assertStoreRanks(assert, store)
+
+		// moving the todo out of on_hold cancels the snooze, and undoing the move brings it back
+		assert.Nil(store.ChangeStatus(ctx, todos[0], onHold, open))
+		assert.Nil(storeTodo(assert, store, todos[0]).SnoozedUntil)
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]string{"a"}, storeTitles(assert, store, db.StatusOnHold))
+		assert.True(until.Equal(*storeTodo(assert, store, todos[0]).SnoozedUntil))
+
+		woken, err := store.WakeSnoozed(ctx)
+		assert.Nil(err)
+		assert.Empty(woken)
+
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]string{"a", "b"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(storeTodo(assert, store, todos[0]).SnoozedUntil)
+		assertStoreRanks(assert, store)
+	})
+}
+
+func TestStoreLabels(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b")
+
+		_, err := store.NewLabel(ctx, "urgent")
+		assert.ErrorIs(err, db.ErrLabelExists)
+
+		home, err := store.NewLabel(ctx, "home")
+		assert.Nil(err)
+		assert.Len(store.AllLabels(), 10)
+
+		errands, err := store.NewLabel(ctx, "errands")
+		assert.Nil(err)
+		assert.ErrorIs(store.UpdateLabel(ctx, errands, "home"), db.ErrLabelExists)
+		assert.Nil(store.UpdateLabel(ctx, errands, "chores"))
+		assert.ErrorIs(store.SetLabelColor(ctx, errands, "red"), db.ErrInvalidColor)
+		assert.Nil(store.SetLabelColor(ctx, errands, "#FF0000"))
+
+		assert.Nil(store.AddTodoLabel(ctx, todos[0], home))
+		assert.Nil(store.AddTodoLabel(ctx, todos[0], home))
+		assert.Equal([]*db.Label{home}, storeTodo(assert, store, todos[0]).Labels)
+		assert.Nil(store.AddTodoLabel(ctx, todos[0], errands))
+		assert.Nil(store.AddTodoLabel(ctx, todos[1], errands))
+		assert.Equal([]string{"a", "b"}, todoTitles(store.TodosWithLabel(errands)))
+
+		assert.ErrorIs(store.MergeLabels(ctx, home, home), db.ErrMergeLabelIntoItself)
+		assert.Nil(store.MergeLabels(ctx, errands, home))
+		assert.Equal([]*db.Label{home}, storeTodo(assert, store, todos[0]).Labels)
+		assert.Equal([]*db.Label{home}, storeTodo(assert, store, todos[1]).Labels)
+		assert.NotContains(store.AllLabels(), errands)
+
+		assert.Nil(store.RemoveTodoLabel(ctx, todos[1], home))
+		assert.Empty(storeTodo(assert, store, todos[1]).Labels)
+
+		assert.Nil(store.DeleteLabel(ctx, home))
+		assert.Empty(storeTodo(assert, store, todos[0]).Labels)
+		assert.Len(store.AllLabels(), 9)
+
+		_, err = store.LabelByName(ctx, "home")
+		assert.ErrorIs(err, db.ErrLabelNotFound)
+	})
+}
+
+func TestStoreTodoLabelContract(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todo := storeTodos(assert, store, "a")[0]
+		urgent := storeLabel(assert, store, "urgent")
+		task := storeLabel(assert, store, "task")
+
+		assert.ErrorIs(store.AddTodoLabel(ctx, nil, urgent), db.ErrNilTodo)
+		assert.ErrorIs(store.AddTodoLabel(ctx, todo, nil), db.ErrNilLabel)
+		assert.ErrorIs(store.RemoveTodoLabel(ctx, nil, urgent), db.ErrNilTodo)
+		assert.ErrorIs(store.RemoveTodoLabel(ctx, todo, nil), db.ErrNilLabel)
+
+		assert.Nil(store.AddTodoLabel(ctx, todo, urgent))
+
+		history, err := store.History(ctx, todo)
+		assert.Nil(err)
+
+		// adding a label twice or removing one the todo doesn't have changes nothing and leaves nothing to undo
+		assert.Nil(store.AddTodoLabel(ctx, todo, urgent))
+		assert.Nil(store.RemoveTodoLabel(ctx, todo, task))
+		assert.Equal([]*db.Label{urgent}, storeTodo(assert, store, todo).Labels)
+
+		unchanged, err := store.History(ctx, todo)
+		assert.Nil(err)
+		assert.Equal(len(history), len(unchanged))
+
+		assert.Nil(store.Undo(ctx))
+		assert.Empty(storeTodo(assert, store, todo).Labels)
+		assert.Nil(store.Redo(ctx))
+		assert.Equal([]*db.Label{urgent}, storeTodo(assert, store, todo).Labels)
+
+		assert.Nil(store.DeleteLabel(ctx, task))
+		assert.ErrorIs(store.AddTodoLabel(ctx, todo, task), db.ErrLabelNotFound)
+		assert.ErrorIs(store.RemoveTodoLabel(ctx, todo, task), db.ErrLabelNotFound)
+
+		assert.Nil(store.DeleteTodo(ctx, todo))
+		assert.ErrorIs(store.AddTodoLabel(ctx, todo, urgent), db.ErrTodoNotFound)
+		assert.ErrorIs(store.RemoveTodoLabel(ctx, todo, urgent), db.ErrTodoNotFound)
+	})
+}
+
+func TestStoreChecklist(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todo := storeTodos(assert, store, "pack")[0]
+
+		_, err := store.AddChecklistItem(ctx, todo, "")
+		assert.ErrorIs(err, db.ErrEmptyChecklistItem)
+
+		items := []*db.ChecklistItem{}
+
+		for _, text := range []string{"socks", "shirts", "toothbrush"} {
+			item, err := store.AddChecklistItem(ctx, todo, text)
+			assert.Nil(err)
+
+			items = append(items, item)
+		}
+
+		assert.Nil(store.SetChecklistItemDone(ctx, todo, items[1], true))
+		assert.Nil(store.MoveChecklistItem(ctx, todo, items[2], 0))
+		assert.Equal([]string{"toothbrush", "socks", "shirts"}, checklistTexts(storeTodo(assert, store, todo)))
+
+		done, total := storeTodo(assert, store, todo).ChecklistProgress()
+		assert.Equal(1, done)
+		assert.Equal(3, total)
+
+		assert.Nil(store.DeleteChecklistItem(ctx, todo, items[0]))
+		assert.Equal([]string{"toothbrush", "shirts"}, checklistTexts(storeTodo(assert, store, todo)))
+		assert.ErrorIs(store.DeleteChecklistItem(ctx, todo, items[0]), db.ErrChecklistItemNotFound)
+
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]string{"toothbrush", "socks", "shirts"}, checklistTexts(storeTodo(assert, store, todo)))
+	})
+}
+
+func TestStoreUndoRedo(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b", "c")
+		open := storeStatus(assert, store, db.StatusOpen)
+		closed := storeStatus(assert, store, db.StatusClosed)
+
+		assert.Nil(store.ChangeStatus(ctx, todos[1], open, closed))
+		assert.Nil(store.UpdateTodo(ctx, todos[0], "A", "first"))
+		assert.Nil(store.DeleteTodo(ctx, todos[2]))
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(store.Undo(ctx))
+		}
+
+		assert.Equal([]string{"a", "b", "c"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Empty(storeTitles(assert, store, db.StatusClosed))
+		assertStoreRanks(assert, store)
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(store.Redo(ctx))
+		}
+
+		assert.ErrorIs(store.Redo(ctx), db.ErrNothingToRedo)
+		assert.Equal([]string{"A"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Equal([]string{"b"}, storeTitles(assert, store, db.StatusClosed))
+		assert.Equal("first", storeTodo(assert, store, todos[0]).Description)
+		assertStoreRanks(assert, store)
+
+		reloaded, err := store.ReloadIfChanged(ctx)
+		assert.Nil(err)
+		assert.False(reloaded)
+	})
+}
+
+func TestStoreUndoWithLabelChanges(t *testing.T) {
+	t.Parallel()
+
+	// renaming, recoloring and creating labels can't be undone, so undoing other operations must leave them alone
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todo := storeTodos(assert, store, "a")[0]
+
+		task, err := store.LabelByName(ctx, "task")
+		assert.Nil(err)
+		assert.Nil(store.UpdateLabel(ctx, task, "chore"))
+
+		fresh, err := store.NewLabel(ctx, "fresh")
+		assert.Nil(err)
+
+		assert.Nil(store.Undo(ctx))
+		assert.Empty(storeTitles(assert, store, db.StatusOpen))
+		assert.Equal(task.ID, storeLabel(assert, store, "chore").ID)
+		assert.Equal(fresh, storeLabel(assert, store, "fresh"))
+
+		assert.Nil(store.Redo(ctx))
+		assert.Equal([]string{"a"}, storeTitles(assert, store, db.StatusOpen))
+		assert.Nil(store.AddTodoLabel(ctx, todo, fresh))
+		assert.Nil(store.SetLabelColor(ctx, fresh, "#123456"))
+		assert.Nil(store.UpdateLabel(ctx, fresh, "newer"))
+
+		assert.Nil(store.Undo(ctx))
+		assert.Empty(storeTodo(assert, store, todo).Labels)
+		assert.Equal(fresh.ID, storeLabel(assert, store, "newer").ID)
+		assert.Equal("#123456", storeLabel(assert, store, "newer").Color)
+
+		assert.Nil(store.Redo(ctx))
+		assert.Nil(store.DeleteLabel(ctx, fresh))
+		assert.Nil(store.UpdateLabel(ctx, task, "errand"))
+		assert.Nil(store.MergeLabels(ctx, task, storeLabel(assert, store, "urgent")))
+
+		assert.Nil(store.Undo(ctx))
+		assert.Nil(store.Undo(ctx))
+		assert.Equal([]*db.Label{storeLabel(assert, store, "newer")}, storeTodo(assert, store, todo).Labels)
+		assert.Equal(task.ID, storeLabel(assert, store, "errand").ID)
+		assert.Len(store.AllLabels(), 10)
+	})
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "a", "b")
+
+		label, err := store.LabelByName(ctx, "urgent")
+		assert.Nil(err)
+		assert.Nil(store.AddTodoLabel(ctx, todos[0], label))
+
+		snapshot := store.Snapshot()
+
+		assert.Nil(store.UpdateLabel(ctx, label, "pressing"))
+		assert.Nil(store.MoveToTop(ctx, todos[1]))
+
+		open, err := snapshot.StatusByName(ctx, db.StatusOpen)
+		assert.Nil(err)
+		assert.NotSame(storeStatus(assert, store, db.StatusOpen), open)
+		assert.Equal([]string{"a", "b"}, statusTitles(open))
+
+		urgent, err := snapshot.LabelByName(ctx, "urgent")
+		assert.Nil(err)
+		assert.Same(urgent, open.Todos[0].Labels[0])
+
+		_, err = snapshot.LabelByName(ctx, "pressing")
+		assert.ErrorIs(err, db.ErrLabelNotFound)
+
+		_, err = snapshot.StatusByName(ctx, "someday")
+		assert.ErrorIs(err, db.ErrStatusNotFound)
+	})
+}
+
+func TestStoreHistoryAndSearch(t *testing.T) {
+	t.Parallel()
+
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		start := time.Now().Add(-time.Minute)
+		todos := storeTodos(assert, store, "write the report", "read the paper")
+		open := storeStatus(assert, store, db.StatusOpen)
+		closed := storeStatus(assert, store, db.StatusClosed)
+
+		assert.Nil(store.UpdateTodo(ctx, todos[0], "write the report", "about sqlite"))
+		assert.Nil(store.ChangeStatus(ctx, todos[0], open, closed))
+		assert.Nil(store.ChangeStatus(ctx, todos[0], closed, storeStatus(assert, store, db.StatusDone)))
+		assert.Nil(store.ChangeStatus(ctx, todos[1], open, storeStatus(assert, store, db.StatusAbandoned)))
+
+		events, err := store.History(ctx, todos[0])
+		assert.Nil(err)
+
+		types := []db.EventType{}
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+
+		assert.Equal(
+			[]db.EventType{db.EventCreated, db.EventEdited, db.EventStatusChanged, db.EventStatusChanged}, types,
+		)
+
+		end := time.Now().Add(time.Minute)
+
+		completed, err := store.TodosCompletedBetween(ctx, start, end)
+		assert.Nil(err)
+		assert.Len(completed, 1)
+		assert.Equal(todos[0].ID(), completed[0].Todo.ID())
+
+		abandoned, err := store.TodosAbandonedBetween(ctx, start, end)
+		assert.Nil(err)
+		assert.Len(abandoned, 1)
+		assert.Equal(todos[1].ID(), abandoned[0].Todo.ID())
+
+		found, err := store.Search(ctx, "sqlite")
+		assert.Nil(err)
+		assert.Equal([]string{"write the report"}, todoTitles(found))
+
+		found, err = store.Search(ctx, "the")
+		assert.Nil(err)
+		assert.ElementsMatch(todoTitles(todos), todoTitles(found))
+	})
+}
+
+func TestStoreSearchMatchesWordPrefixes(t *testing.T) {
+	t.Parallel()
+
+	// with the sqlite_fts5 build tag the sqlite store searches its full-text index, and without it both stores search
+	// in memory; either way they must find the same todos
+	testStores(t, func(assert *assert.Assertions, store db.Store) {
+		ctx := context.Background()
+		todos := storeTodos(assert, store, "send the e-mail", "quarterly report_v2")
+
+		assert.Nil(store.UpdateTodo(ctx, todos[1], todos[1].Title, "for the café"))
+
+		for query, want := range map[string][]*db.Todo{
+			"rep":      {todos[1]},
+			"port":     nil,
+			"mail":     {todos[0]},
+			"e-ma":     {todos[0]},
+			"ail":      nil,
+			"v2":       {todos[1]},
+			"café":     {todos[1]},
+			"cafe":     nil,
+			"--":       nil,
+			"the":      {todos[0], todos[1]},
+			"send the": {todos[0]},
+		} {
+			found, err := store.Search(ctx, query)
+			assert.Nil(err, query)
+			assert.ElementsMatch(todoTitles(want), todoTitles(found), query)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+-- Sample data used to populate fixture databases at every schema version. Only columns present in the first
+-- schema version may be referenced here, so that the same data can be loaded before each upgrade.
+INSERT INTO todo (id, title, description, status_id, rank, created_datetime, updated_datetime) VALUES
+	(1, 'fixture open 1', 'the first open todo', 1, 0, '2021-11-01 10:00:00', NULL),
+	(2, 'fixture closed', 'a closed todo', 2, 0, '2021-11-02 10:00:00', '2021-11-03 09:30:00'),
+	(3, 'fixture open 2', '', 1, 1, '2021-11-04 10:00:00', NULL),
+	(4, 'fixture done', '', 4, 0, '2021-11-05 10:00:00', '2021-11-06 17:00:00')
+;
+
+INSERT INTO label (id, name) VALUES
+	(10, 'fixture_label')
+;
+
+INSERT INTO todo_label (todo_id, label_id) VALUES
+	(1, 1),
+	(1, 10),
+	(2, 4)
+;
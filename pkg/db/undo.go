@@ -0,0 +1,498 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxUndoOperations limits how many operations can be undone so that the undo stack doesn't grow without bound
+// during a long session.
+const maxUndoOperations = 100
+
+var (
+	// ErrNothingToUndo is returned from Undo when no operations have been performed.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned from Redo when no operations have been undone.
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// operation is a reversible change to the Database. undo reverts the change and redo reapplies it; neither
+// validates the change, since the state they restore has already been validated once.
+type operation struct {
+	description string
+	undo        func(ctx context.Context) error
+	redo        func(ctx context.Context) error
+}
+
+// pushUndo records an operation that was just performed. Performing a new operation discards any operations that
+// could have been redone.
+func (m *model) pushUndo(op *operation) {
+	m.undoStack = append(m.undoStack, op)
+	if len(m.undoStack) > maxUndoOperations {
+		m.undoStack = m.undoStack[len(m.undoStack)-maxUndoOperations:]
+	}
+
+	m.redoStack = nil
+}
+
+// placement is where a Todo is: its status and rank, and when it wakes up if it is snoozed. Moving a Todo out of
+// on_hold clears its wake-up time, so undoing the move has to restore it.
+type placement struct {
+	status       *Status
+	rank         int
+	snoozedUntil *time.Time
+	woken        bool
+}
+
+func placementOf(todo *Todo) placement {
+	return placement{status: todo.Status, rank: todo.Rank, snoozedUntil: todo.SnoozedUntil, woken: todo.Woken}
+}
+
+// pushMove records the undo operation for a change of status or rank, after the change has been applied.
+func (d *Database) pushMove(description string, todo *Todo, old placement) {
+	newPlacement := placementOf(todo)
+
+	d.pushUndo(&operation{
+		description: description,
+		undo: func(ctx context.Context) error {
+			return d.placeTodo(ctx, todo, old)
+		},
+		redo: func(ctx context.Context) error {
+			return d.placeTodo(ctx, todo, newPlacement)
+		},
+	})
+}
+
+// Undo reverts the most recent operation that hasn't already been undone.
+func (d *Database) Undo(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.undo(ctx)
+}
+
+// Redo reapplies the most recently undone operation.
+func (d *Database) Redo(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.redo(ctx)
+}
+
+func (m *model) undo(ctx context.Context) error {
+	if len(m.undoStack) == 0 {
+		return ErrNothingToUndo
+	}
+
+	op := m.undoStack[len(m.undoStack)-1]
+
+	if err := op.undo(ctx); err != nil {
+		return fmt.Errorf("error undoing %s: %w", op.description, err)
+	}
+
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.redoStack = append(m.redoStack, op)
+
+	return nil
+}
+
+func (m *model) redo(ctx context.Context) error {
+	if len(m.redoStack) == 0 {
+		return ErrNothingToRedo
+	}
+
+	op := m.redoStack[len(m.redoStack)-1]
+
+	if err := op.redo(ctx); err != nil {
+		return fmt.Errorf("error redoing %s: %w", op.description, err)
+	}
+
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.undoStack = append(m.undoStack, op)
+
+	return nil
+}
+
+// without returns a copy of todos without the given Todo.
+func without(todos []*Todo, todo *Todo) []*Todo {
+	result := make([]*Todo, 0, len(todos))
+
+	for _, t := range todos {
+		if t != todo {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+// insertAt returns a copy of todos with the given Todo inserted at rank, or appended if rank is out of range.
+func insertAt(todos []*Todo, todo *Todo, rank int) []*Todo {
+	if rank < 0 || rank > len(todos) {
+		rank = len(todos)
+	}
+
+	result := make([]*Todo, 0, len(todos)+1)
+	result = append(result, todos[:rank]...)
+	result = append(result, todo)
+
+	return append(result, todos[rank:]...)
+}
+
+// persistOrder rewrites the status and rank of every Todo in the given orderings. Existing ranks in each status are
+// negated first so that intermediate states don't violate the unique index on status_id + rank.
+func persistOrder(ctx context.Context, txn *sql.Tx, orders map[*Status][]*Todo) error {
+	for status := range orders {
+		_, err := txn.ExecContext(ctx, `UPDATE todo SET rank=-1 - rank WHERE status_id=$1 AND rank >= 0`, status.id)
+		if err != nil {
+			return fmt.Errorf("error temp updating todos: %w", err)
+		}
+	}
+
+	for status, todos := range orders {
+		for rank, todo := range todos {
+			_, err := txn.ExecContext(ctx, `UPDATE todo SET status_id=$1, rank=$2 WHERE id=$3`, status.id, rank, todo.id)
+			if err != nil {
+				return fmt.Errorf("error updating todo: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyOrder updates the in-memory state to match orderings that were persisted with persistOrder.
+func applyOrder(orders map[*Status][]*Todo) {
+	for status, todos := range orders {
+		status.Todos = todos
+
+		for rank, todo := range todos {
+			todo.Status = status
+			todo.Rank = rank
+		}
+	}
+}
+
+// placeTodo moves a Todo to the given placement, shifting other Todos to make room. Unlike ChangeStatus, it doesn't
+// validate the move, which allows undo to reverse moves that aren't normally permitted.
+func (d *Database) placeTodo(ctx context.Context, todo *Todo, to placement) error {
+	status, rank := to.status, to.rank
+	oldStatus := todo.Status
+	orders := map[*Status][]*Todo{}
+
+	target := without(oldStatus.Todos, todo)
+	if status != oldStatus {
+		orders[oldStatus] = target
+		target = status.Todos
+	}
+
+	if rank < 0 || rank > len(target) {
+		rank = len(target)
+	}
+
+	orders[status] = insertAt(target, todo, rank)
+
+	event := &TodoEvent{
+		Type:      EventReranked,
+		OldStatus: oldStatus,
+		NewStatus: status,
+		OldRank:   todo.Rank,
+		NewRank:   rank,
+	}
+	if status != oldStatus {
+		event.Type = EventStatusChanged
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if err = persistOrder(ctx, txn, orders); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	_, err = txn.ExecContext(ctx, `UPDATE todo SET snoozed_until=$1, woken=$2 WHERE id=$3`,
+		nullTime(to.snoozedUntil), to.woken, todo.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error updating snooze of todo '%s': %w", todo.Title, err))
+	}
+
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &event.Datetime
+
+	applyOrder(orders)
+
+	todo.SnoozedUntil = to.snoozedUntil
+	todo.Woken = to.woken
+
+	return nil
+}
+
+// deleteTodo removes a Todo with its labels, checklist and dependencies and compacts the ranks of the remaining Todos
+// in its status. The Todo object is left intact so that it can be restored with restoreTodo.
+func (d *Database) deleteTodo(ctx context.Context, todo *Todo) error {
+	status := todo.Status
+	orders := map[*Status][]*Todo{status: without(status.Todos, todo)}
+	dependents := d.dependentsOf(todo)
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	// record the deletion while the todo still exists; it is kept with the rest of the history for restoreTodo
+	event := &TodoEvent{Type: EventDeleted, OldStatus: status, OldRank: todo.Rank}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	history, err := deleteEvents(ctx, txn, todo)
+	if err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM todo_label WHERE todo_id=$1`, todo.id); err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error removing labels from todo '%s': %w", todo.Title, err))
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM checklist_item WHERE todo_id=$1`, todo.id); err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error removing checklist from todo '%s': %w", todo.Title, err))
+	}
+
+	_, err = txn.ExecContext(ctx, `DELETE FROM todo_dependency WHERE todo_id=$1 OR blocker_id=$1`, todo.id)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error removing dependencies of todo '%s': %w", todo.Title, err))
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM todo WHERE id=$1`, todo.id); err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error deleting todo '%s': %w", todo.Title, err))
+	}
+
+	if err = persistOrder(ctx, txn, orders); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	// recording the deletion indexed the todo again, so remove it from the search index now that it's gone
+	if err = d.indexTodo(ctx, txn, todo); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	applyOrder(orders)
+
+	d.todos = without(d.todos, todo)
+
+	todo.history = history
+
+	todo.dependents = dependents
+	for _, dependent := range dependents {
+		idx := dependencyIndex(dependent, todo)
+		dependent.BlockedBy = append(dependent.BlockedBy[:idx:idx], dependent.BlockedBy[idx+1:]...)
+	}
+
+	return nil
+}
+
+// restoreTodo reinserts a Todo removed by deleteTodo with its original id, labels, checklist and dependencies at the
+// given rank in the given status.
+func (d *Database) restoreTodo(ctx context.Context, todo *Todo, status *Status, rank int) error {
+	orders := map[*Status][]*Todo{status: insertAt(status.Todos, todo, rank)}
+
+	// Todos that were deleted after this one can't be restored as its blockers or dependents
+	todo.BlockedBy = d.liveTodos(todo.BlockedBy)
+	todo.dependents = d.liveTodos(todo.dependents)
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	// insert at the end of the list, which is always free, and then move into place
+	_, err = txn.ExecContext(ctx,
+		`INSERT INTO todo (
+			id, title, description, status_id, rank, created_datetime, updated_datetime, due_date, recurrence,
+			snoozed_until, woken
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		todo.id, todo.Title, todo.Description, status.id, len(status.Todos), todo.CreatedDatetime, todo.UpdatedDatetime,
+		nullDueDate(todo.DueDate), nullString(FormatRecurrence(todo.Recurrence)), nullTime(todo.SnoozedUntil), todo.Woken,
+	)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error restoring todo '%s': %w", todo.Title, err))
+	}
+
+	for _, label := range todo.Labels {
+		_, err = txn.ExecContext(ctx, `INSERT INTO todo_label (todo_id, label_id) VALUES ($1, $2)`, todo.id, label.ID)
+		if err != nil {
+			return rollbackOnError(txn, fmt.Errorf("error restoring label '%s' to todo '%s': %w", label.Name, todo.Title, err))
+		}
+	}
+
+	if err = insertChecklistItems(ctx, txn, todo, todo.Checklist); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = insertDependencies(ctx, txn, todo); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = restoreEvents(ctx, txn, todo.history); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = persistOrder(ctx, txn, orders); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	event := &TodoEvent{Type: EventRestored, NewStatus: status, NewRank: rank}
+	if err = d.recordChange(ctx, txn, todo, event, nil); err != nil {
+		return rollbackOnError(txn, err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	todo.UpdatedDatetime = &event.Datetime
+
+	applyOrder(orders)
+
+	d.todos = append(d.todos, todo)
+
+	for _, dependent := range todo.dependents {
+		dependent.BlockedBy = append(dependent.BlockedBy, todo)
+	}
+
+	todo.dependents = nil
+	todo.history = nil
+
+	return nil
+}
+
+// labelIndex returns the position of the Label in the list, or -1 if it isn't there.
+func labelIndex(labels []*Label, label *Label) int {
+	for i, l := range labels {
+		if l.ID == label.ID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// deleteLabel deletes a Label and removes it from every Todo that has it, recording the removal in each Todo's
+// history. It returns the position of the Label in the labels of each affected Todo so that it can be restored with
+// restoreLabel.
+func (d *Database) deleteLabel(ctx context.Context, label *Label) (map[*Todo]int, error) {
+	positions := map[*Todo]int{}
+
+	for _, todo := range d.todos {
+		if idx := labelIndex(todo.Labels, label); idx >= 0 {
+			positions[todo] = idx
+		}
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM todo_label WHERE label_id=$1`, label.ID); err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error removing label '%s' from todos: %w", label.Name, err))
+	}
+
+	if _, err = txn.ExecContext(ctx, `DELETE FROM label WHERE id=$1`, label.ID); err != nil {
+		return nil, rollbackOnError(txn, fmt.Errorf("error deleting label '%s': %w", label.Name, err))
+	}
+
+	events := map[*Todo]*TodoEvent{}
+
+	for todo := range positions {
+		events[todo] = &TodoEvent{Type: EventLabelRemoved}
+		if err = d.recordChange(ctx, txn, todo, events[todo], label); err != nil {
+			return nil, rollbackOnError(txn, err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing changes: %w", err)
+	}
+
+	for todo, idx := range positions {
+		todo.Labels = append(todo.Labels[:idx], todo.Labels[idx+1:]...)
+		todo.UpdatedDatetime = &events[todo].Datetime
+	}
+
+	if idx := labelIndex(d.labels, label); idx >= 0 {
+		d.labels = append(d.labels[:idx], d.labels[idx+1:]...)
+	}
+
+	return positions, nil
+}
+
+// restoreLabel reinserts a Label removed by deleteLabel with its original id at the given position in the list of
+// labels, and adds it back to each Todo at the given position.
+func (d *Database) restoreLabel(ctx context.Context, label *Label, idx int, positions map[*Todo]int) error {
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error opening transaction: %w", err)
+	}
+
+	_, err = txn.ExecContext(ctx,
+		`INSERT INTO label (id, name, color) VALUES ($1, $2, $3)`,
+		label.ID, label.Name, label.Color,
+	)
+	if err != nil {
+		return rollbackOnError(txn, fmt.Errorf("error restoring label '%s': %w", label.Name, err))
+	}
+
+	events := map[*Todo]*TodoEvent{}
+
+	for todo := range positions {
+		_, err = txn.ExecContext(ctx, `INSERT INTO todo_label (todo_id, label_id) VALUES ($1, $2)`, todo.id, label.ID)
+		if err != nil {
+			return rollbackOnError(txn, fmt.Errorf("error restoring label '%s' to todo '%s': %w", label.Name, todo.Title, err))
+		}
+
+		events[todo] = &TodoEvent{Type: EventLabelAdded}
+		if err = d.recordChange(ctx, txn, todo, events[todo], label); err != nil {
+			return rollbackOnError(txn, err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	for todo, pos := range positions {
+		labels := make([]*Label, 0, len(todo.Labels)+1)
+		labels = append(labels, todo.Labels[:pos]...)
+		labels = append(labels, label)
+		todo.Labels = append(labels, todo.Labels[pos:]...)
+		todo.UpdatedDatetime = &events[todo].Datetime
+	}
+
+	if idx < 0 || idx > len(d.labels) {
+		idx = len(d.labels)
+	}
+
+	labels := make([]*Label, 0, len(d.labels)+1)
+	labels = append(labels, d.labels[:idx]...)
+	labels = append(labels, label)
+	d.labels = append(labels, d.labels[idx:]...)
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertTitles(assert *assert.Assertions, status *db.Status, titles ...string) {
+	assert.Equal(len(titles), len(status.Todos), status.Name)
+
+	for idx, todo := range status.Todos {
+		if idx < len(titles) {
+			assert.Equal(titles[idx], todo.Title, status.Name)
+		}
+
+		assert.Equal(idx, todo.Rank, todo.Title)
+		assert.Equal(status, todo.Status, todo.Title)
+	}
+}
+
+func TestUndoNothing(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	assert.ErrorIs(database.Undo(ctx), db.ErrNothingToUndo)
+	assert.ErrorIs(database.Redo(ctx), db.ErrNothingToRedo)
+
+	addTodo(assert, database, "todo 1", "")
+
+	assert.ErrorIs(database.Redo(ctx), db.ErrNothingToRedo)
+	assert.Nil(database.Undo(ctx))
+	assert.ErrorIs(database.Undo(ctx), db.ErrNothingToUndo)
+
+	// a new operation discards anything that could have been redone
+	addTodo(assert, database, "todo 2", "")
+	assert.ErrorIs(database.Redo(ctx), db.ErrNothingToRedo)
+}
+
+func TestUndoStatusChange(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_undo*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	open := database.Snapshot().Statuses[db.StatusOpen]
+	closed := database.Snapshot().Statuses[db.StatusClosed]
+
+	err = database.ChangeStatus(ctx, todo2, open, closed)
+	assert.Nil(err)
+
+	// moving from closed back to open isn't normally allowed, but undo restores the original position
+	err = database.Undo(ctx)
+	assert.Nil(err)
+
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 2", "todo 3")
+	assertTitles(assert, storeStatus(assert, database, db.StatusClosed))
+
+	err = database.Redo(ctx)
+	assert.Nil(err)
+
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 3")
+	assertTitles(assert, storeStatus(assert, database, db.StatusClosed), "todo 2")
+
+	err = database.Undo(ctx)
+	assert.Nil(err)
+
+	database2, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database2.Close()
+
+	assertTitles(assert, database2.Snapshot().Statuses[db.StatusOpen], "todo 1", "todo 2", "todo 3")
+	assertTitles(assert, database2.Snapshot().Statuses[db.StatusClosed])
+
+	events, err := database.History(ctx, todo2)
+	assert.Nil(err)
+	assert.Equal(db.EventStatusChanged, events[len(events)-1].Type)
+	assert.Equal(closed.Name, events[len(events)-1].OldStatus.Name)
+	assert.Equal(open.Name, events[len(events)-1].NewStatus.Name)
+}
+
+func TestUndoRerank(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	addTodo(assert, database, "todo 2", "")
+	todo3 := addTodo(assert, database, "todo 3", "")
+	todo4 := addTodo(assert, database, "todo 4", "")
+
+	assert.Nil(database.MoveToTop(ctx, todo3))
+	assert.Nil(database.MoveDown(ctx, todo1))
+	assert.Nil(database.MoveToBottom(ctx, todo3))
+	assert.Nil(database.MoveUp(ctx, todo4))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 2", "todo 4", "todo 1", "todo 3")
+
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 2", "todo 1", "todo 4", "todo 3")
+
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 3", "todo 2", "todo 1", "todo 4")
+
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 3", "todo 1", "todo 2", "todo 4")
+
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 2", "todo 3", "todo 4")
+
+	assert.Nil(database.Redo(ctx))
+	assert.Nil(database.Redo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 3", "todo 2", "todo 1", "todo 4")
+}
+
+func TestUndoEdit(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addTodo(assert, database, "todo 1", "desc 1")
+
+	assert.Nil(database.UpdateTodo(ctx, todo, "todo one", "desc one"))
+
+	assert.Nil(database.Undo(ctx))
+
+	current := storeTodo(assert, database, todo)
+	assert.Equal("todo 1", current.Title)
+	assert.Equal("desc 1", current.Description)
+
+	assert.Nil(database.Redo(ctx))
+
+	current = storeTodo(assert, database, todo)
+	assert.Equal("todo one", current.Title)
+	assert.Equal("desc one", current.Description)
+}
+
+func TestUndoLabels(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	todo := addDefaultTodo(assert, database)
+
+	labels := database.Snapshot().Labels
+
+	for _, label := range labels[:3] {
+		assert.Nil(database.AddTodoLabel(ctx, todo, label))
+	}
+
+	assert.Nil(database.RemoveTodoLabel(ctx, todo, labels[1]))
+	assert.Equal(2, len(storeTodo(assert, database, todo).Labels))
+
+	// undoing the removal restores the label to its original position
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(labels[:3], storeTodo(assert, database, todo).Labels)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(labels[:2], storeTodo(assert, database, todo).Labels)
+
+	assert.Nil(database.Redo(ctx))
+	assert.Equal(labels[:3], storeTodo(assert, database, todo).Labels)
+
+	// the db state matches: a restored label can be removed and added again
+	assert.Nil(database.RemoveTodoLabel(ctx, todo, labels[1]))
+	assert.Nil(database.AddTodoLabel(ctx, todo, labels[1]))
+}
+
+func TestUndoCreate(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_undo*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	assert.Nil(database.AddTodoLabel(ctx, todo2, database.Snapshot().Labels[0]))
+	assert.Nil(database.MoveToTop(ctx, todo2))
+
+	assert.Equal(3, len(database.Snapshot().Todos))
+
+	// undo the move, the label, and the last creation
+	for i := 0; i < 3; i++ {
+		assert.Nil(database.Undo(ctx))
+	}
+
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 2")
+	assert.Equal(2, len(database.Snapshot().Todos))
+	assert.Equal(0, len(storeTodo(assert, database, todo2).Labels))
+
+	// undo the creation of todo 2, then redo everything
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1")
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(database.Redo(ctx))
+	}
+
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 2", "todo 1", "todo 3")
+	assert.Equal(database.Snapshot().Labels[0], storeTodo(assert, database, todo2).Labels[0])
+
+	database2, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database2.Close()
+
+	snapshot := database2.Snapshot()
+	assertTitles(assert, snapshot.Statuses[db.StatusOpen], "todo 2", "todo 1", "todo 3")
+	assert.Equal(snapshot.Labels[0].Name, snapshot.Statuses[db.StatusOpen].Todos[0].Labels[0].Name)
+}
+
+func TestUndoDelete(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_undo*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	todo1 := addTodo(assert, database, "todo 1", "")
+	todo2 := addTodo(assert, database, "todo 2", "")
+	addTodo(assert, database, "todo 3", "")
+
+	label := database.Snapshot().Labels[1]
+	assert.Nil(database.AddTodoLabel(ctx, todo1, database.Snapshot().Labels[0]))
+	assert.Nil(database.AddTodoLabel(ctx, todo1, label))
+	assert.Nil(database.AddTodoLabel(ctx, todo2, label))
+
+	assert.Nil(database.DeleteLabel(ctx, label))
+	assert.Nil(database.DeleteTodo(ctx, todo2))
+
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 3")
+
+	// restoring the todo brings back the labels it had when it was deleted
+	assert.Nil(database.Undo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 2", "todo 3")
+	assert.Equal(0, len(storeTodo(assert, database, todo2).Labels))
+
+	// restoring the label reattaches it to the todos that had it, in the original positions
+	assert.Nil(database.Undo(ctx))
+	assert.Equal(label, database.Snapshot().Labels[1])
+	assert.Equal([]*db.Label{database.Snapshot().Labels[0], label}, storeTodo(assert, database, todo1).Labels)
+	assert.Equal([]*db.Label{label}, storeTodo(assert, database, todo2).Labels)
+
+	assert.Nil(database.Redo(ctx))
+	assert.Nil(database.Redo(ctx))
+	assertTitles(assert, storeStatus(assert, database, db.StatusOpen), "todo 1", "todo 3")
+	assert.Equal([]*db.Label{database.Snapshot().Labels[0]}, storeTodo(assert, database, todo1).Labels)
+
+	assert.Nil(database.Undo(ctx))
+	assert.Nil(database.Undo(ctx))
+
+	database2, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database2.Close()
+
+	snapshot := database2.Snapshot()
+	assertTitles(assert, snapshot.Statuses[db.StatusOpen], "todo 1", "todo 2", "todo 3")
+	assert.Equal(2, len(snapshot.Statuses[db.StatusOpen].Todos[0].Labels))
+	assert.Equal(label.Name, snapshot.Statuses[db.StatusOpen].Todos[1].Labels[0].Name)
+	assert.Equal(9, len(snapshot.Labels))
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// LabelSeparator separates the names of labels in the labels column of the CSV format.
+const LabelSeparator = ";"
+
+// CSVHeader returns the names of the columns of the CSV format. Rank starts at 0 within each status and timestamps are
+// RFC 3339.
+func CSVHeader() []string {
+	return []string{"id", "status", "rank", "title", "description", "labels", "due_date", "created", "updated"}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func writeCSV(w io.Writer, statuses []*db.Status) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(CSVHeader()); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+
+	for _, status := range statuses {
+		for _, todo := range status.Todos {
+			record := []string{
+				strconv.Itoa(todo.ID()),
+				status.Name,
+				strconv.Itoa(todo.Rank),
+				todo.Title,
+				todo.Description,
+				strings.Join(labelNames(todo), LabelSeparator),
+				db.FormatDueDate(todo.DueDate),
+				formatTime(todo.CreatedDatetime),
+				formatTime(todo.UpdatedDatetime),
+			}
+
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing csv: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+
+	return nil
+}
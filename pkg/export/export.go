@@ -0,0 +1,86 @@
+// Package export writes todos as JSON, CSV or Markdown so that they can be used outside the app.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// Format identifies an export format.
+type Format string
+
+// These constants are the supported export formats.
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "md"
+)
+
+// ErrUnknownFormat is returned when an export format isn't supported.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat returns the Format with the given name, e.g. "csv".
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatJSON, FormatCSV, FormatMarkdown:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("%w '%s'; use json, csv or md", ErrUnknownFormat, name)
+}
+
+// Write writes the todos with the given statuses, or with every status if none are given, in the given format. Todos
+// are written status by status in display order, and in rank order within each status, from a Snapshot of the
+// database so that changes made while writing don't show up halfway through.
+func Write(w io.Writer, database *db.Database, format Format, statuses ...*db.Status) error {
+	snapshot := database.Snapshot()
+	statuses = selectStatuses(snapshot, statuses)
+
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, snapshot, statuses)
+	case FormatCSV:
+		return writeCSV(w, statuses)
+	case FormatMarkdown:
+		return writeMarkdown(w, statuses)
+	}
+
+	return fmt.Errorf("%w '%s'", ErrUnknownFormat, format)
+}
+
+// selectStatuses returns the snapshot's copies of the chosen statuses in display order, or every status if none were
+// chosen.
+func selectStatuses(snapshot *db.Snapshot, chosen []*db.Status) []*db.Status {
+	statuses := []*db.Status{}
+
+	for _, status := range snapshot.OrderedStatuses() {
+		if len(chosen) == 0 || containsStatus(chosen, status) {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses
+}
+
+func containsStatus(statuses []*db.Status, status *db.Status) bool {
+	for _, s := range statuses {
+		if s.Name == status.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func labelNames(todo *db.Todo) []string {
+	names := make([]string, 0, len(todo.Labels))
+	for _, label := range todo.Labels {
+		names = append(names, label.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,157 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+	"github.com/stretchr/testify/assert"
+)
+
+// getDB returns a database with two open todos, the first labelled and due, and a closed one.
+func getDB(assert *assert.Assertions) *db.Database {
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_export*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	report, err := database.NewTodo(ctx, "write report", "for the board")
+	assert.Nil(err)
+
+	_, err = database.NewTodo(ctx, "book travel", "")
+	assert.Nil(err)
+
+	review, err := database.NewTodo(ctx, "review, then merge", "")
+	assert.Nil(err)
+
+	label, err := database.NewLabel(ctx, "work")
+	assert.Nil(err)
+	assert.Nil(database.AddTodoLabel(ctx, report, label))
+
+	due, err := db.ParseDueDate("2022-01-14")
+	assert.Nil(err)
+	assert.Nil(database.SetDueDate(ctx, report, due))
+
+	_, err = database.AddChecklistItem(ctx, report, "draft")
+	assert.Nil(err)
+
+	assert.Nil(database.ChangeStatus(ctx, review, review.Status, database.Snapshot().Statuses[db.StatusClosed]))
+
+	return database
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	format, err := export.ParseFormat("MD")
+	assert.Nil(err)
+	assert.Equal(export.FormatMarkdown, format)
+
+	_, err = export.ParseFormat("xml")
+	assert.ErrorIs(err, export.ErrUnknownFormat)
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	var buf bytes.Buffer
+
+	assert.Nil(export.Write(&buf, database, export.FormatJSON))
+
+	var doc export.Document
+
+	assert.Nil(json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(export.SchemaVersion, doc.SchemaVersion)
+	assert.Equal(len(database.Snapshot().Labels), len(doc.Labels))
+
+	work, err := database.LabelByName(context.Background(), "work")
+	assert.Nil(err)
+	assert.Contains(doc.Labels, export.Label{Name: "work", Color: work.Color})
+
+	// statuses in display order: open, then closed
+	assert.Equal(3, len(doc.Todos))
+	assert.Equal("review, then merge", doc.Todos[2].Title)
+	assert.Equal(db.StatusClosed, doc.Todos[2].Status)
+
+	report := doc.Todos[0]
+	assert.Equal("write report", report.Title)
+	assert.Equal("for the board", report.Description)
+	assert.Equal(db.StatusOpen, report.Status)
+	assert.Equal(0, report.Rank)
+	assert.Equal([]string{"work"}, report.Labels)
+	assert.Equal("2022-01-14", report.DueDate)
+	assert.Equal([]export.ChecklistItem{{Text: "draft"}}, report.Checklist)
+	assert.NotNil(report.Created)
+	assert.NotNil(report.Updated)
+
+	buf.Reset()
+
+	assert.Nil(export.Write(&buf, database, export.FormatJSON, database.Snapshot().Statuses[db.StatusClosed]))
+	assert.Nil(json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(1, len(doc.Todos))
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	var buf bytes.Buffer
+
+	assert.Nil(export.Write(&buf, database, export.FormatCSV, database.Snapshot().Statuses[db.StatusOpen]))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(err)
+	assert.Equal(3, len(records))
+	assert.Equal(export.CSVHeader(), records[0])
+	assert.Equal([]string{"1", "open", "0", "write report", "for the board", "work", "2022-01-14"}, records[1][:7])
+	assert.Equal([]string{"2", "open", "1", "book travel", "", "", ""}, records[2][:7])
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	_, err := database.NewTodo(context.Background(), "fix *all* the [bugs]", "first this\r\n\n- then that")
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(export.Write(&buf, database, export.FormatMarkdown))
+
+	expected := "## Open\n\n" +
+		"1. write report `work` (0/1 done) - due 2022-01-14\n" +
+		"   for the board\n" +
+		"2. book travel\n" +
+		"3. fix \\*all\\* the \\[bugs\\]\n" +
+		"   first this\n" +
+		"\n" +
+		"   - then that\n" +
+		"\n" +
+		"## Closed\n\n" +
+		"1. review, then merge\n"
+
+	assert.Equal(expected, buf.String())
+}
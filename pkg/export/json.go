@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// SchemaVersion is the version of the JSON Document. It increases whenever a field is removed or changes meaning, so
+// that readers can tell which fields to expect; new fields may be added without changing it.
+const SchemaVersion = 1
+
+// Document is the top-level object written by the JSON format.
+type Document struct {
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	// Labels lists every label, including labels that no exported todo carries, so that colors survive a round trip.
+	Labels []Label `json:"labels"`
+	Todos  []Todo  `json:"todos"`
+}
+
+// Label is a label in a Document.
+type Label struct {
+	Name string `json:"name"`
+	// Color is a hex color like "#FF0000".
+	Color string `json:"color"`
+}
+
+// Todo is a todo in a Document.
+type Todo struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"`
+	// Rank is the position of the todo within its status, starting at 0.
+	Rank int `json:"rank"`
+	// Labels are the names of the todo's labels.
+	Labels []string `json:"labels"`
+	// DueDate looks like "2021-12-31".
+	DueDate string `json:"due_date,omitempty"`
+	// Recurrence is a rule like "weekly mon,thu"; see db.ParseRecurrence.
+	Recurrence   string          `json:"recurrence,omitempty"`
+	SnoozedUntil *time.Time      `json:"snoozed_until,omitempty"`
+	Checklist    []ChecklistItem `json:"checklist,omitempty"`
+	// BlockedBy lists the ids of the todos that must be done before this one.
+	BlockedBy []int      `json:"blocked_by,omitempty"`
+	Created   *time.Time `json:"created"`
+	Updated   *time.Time `json:"updated"`
+}
+
+// ChecklistItem is a step in the checklist of a Todo.
+type ChecklistItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// NewDocument returns the Document describing the todos with the given statuses.
+func NewDocument(snapshot *db.Snapshot, statuses []*db.Status, exportedAt time.Time) *Document {
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    exportedAt,
+		Labels:        make([]Label, 0, len(snapshot.Labels)),
+		Todos:         []Todo{},
+	}
+
+	for _, label := range snapshot.Labels {
+		doc.Labels = append(doc.Labels, Label{Name: label.Name, Color: label.Color})
+	}
+
+	for _, status := range statuses {
+		for _, todo := range status.Todos {
+			doc.Todos = append(doc.Todos, NewTodo(todo))
+		}
+	}
+
+	return doc
+}
+
+// NewTodo returns the Todo describing a db.Todo.
+func NewTodo(todo *db.Todo) Todo {
+	t := Todo{
+		ID:           todo.ID(),
+		Title:        todo.Title,
+		Description:  todo.Description,
+		Status:       todo.Status.Name,
+		Rank:         todo.Rank,
+		Labels:       labelNames(todo),
+		DueDate:      db.FormatDueDate(todo.DueDate),
+		Recurrence:   db.FormatRecurrence(todo.Recurrence),
+		SnoozedUntil: todo.SnoozedUntil,
+		Created:      todo.CreatedDatetime,
+		Updated:      todo.UpdatedDatetime,
+	}
+
+	for _, item := range todo.Checklist {
+		t.Checklist = append(t.Checklist, ChecklistItem{Text: item.Text, Done: item.Done})
+	}
+
+	for _, blocker := range todo.BlockedBy {
+		t.BlockedBy = append(t.BlockedBy, blocker.ID())
+	}
+
+	return t
+}
+
+func writeJSON(w io.Writer, snapshot *db.Snapshot, statuses []*db.Status) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(NewDocument(snapshot, statuses, time.Now())); err != nil {
+		return fmt.Errorf("error writing json: %w", err)
+	}
+
+	return nil
+}
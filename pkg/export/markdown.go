@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// statusHeading returns the heading for a status in the Markdown format, e.g. "On hold" for on_hold.
+func statusHeading(name string) string {
+	heading := strings.ReplaceAll(name, "_", " ")
+	if heading == "" {
+		return heading
+	}
+
+	return strings.ToUpper(heading[:1]) + heading[1:]
+}
+
+// escapeMarkdown escapes the characters that would make Markdown format plain text, like * and _ for emphasis.
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "~", `\~`, "|", `\|`,
+	).Replace(text)
+}
+
+// markdownItem returns the list item for a Todo: its title followed by its labels as code spans, which most Markdown
+// renderers show as badges, how far along its checklist is and when it's due. The title is escaped so that it shows
+// as typed. The description goes on the next lines, indented to stay within the item; it's left as it is, so it may
+// use Markdown.
+func markdownItem(todo *db.Todo) string {
+	item := fmt.Sprintf("%d. %s", todo.Rank+1, escapeMarkdown(todo.Title))
+
+	for _, name := range labelNames(todo) {
+		item += fmt.Sprintf(" `%s`", name)
+	}
+
+	if done, total := todo.ChecklistProgress(); total > 0 {
+		item += fmt.Sprintf(" (%d/%d done)", done, total)
+	}
+
+	if todo.DueDate != nil {
+		item += fmt.Sprintf(" - due %s", db.FormatDueDate(todo.DueDate))
+	}
+
+	if todo.Description == "" {
+		return item
+	}
+
+	for _, line := range strings.Split(todo.Description, "\n") {
+		item += "\n"
+
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			item += "   " + line
+		}
+	}
+
+	return item
+}
+
+// writeMarkdown writes a section per status with a numbered list of its todos; empty statuses are left out.
+func writeMarkdown(w io.Writer, statuses []*db.Status) error {
+	writer := bufio.NewWriter(w)
+	first := true
+
+	for _, status := range statuses {
+		if len(status.Todos) == 0 {
+			continue
+		}
+
+		if !first {
+			fmt.Fprintln(writer)
+		}
+
+		first = false
+
+		fmt.Fprintf(writer, "## %s\n\n", statusHeading(status.Name))
+
+		for _, todo := range status.Todos {
+			fmt.Fprintln(writer, markdownItem(todo))
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing markdown: %w", err)
+	}
+
+	return nil
+}
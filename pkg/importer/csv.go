@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// ErrInvalidMapping is returned when a column mapping names an unknown field or a column that isn't in the file.
+var ErrInvalidMapping = errors.New("invalid column mapping")
+
+// Mapping maps the fields of an Item to the names of the CSV columns they are read from. The fields are title,
+// description, status, rank, labels and due_date; only title is required.
+type Mapping map[string]string
+
+// DefaultMapping returns the Mapping for files written by the CSV export format, where every column is named after
+// its field.
+func DefaultMapping() Mapping {
+	return Mapping{
+		"title":       "title",
+		"description": "description",
+		"status":      "status",
+		"rank":        "rank",
+		"labels":      "labels",
+		"due_date":    "due_date",
+	}
+}
+
+// ParseMapping returns the DefaultMapping overridden by a list like "title=Task,labels=Tags".
+func ParseMapping(text string) (Mapping, error) {
+	mapping := DefaultMapping()
+
+	for _, pair := range strings.Split(text, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		field := strings.TrimSpace(parts[0])
+
+		if _, ok := mapping[field]; !ok || len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("%w: '%s' should look like field=column, where field is one of "+
+				"title, description, status, rank, labels or due_date", ErrInvalidMapping, pair)
+		}
+
+		mapping[field] = strings.TrimSpace(parts[1])
+	}
+
+	return mapping, nil
+}
+
+// ReadCSV reads a CSV file with a header row, taking each field of the items from the column given by the Mapping.
+// Labels are separated by semicolons or commas, and rows without a rank keep the order of the file.
+func ReadCSV(r io.Reader, mapping Mapping) (*Source, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	columns, err := mapColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &Source{Items: []*Item{}}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return source, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading csv: %w", err)
+		}
+
+		item, err := csvItem(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv line %d: %w", line, err)
+		}
+
+		source.Items = append(source.Items, item)
+	}
+}
+
+// mapColumns returns the index of the column for each field in the header. Columns from the DefaultMapping may be
+// missing, except for title, but columns that were mapped explicitly must be there.
+func mapColumns(header []string, mapping Mapping) (map[string]int, error) {
+	defaults := DefaultMapping()
+	columns := map[string]int{}
+
+	for field, name := range mapping {
+		idx := indexOf(header, name)
+		if idx >= 0 {
+			columns[field] = idx
+		} else if field == "title" || name != defaults[field] {
+			return nil, fmt.Errorf("%w: there is no column named '%s' for %s", ErrInvalidMapping, name, field)
+		}
+	}
+
+	return columns, nil
+}
+
+func indexOf(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func csvItem(record []string, columns map[string]int) (*Item, error) {
+	value := func(field string) string {
+		idx, ok := columns[field]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	item := &Item{
+		Title:       value("title"),
+		Description: value("description"),
+		Status:      value("status"),
+		Labels:      splitLabels(value("labels")),
+	}
+
+	if item.Title == "" {
+		return nil, db.ErrEmptyTitle
+	}
+
+	if rank := value("rank"); rank != "" {
+		var err error
+		if item.Rank, err = strconv.Atoi(rank); err != nil {
+			return nil, fmt.Errorf("invalid rank '%s': %w", rank, err)
+		}
+	}
+
+	due, err := db.ParseDueDate(value("due_date"))
+	if err != nil {
+		return nil, err
+	}
+
+	item.DueDate = due
+
+	return item, nil
+}
+
+// splitLabels splits a list of labels separated by semicolons or commas, leaving out duplicates.
+func splitLabels(text string) []string {
+	labels := []string{}
+
+	for _, name := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == ',' }) {
+		if name = strings.TrimSpace(name); name != "" && !containsName(labels, name) {
+			labels = append(labels, name)
+		}
+	}
+
+	return labels
+}
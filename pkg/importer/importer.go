@@ -0,0 +1,417 @@
+// Package importer reads todos from our own JSON export, CSV files and todo.txt files and adds them to a database.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+)
+
+// Format identifies an import format.
+type Format string
+
+// These constants are the supported import formats.
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatTodoTxt Format = "todotxt"
+)
+
+// ErrUnknownFormat is returned when an import format isn't supported.
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// ParseFormat returns the Format with the given name, e.g. "csv".
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatJSON, FormatCSV, FormatTodoTxt:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("%w '%s'; use json, csv or todotxt", ErrUnknownFormat, name)
+}
+
+// FormatForFile guesses the Format of a file from its extension: .json, .csv or .txt for todo.txt.
+func FormatForFile(filename string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".txt":
+		return FormatTodoTxt, nil
+	}
+
+	return "", fmt.Errorf("%w: can't tell the format of '%s'; use json, csv or todotxt", ErrUnknownFormat, filename)
+}
+
+// Item is a todo read from a file, before it is added to the database.
+type Item struct {
+	Title       string
+	Description string
+	// Status is the name of the status the todo goes to; empty means open.
+	Status string
+	// Rank orders the items within a status; items with the same rank keep the order they were read in.
+	Rank         int
+	Labels       []string
+	DueDate      *time.Time
+	Recurrence   *db.Recurrence
+	SnoozedUntil *time.Time
+	Checklist    []export.ChecklistItem
+	// ID identifies the item in BlockedBy, which lists the IDs of the items that block this one. Only the JSON format
+	// has IDs; they are unrelated to the ids of the todos that Import creates.
+	ID        int
+	BlockedBy []int
+}
+
+// Source is everything read from a file.
+type Source struct {
+	Items []*Item
+	// LabelColors maps the names of labels to their colors, for labels that Import creates.
+	LabelColors map[string]string
+}
+
+// Planned is a todo that Import adds, or would add in a dry run.
+type Planned struct {
+	Item   *Item
+	Status *db.Status
+	// Todo is a copy of the new Todo as Import left it, or nil in a dry run.
+	Todo *db.Todo
+	// blockers are the IDs from Item.BlockedBy that checkDependencies kept.
+	blockers []int
+}
+
+// Report describes what Import changed, or would change in a dry run.
+type Report struct {
+	DryRun bool
+	// Labels are the names of the labels that didn't exist yet.
+	Labels []string
+	Todos  []*Planned
+	// Warnings describe todos that didn't go where the file said, and anything else that was left out.
+	Warnings []string
+}
+
+func (r *Report) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Import adds the todos from the Source to the database, creating any labels that don't exist yet. Todos are added at
+// the end of their status in rank order. Todos that would take a status over its limit, e.g. MaxClosedTodos for the
+// closed list, go to the end of the open list instead, with a warning. With dryRun, the Report describes what would
+// change but nothing is changed.
+//
+// Everything that could stop the import is checked before anything is added, so that an invalid file changes
+// nothing; only an error from the database itself can leave a file partly imported.
+func Import(ctx context.Context, database *db.Database, source *Source, dryRun bool) (*Report, error) {
+	report, err := plan(ctx, database, source)
+	if err != nil {
+		return nil, err
+	}
+
+	report.DryRun = dryRun
+	if dryRun {
+		return report, nil
+	}
+
+	if err = createLabels(ctx, database, source, report); err != nil {
+		return report, err
+	}
+
+	for _, planned := range report.Todos {
+		if planned.Todo, err = addTodo(ctx, database, planned, report); err != nil {
+			return report, err
+		}
+	}
+
+	err = addDependencies(ctx, database, report)
+	refreshTodos(ctx, database, report)
+
+	return report, err
+}
+
+// refreshTodos replaces the copies that addTodo returned with copies of the todos as they are now, e.g. blocked by
+// each other.
+func refreshTodos(ctx context.Context, database *db.Database, report *Report) {
+	snapshot := database.Snapshot()
+
+	for _, planned := range report.Todos {
+		if planned.Todo == nil {
+			continue
+		}
+
+		if todo, err := snapshot.TodoByID(ctx, planned.Todo.ID()); err == nil {
+			planned.Todo = todo
+		}
+	}
+}
+
+// plan decides which status each item goes to and which labels are missing, and checks that every item can be
+// added, without changing anything.
+func plan(ctx context.Context, database *db.Database, source *Source) (*Report, error) {
+	report := &Report{Labels: []string{}, Todos: []*Planned{}, Warnings: []string{}}
+	snapshot := database.Snapshot()
+	open := snapshot.Statuses[db.StatusOpen]
+	counts := map[*db.Status]int{}
+
+	for _, status := range snapshot.Statuses {
+		counts[status] = len(status.Todos)
+	}
+
+	items := make([]*Item, len(source.Items))
+	copy(items, source.Items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Rank < items[j].Rank
+	})
+
+	for _, item := range items {
+		if err := checkItem(item); err != nil {
+			return nil, err
+		}
+
+		status := findStatus(snapshot, item.Status)
+		if status == nil {
+			report.warn("there is no status named '%s'; '%s' goes to open instead", item.Status, item.Title)
+			status = open
+		}
+
+		if limit := database.StatusLimit(status); limit > 0 && counts[status] >= limit && status != open {
+			report.warn("%s is full (the limit is %d); '%s' goes to open instead", status.Name, limit, item.Title)
+			status = open
+		}
+
+		if limit := database.StatusLimit(open); status == open && limit > 0 && counts[open] >= limit {
+			return nil, fmt.Errorf("%w: can't add '%s' to open because the limit is %d",
+				db.ErrWIPLimitReached, item.Title, limit)
+		}
+
+		counts[status]++
+
+		report.Todos = append(report.Todos, &Planned{Item: item, Status: status})
+
+		for _, name := range item.Labels {
+			if _, err := snapshot.LabelByName(ctx, name); err == nil || containsName(report.Labels, name) {
+				continue
+			}
+
+			report.Labels = append(report.Labels, name)
+		}
+	}
+
+	if err := checkDependencies(report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// checkItem returns the error that adding the item would fail with, if any.
+func checkItem(item *Item) error {
+	if item.Title == "" {
+		return db.ErrEmptyTitle
+	}
+
+	for _, step := range item.Checklist {
+		if step.Text == "" {
+			return fmt.Errorf("%w: '%s' has an empty checklist item", db.ErrEmptyChecklistItem, item.Title)
+		}
+	}
+
+	return nil
+}
+
+// checkDependencies decides which blockers addDependencies adds and returns an error if the items block each other in
+// a cycle. It warns about the blockers that are skipped: those that aren't imported, and those that aren't finished
+// when the todo they block is done.
+func checkDependencies(report *Report) error {
+	blockers := map[int][]int{}
+	byID := map[int]*Planned{}
+
+	for _, planned := range report.Todos {
+		if planned.Item.ID != 0 {
+			blockers[planned.Item.ID] = planned.Item.BlockedBy
+			byID[planned.Item.ID] = planned
+		}
+	}
+
+	for _, planned := range report.Todos {
+		item := planned.Item
+
+		for _, id := range item.BlockedBy {
+			if _, ok := blockers[id]; !ok {
+				report.warn("'%s' was blocked by #%d, which wasn't imported", item.Title, id)
+
+				continue
+			}
+
+			if item.ID != 0 && waitsOn(blockers, id, item.ID) {
+				return fmt.Errorf("%w: '%s' is blocked by #%d, which waits on '%s'",
+					db.ErrDependencyCycle, item.Title, id, item.Title)
+			}
+
+			if blocker := byID[id]; planned.Status.Name == db.StatusDone && !db.Finished(blocker.Status) {
+				report.warn("'%s' is done, so it no longer waits on '%s', which is %s",
+					item.Title, blocker.Item.Title, blocker.Status.Name)
+
+				continue
+			}
+
+			planned.blockers = append(planned.blockers, id)
+		}
+	}
+
+	return nil
+}
+
+// waitsOn returns whether the item with the given id is, or is blocked directly or indirectly by, the target item.
+func waitsOn(blockers map[int][]int, id, target int) bool {
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current == target {
+			return true
+		}
+
+		for _, blocker := range blockers[current] {
+			if !seen[blocker] {
+				seen[blocker] = true
+
+				queue = append(queue, blocker)
+			}
+		}
+	}
+
+	return false
+}
+
+// findStatus returns the status with the given name, also accepting names like "On hold" for on_hold, or nil if
+// there is no such status. An empty name means open.
+func findStatus(snapshot *db.Snapshot, name string) *db.Status {
+	if name == "" {
+		return snapshot.Statuses[db.StatusOpen]
+	}
+
+	if status, ok := snapshot.Statuses[name]; ok {
+		return status
+	}
+
+	return snapshot.Statuses[strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")]
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func createLabels(ctx context.Context, database *db.Database, source *Source, report *Report) error {
+	for _, name := range report.Labels {
+		label, err := database.NewLabel(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		color, ok := source.LabelColors[name]
+		if !ok {
+			continue
+		}
+
+		if err = database.SetLabelColor(ctx, label, color); errors.Is(err, db.ErrInvalidColor) {
+			report.warn("label '%s' keeps its default color: %s", name, err)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addTodo creates the Todo for a planned item along with its labels, due date, recurrence, checklist and snooze.
+func addTodo(ctx context.Context, database *db.Database, planned *Planned, report *Report) (*db.Todo, error) {
+	item := planned.Item
+
+	todo, err := database.NewTodoInStatus(ctx, item.Title, item.Description, planned.Status)
+	if err != nil {
+		return nil, fmt.Errorf("error adding todo '%s': %w", item.Title, err)
+	}
+
+	for _, name := range item.Labels {
+		label, err := database.LabelByName(ctx, name)
+		if err != nil {
+			return todo, err
+		}
+
+		if err = database.AddTodoLabel(ctx, todo, label); err != nil {
+			return todo, fmt.Errorf("error adding label '%s' to todo '%s': %w", name, item.Title, err)
+		}
+	}
+
+	if err = database.SetDueDate(ctx, todo, item.DueDate); err != nil {
+		return todo, err
+	}
+
+	if err = database.SetRecurrence(ctx, todo, item.Recurrence); err != nil {
+		return todo, err
+	}
+
+	for _, step := range item.Checklist {
+		checklistItem, err := database.AddChecklistItem(ctx, todo, step.Text)
+		if err != nil {
+			return todo, err
+		}
+
+		if step.Done {
+			if err = database.CheckChecklistItem(ctx, todo, checklistItem); err != nil {
+				return todo, err
+			}
+		}
+	}
+
+	if item.SnoozedUntil != nil && planned.Status.Name == db.StatusOnHold {
+		if err = database.Snooze(ctx, todo, *item.SnoozedUntil); errors.Is(err, db.ErrSnoozeInPast) {
+			report.warn("'%s' stays on hold without waking up: it was snoozed until %s",
+				item.Title, item.SnoozedUntil.Format(time.RFC3339))
+		} else if err != nil {
+			return todo, err
+		}
+	}
+
+	return todo, nil
+}
+
+// addDependencies blocks the new todos by each other as the items said, except for the blockers that
+// checkDependencies skipped with a warning.
+func addDependencies(ctx context.Context, database *db.Database, report *Report) error {
+	byID := map[int]*db.Todo{}
+
+	for _, planned := range report.Todos {
+		if planned.Item.ID != 0 {
+			byID[planned.Item.ID] = planned.Todo
+		}
+	}
+
+	for _, planned := range report.Todos {
+		for _, id := range planned.blockers {
+			blocker := byID[id]
+
+			if err := database.AddDependency(ctx, planned.Todo, blocker); err != nil {
+				return fmt.Errorf("error blocking '%s' by '%s': %w", planned.Item.Title, blocker.Title, err)
+			}
+		}
+	}
+
+	return nil
+}
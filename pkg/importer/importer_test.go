@@ -0,0 +1,307 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+	"github.com/matt-steen/todo-tracker/pkg/importer"
+	"github.com/stretchr/testify/assert"
+)
+
+func getDB(assert *assert.Assertions) *db.Database {
+	tempFile, err := os.CreateTemp("/tmp", "test_import*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(context.Background(), tempFile.Name())
+	assert.Nil(err)
+
+	return database
+}
+
+func titles(todos []*db.Todo) []string {
+	names := make([]string, 0, len(todos))
+	for _, todo := range todos {
+		names = append(names, todo.Title)
+	}
+
+	return names
+}
+
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	format, err := importer.ParseFormat("TodoTxt")
+	assert.Nil(err)
+	assert.Equal(importer.FormatTodoTxt, format)
+
+	_, err = importer.ParseFormat("md")
+	assert.ErrorIs(err, importer.ErrUnknownFormat)
+
+	format, err = importer.FormatForFile("/home/me/todo.txt")
+	assert.Nil(err)
+	assert.Equal(importer.FormatTodoTxt, format)
+
+	_, err = importer.FormatForFile("todos")
+	assert.ErrorIs(err, importer.ErrUnknownFormat)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	from := getDB(assert)
+	defer from.Close()
+
+	report, err := from.NewTodo(ctx, "write report", "for the board")
+	assert.Nil(err)
+
+	slides, err := from.NewTodo(ctx, "make slides", "")
+	assert.Nil(err)
+	assert.Nil(from.AddDependency(ctx, report, slides))
+
+	label, err := from.NewLabel(ctx, "board")
+	assert.Nil(err)
+	assert.Nil(from.SetLabelColor(ctx, label, "#123456"))
+	assert.Nil(from.AddTodoLabel(ctx, report, label))
+
+	due, err := db.ParseDueDate("2022-01-14")
+	assert.Nil(err)
+	assert.Nil(from.SetDueDate(ctx, report, due))
+
+	item, err := from.AddChecklistItem(ctx, report, "outline")
+	assert.Nil(err)
+	assert.Nil(from.CheckChecklistItem(ctx, report, item))
+
+	assert.Nil(from.ChangeStatus(ctx, slides, slides.Status, from.Snapshot().Statuses[db.StatusClosed]))
+
+	var buf bytes.Buffer
+
+	assert.Nil(export.Write(&buf, from, export.FormatJSON))
+
+	source, err := importer.ReadJSON(&buf)
+	assert.Nil(err)
+
+	to := getDB(assert)
+	defer to.Close()
+
+	result, err := importer.Import(ctx, to, source, false)
+	assert.Nil(err)
+	assert.Equal([]string{"board"}, result.Labels)
+	assert.Empty(result.Warnings)
+
+	assert.Equal([]string{"make slides"}, titles(to.Snapshot().Statuses[db.StatusClosed].Todos))
+	assert.Equal([]string{"write report"}, titles(to.Snapshot().Statuses[db.StatusOpen].Todos))
+
+	imported := to.Snapshot().Statuses[db.StatusOpen].Todos[0]
+	assert.Equal("for the board", imported.Description)
+	assert.Equal("board", imported.Labels[0].Name)
+	assert.Equal("#123456", imported.Labels[0].Color)
+	assert.Equal("2022-01-14", db.FormatDueDate(imported.DueDate))
+	assert.Equal("outline", imported.Checklist[0].Text)
+	assert.True(imported.Checklist[0].Done)
+	assert.Equal([]string{"make slides"}, titles(imported.BlockedBy))
+}
+
+func TestReadJSONRejectsNewerVersions(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	doc, err := json.Marshal(export.Document{SchemaVersion: export.SchemaVersion + 1})
+	assert.Nil(err)
+
+	_, err = importer.ReadJSON(bytes.NewReader(doc))
+	assert.ErrorIs(err, importer.ErrUnsupportedVersion)
+}
+
+func TestReadCSV(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	file := "Task,Notes,Tags,Due\n" +
+		"book travel,,\"travel, admin\",2022-02-01\n" +
+		"renew passport,before March,admin,\n"
+
+	_, err := importer.ReadCSV(strings.NewReader(file), importer.DefaultMapping())
+	assert.ErrorIs(err, importer.ErrInvalidMapping)
+
+	_, err = importer.ParseMapping("owner=Who")
+	assert.ErrorIs(err, importer.ErrInvalidMapping)
+
+	mapping, err := importer.ParseMapping("title=Task, description=Notes, labels=Tags, due_date=Due")
+	assert.Nil(err)
+
+	source, err := importer.ReadCSV(strings.NewReader(file), mapping)
+	assert.Nil(err)
+	assert.Equal(2, len(source.Items))
+	assert.Equal("book travel", source.Items[0].Title)
+	assert.Equal([]string{"travel", "admin"}, source.Items[0].Labels)
+	assert.Equal("2022-02-01", db.FormatDueDate(source.Items[0].DueDate))
+	assert.Equal("before March", source.Items[1].Description)
+	assert.Nil(source.Items[1].DueDate)
+
+	_, err = importer.ReadCSV(strings.NewReader("Task,Notes,Tags,Due\nbook travel,,,soon\n"), mapping)
+	assert.ErrorIs(err, db.ErrInvalidDueDate)
+}
+
+func TestReadTodoTxt(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	file := "call mom @phone\n" +
+		"\n" +
+		"(B) 2022-01-03 plan launch +launch due:2022-02-01\n" +
+		"x 2022-01-05 2022-01-02 send invites +launch @email\n" +
+		"(A) fix the build +launch\n"
+
+	source, err := importer.ReadTodoTxt(strings.NewReader(file))
+	assert.Nil(err)
+	assert.Equal(4, len(source.Items))
+
+	call, plan, invites, build := source.Items[0], source.Items[1], source.Items[2], source.Items[3]
+	assert.Equal("call mom", call.Title)
+	assert.Equal([]string{"phone"}, call.Labels)
+	assert.Equal("", call.Status)
+	assert.Equal("plan launch", plan.Title)
+	assert.Equal("2022-02-01", db.FormatDueDate(plan.DueDate))
+	assert.Equal("send invites", invites.Title)
+	assert.Equal(db.StatusDone, invites.Status)
+	assert.Equal([]string{"launch", "email"}, invites.Labels)
+	assert.Less(build.Rank, plan.Rank)
+	assert.Less(plan.Rank, call.Rank)
+
+	database := getDB(assert)
+	defer database.Close()
+
+	_, err = importer.Import(context.Background(), database, source, false)
+	assert.Nil(err)
+
+	statuses := database.Snapshot().Statuses
+	assert.Equal([]string{"fix the build", "plan launch", "call mom"}, titles(statuses[db.StatusOpen].Todos))
+	assert.Equal([]string{"send invites"}, titles(statuses[db.StatusDone].Todos))
+
+	_, err = importer.ReadTodoTxt(strings.NewReader("(A) +launch\n"))
+	assert.ErrorIs(err, db.ErrEmptyTitle)
+}
+
+func TestImportSpillsIntoOpen(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	assert.Nil(database.SetMaxClosedTodos(ctx, 1))
+
+	source := &importer.Source{Items: []*importer.Item{
+		{Title: "first", Status: db.StatusClosed},
+		{Title: "second", Status: db.StatusClosed},
+		{Title: "third", Status: "someday"},
+	}}
+
+	report, err := importer.Import(ctx, database, source, false)
+	assert.Nil(err)
+	assert.Equal(2, len(report.Warnings))
+	assert.Equal([]string{"first"}, titles(database.Snapshot().Statuses[db.StatusClosed].Todos))
+	assert.Equal([]string{"second", "third"}, titles(database.Snapshot().Statuses[db.StatusOpen].Todos))
+}
+
+func TestImportDryRun(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	labels := len(database.Snapshot().Labels)
+
+	source, err := importer.ReadTodoTxt(strings.NewReader("x ship it +launch\nplan launch +launch\n"))
+	assert.Nil(err)
+
+	report, err := importer.Import(ctx, database, source, true)
+	assert.Nil(err)
+	assert.True(report.DryRun)
+	assert.Equal([]string{"launch"}, report.Labels)
+	assert.Equal(2, len(report.Todos))
+	assert.Equal(db.StatusDone, report.Todos[0].Status.Name)
+	assert.Nil(report.Todos[0].Todo)
+
+	assert.Empty(database.Snapshot().Todos)
+	assert.Equal(labels, len(database.Snapshot().Labels))
+}
+
+func TestImportChecksEverythingFirst(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	labels := len(database.Snapshot().Labels)
+
+	// the first items could be added, but nothing is added because of the last one
+	_, err := importer.Import(ctx, database, &importer.Source{Items: []*importer.Item{
+		{Title: "first", Labels: []string{"fresh"}},
+		{Title: "second", Checklist: []export.ChecklistItem{{Text: "outline"}, {Text: ""}}},
+	}}, false)
+	assert.ErrorIs(err, db.ErrEmptyChecklistItem)
+
+	_, err = importer.Import(ctx, database, &importer.Source{Items: []*importer.Item{
+		{Title: "first", ID: 1, BlockedBy: []int{3}},
+		{Title: "second", ID: 2, BlockedBy: []int{1, 7}},
+		{Title: "third", ID: 3, BlockedBy: []int{2}},
+	}}, false)
+	assert.ErrorIs(err, db.ErrDependencyCycle)
+
+	// the second item spills into open, which is full
+	assert.Nil(database.SetWIPLimit(ctx, database.Snapshot().Statuses[db.StatusOpen], 1))
+	assert.Nil(database.SetMaxClosedTodos(ctx, 1))
+
+	_, err = importer.Import(ctx, database, &importer.Source{Items: []*importer.Item{
+		{Title: "first", Status: db.StatusClosed},
+		{Title: "second", Status: db.StatusClosed},
+		{Title: "third"},
+	}}, false)
+	assert.ErrorIs(err, db.ErrWIPLimitReached)
+
+	assert.Empty(database.Snapshot().Todos)
+	assert.Equal(labels, len(database.Snapshot().Labels))
+	assert.ErrorIs(database.Undo(ctx), db.ErrNothingToUndo)
+}
+
+func TestImportSkipsBlockersOfDoneTodos(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	database := getDB(assert)
+	defer database.Close()
+
+	report, err := importer.Import(ctx, database, &importer.Source{Items: []*importer.Item{
+		{Title: "ship it", ID: 1, Status: db.StatusDone, BlockedBy: []int{2, 3}},
+		{Title: "write docs", ID: 2},
+		{Title: "drop the beta", ID: 3, Status: db.StatusAbandoned},
+	}}, false)
+	assert.Nil(err)
+	assert.Equal([]string{"'ship it' is done, so it no longer waits on 'write docs', which is open"}, report.Warnings)
+	assert.Equal([]string{"drop the beta"}, titles(report.Todos[0].Todo.BlockedBy))
+}
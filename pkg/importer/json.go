@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+)
+
+// ErrUnsupportedVersion is returned from ReadJSON when the document was written by a newer version of the app.
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// ReadJSON reads a Document written by the JSON export format.
+func ReadJSON(r io.Reader) (*Source, error) {
+	var doc export.Document
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error reading json: %w", err)
+	}
+
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > export.SchemaVersion {
+		return nil, fmt.Errorf("%w %d; this version of tt reads versions up to %d",
+			ErrUnsupportedVersion, doc.SchemaVersion, export.SchemaVersion)
+	}
+
+	source := &Source{Items: make([]*Item, 0, len(doc.Todos)), LabelColors: map[string]string{}}
+
+	for _, label := range doc.Labels {
+		source.LabelColors[label.Name] = label.Color
+	}
+
+	for _, todo := range doc.Todos {
+		due, err := db.ParseDueDate(todo.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("error reading todo #%d: %w", todo.ID, err)
+		}
+
+		recurrence, err := db.ParseRecurrence(todo.Recurrence)
+		if err != nil {
+			return nil, fmt.Errorf("error reading todo #%d: %w", todo.ID, err)
+		}
+
+		source.Items = append(source.Items, &Item{
+			Title:        todo.Title,
+			Description:  todo.Description,
+			Status:       todo.Status,
+			Rank:         todo.Rank,
+			Labels:       todo.Labels,
+			DueDate:      due,
+			Recurrence:   recurrence,
+			SnoozedUntil: todo.SnoozedUntil,
+			Checklist:    todo.Checklist,
+			ID:           todo.ID,
+			BlockedBy:    todo.BlockedBy,
+		})
+	}
+
+	return source, nil
+}
@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+)
+
+// noPriority is the rank of todo.txt tasks without a priority, which come after tasks with priorities (A) to (Z).
+const noPriority = 'Z' - 'A' + 1
+
+// ReadTodoTxt reads a file in the todo.txt format (see https://github.com/todotxt/todo.txt). Completed tasks, which
+// start with "x", go to done and the others to open. Priorities set the rank, so (A) comes first and tasks without a
+// priority come last. Projects and contexts like +launch and @phone become labels without the + or @, and due:
+// tags set the due date. Completion and creation dates are left out.
+func ReadTodoTxt(r io.Reader) (*Source, error) {
+	scanner := bufio.NewScanner(r)
+	source := &Source{Items: []*Item{}}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		item, err := todoTxtItem(text)
+		if err != nil {
+			return nil, fmt.Errorf("error reading todo.txt line %d: %w", line, err)
+		}
+
+		source.Items = append(source.Items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading todo.txt: %w", err)
+	}
+
+	return source, nil
+}
+
+func todoTxtItem(line string) (*Item, error) {
+	item := &Item{Rank: noPriority, Labels: []string{}}
+	fields := strings.Fields(line)
+
+	if fields[0] == "x" {
+		item.Status = db.StatusDone
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && isPriority(fields[0]) {
+		item.Rank = int(fields[0][1] - 'A')
+		fields = fields[1:]
+	}
+
+	// a completed task may have a completion date and a creation date
+	for i := 0; i < 2 && len(fields) > 0 && isDate(fields[0]); i++ {
+		fields = fields[1:]
+	}
+
+	words := []string{}
+
+	for _, field := range fields {
+		switch {
+		case len(field) > 1 && (field[0] == '+' || field[0] == '@'):
+			if !containsName(item.Labels, field[1:]) {
+				item.Labels = append(item.Labels, field[1:])
+			}
+		case strings.HasPrefix(field, "due:"):
+			due, err := db.ParseDueDate(strings.TrimPrefix(field, "due:"))
+			if err != nil {
+				return nil, err
+			}
+
+			item.DueDate = due
+		case strings.HasPrefix(field, "pri:") && isPriority("("+strings.TrimPrefix(field, "pri:")+")"):
+			// some apps keep the priority of completed tasks in a pri: tag
+			item.Rank = int(field[len(field)-1] - 'A')
+		default:
+			words = append(words, field)
+		}
+	}
+
+	item.Title = strings.Join(words, " ")
+	if item.Title == "" {
+		return nil, db.ErrEmptyTitle
+	}
+
+	return item, nil
+}
+
+// isPriority reports whether the field is a priority like (A).
+func isPriority(field string) bool {
+	return len(field) == 3 && field[0] == '(' && field[1] >= 'A' && field[1] <= 'Z' && field[2] == ')'
+}
+
+func isDate(field string) bool {
+	_, err := time.Parse(db.DueDateFormat, field)
+
+	return err == nil
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+)
+
+// Status is a status in the response from GET /statuses.
+type Status struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Color string `json:"color"`
+	// Limit is the maximum number of todos in the status, or 0 if there is no limit.
+	Limit int `json:"limit"`
+	Count int `json:"count"`
+}
+
+// StatusList is the response from GET /statuses.
+type StatusList struct {
+	Statuses []Status `json:"statuses"`
+}
+
+// TodoList is the response from GET /statuses/{status}/todos.
+type TodoList struct {
+	Status string        `json:"status"`
+	Todos  []export.Todo `json:"todos"`
+}
+
+// NewTodoRequest is the body of POST /todos.
+type NewTodoRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+}
+
+// UpdateTodoRequest is the body of PATCH /todos/{id}; fields that are left out keep their values.
+type UpdateTodoRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+// StatusRequest is the body of PUT /todos/{id}/status.
+type StatusRequest struct {
+	Status string `json:"status"`
+}
+
+// RankRequest is the body of PUT /todos/{id}/rank.
+type RankRequest struct {
+	// Position is top, bottom, up or down.
+	Position string `json:"position"`
+}
+
+func (s *Server) findTodo(ctx context.Context, idText string) (*db.Todo, error) {
+	id, err := strconv.Atoi(idText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid todo id '%s'", ErrBadRequest, idText)
+	}
+
+	return s.db.TodoByID(ctx, id)
+}
+
+// writeTodo responds with a Snapshot of the Todo with the given id, e.g. after changing it.
+func (s *Server) writeTodo(ctx context.Context, w http.ResponseWriter, code, id int) error {
+	todo, err := s.db.Snapshot().TodoByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, code, export.NewTodo(todo))
+
+	return nil
+}
+
+func (s *Server) listStatuses(w http.ResponseWriter, _ *http.Request, _ []string) error {
+	snapshot := s.db.Snapshot()
+	list := StatusList{Statuses: []Status{}}
+
+	for _, status := range snapshot.OrderedStatuses() {
+		list.Statuses = append(list.Statuses, Status{
+			Name:  status.Name,
+			Key:   status.Key,
+			Color: status.Color,
+			Limit: s.db.StatusLimit(status),
+			Count: len(status.Todos),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, list)
+
+	return nil
+}
+
+func (s *Server) listTodos(w http.ResponseWriter, _ *http.Request, params []string) error {
+	status, ok := s.db.Snapshot().Statuses[params[0]]
+	if !ok {
+		return fmt.Errorf("%w: no status named '%s'", ErrNotFound, params[0])
+	}
+
+	list := TodoList{Status: status.Name, Todos: make([]export.Todo, 0, len(status.Todos))}
+	for _, todo := range status.Todos {
+		list.Todos = append(list.Todos, export.NewTodo(todo))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+
+	return nil
+}
+
+func (s *Server) createTodo(w http.ResponseWriter, req *http.Request, _ []string) error {
+	var body NewTodoRequest
+	if err := readJSON(w, req, &body); err != nil {
+		return err
+	}
+
+	labels := make([]*db.Label, 0, len(body.Labels))
+
+	for _, name := range body.Labels {
+		label, err := s.db.LabelByName(req.Context(), name)
+		if err != nil {
+			return err
+		}
+
+		labels = append(labels, label)
+	}
+
+	// the todo and its labels are added together, so a label deleted in the meantime leaves nothing behind
+	todo, err := s.db.NewTodoWithLabels(req.Context(), body.Title, body.Description, labels)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/todos/%d", todo.ID()))
+
+	return s.writeTodo(req.Context(), w, http.StatusCreated, todo.ID())
+}
+
+func (s *Server) getTodo(w http.ResponseWriter, req *http.Request, params []string) error {
+	id, err := strconv.Atoi(params[0])
+	if err != nil {
+		return fmt.Errorf("%w: invalid todo id '%s'", ErrBadRequest, params[0])
+	}
+
+	return s.writeTodo(req.Context(), w, http.StatusOK, id)
+}
+
+func (s *Server) updateTodo(w http.ResponseWriter, req *http.Request, params []string) error {
+	todo, err := s.findTodo(req.Context(), params[0])
+	if err != nil {
+		return err
+	}
+
+	var body UpdateTodoRequest
+	if err = readJSON(w, req, &body); err != nil {
+		return err
+	}
+
+	if err = s.db.PatchTodo(req.Context(), todo, body.Title, body.Description); err != nil {
+		return err
+	}
+
+	return s.writeTodo(req.Context(), w, http.StatusOK, todo.ID())
+}
+
+func (s *Server) changeStatus(w http.ResponseWriter, req *http.Request, params []string) error {
+	todo, err := s.findTodo(req.Context(), params[0])
+	if err != nil {
+		return err
+	}
+
+	var body StatusRequest
+	if err = readJSON(w, req, &body); err != nil {
+		return err
+	}
+
+	status, err := s.db.StatusByName(req.Context(), body.Status)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadRequest, err)
+	}
+
+	if err = s.db.MoveToStatus(req.Context(), todo, status); err != nil {
+		return err
+	}
+
+	return s.writeTodo(req.Context(), w, http.StatusOK, todo.ID())
+}
+
+func (s *Server) rerank(w http.ResponseWriter, req *http.Request, params []string) error {
+	todo, err := s.findTodo(req.Context(), params[0])
+	if err != nil {
+		return err
+	}
+
+	var body RankRequest
+	if err = readJSON(w, req, &body); err != nil {
+		return err
+	}
+
+	moves := map[string]func(context.Context, *db.Todo) error{
+		"top":    s.db.MoveToTop,
+		"bottom": s.db.MoveToBottom,
+		"up":     s.db.MoveUp,
+		"down":   s.db.MoveDown,
+	}
+
+	moveFunc, ok := moves[body.Position]
+	if !ok {
+		return fmt.Errorf("%w: unknown position '%s'; use top, bottom, up or down", ErrBadRequest, body.Position)
+	}
+
+	if err = moveFunc(req.Context(), todo); err != nil {
+		return err
+	}
+
+	return s.writeTodo(req.Context(), w, http.StatusOK, todo.ID())
+}
+
+// addLabel adds a label to a todo; adding a label that the todo already has does nothing, as PUT should.
+func (s *Server) addLabel(w http.ResponseWriter, req *http.Request, params []string) error {
+	return s.changeLabel(w, req, params, s.db.AddTodoLabel)
+}
+
+func (s *Server) removeLabel(w http.ResponseWriter, req *http.Request, params []string) error {
+	return s.changeLabel(w, req, params, s.db.RemoveTodoLabel)
+}
+
+// changeLabel adds or removes the label named by the second parameter on the todo with the id in the first.
+func (s *Server) changeLabel(
+	w http.ResponseWriter, req *http.Request, params []string,
+	change func(context.Context, *db.Todo, *db.Label) error,
+) error {
+	todo, err := s.findTodo(req.Context(), params[0])
+	if err != nil {
+		return err
+	}
+
+	label, err := s.db.LabelByName(req.Context(), params[1])
+	if err != nil {
+		return err
+	}
+
+	if err = change(req.Context(), todo, label); err != nil {
+		return err
+	}
+
+	return s.writeTodo(req.Context(), w, http.StatusOK, todo.ID())
+}
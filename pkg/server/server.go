@@ -0,0 +1,267 @@
+// Package server exposes todos over a local HTTP API with JSON requests and responses, so that dashboards and editor
+// integrations can be built on top of them.
+//
+// The endpoints are:
+//
+//	GET    /statuses                   list the statuses in display order
+//	GET    /statuses/{status}/todos    list the todos with a status in rank order
+//	POST   /todos                      create a todo in the open list: {"title", "description", "labels"}
+//	GET    /todos/{id}                 get a todo
+//	PATCH  /todos/{id}                 change the title and/or description of a todo: {"title", "description"}
+//	PUT    /todos/{id}/status          move a todo to another status: {"status"}
+//	PUT    /todos/{id}/rank            move a todo within its status: {"position": "top|bottom|up|down"}
+//	PUT    /todos/{id}/labels/{label}  add a label to a todo
+//	DELETE /todos/{id}/labels/{label}  remove a label from a todo
+//
+// Todos are described by export.Todo. Errors are returned as {"error": "message"} with a 4xx status for invalid
+// requests and changes that aren't allowed, e.g. 409 Conflict when the closed list is full.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxBodyBytes limits the size of request bodies.
+	maxBodyBytes = 1 << 20
+	// readHeaderTimeout limits how long clients may take to send request headers.
+	readHeaderTimeout = 10 * time.Second
+	// shutdownTimeout limits how long ListenAndServe waits for requests in progress when its context is done.
+	shutdownTimeout = 5 * time.Second
+	// bearerPrefix starts the Authorization header of requests that carry a token.
+	bearerPrefix = "Bearer "
+)
+
+var (
+	// ErrUnauthorized is returned when a request doesn't carry the token that the Server requires.
+	ErrUnauthorized = errors.New("missing or invalid token")
+	// ErrBadRequest is returned when a request has an invalid id or body.
+	ErrBadRequest = errors.New("bad request")
+	// ErrNotFound is returned when no endpoint matches the path of a request, or no status has the requested name.
+	ErrNotFound = errors.New("not found")
+	// ErrMethodNotAllowed is returned when an endpoint doesn't support the method of a request.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// handlerFunc handles a request to a route, given the values of the wildcards in the route's pattern.
+type handlerFunc func(w http.ResponseWriter, req *http.Request, params []string) error
+
+// route maps a method and a path pattern like "todos/*/labels/*", where * matches any segment, to a handler.
+type route struct {
+	method  string
+	pattern []string
+	handle  handlerFunc
+}
+
+// match reports whether the route's pattern matches the path segments and returns the values of its wildcards.
+func (r *route) match(segments []string) ([]string, bool) {
+	if len(segments) != len(r.pattern) {
+		return nil, false
+	}
+
+	params := []string{}
+
+	for i, part := range r.pattern {
+		switch {
+		case part == "*":
+			params = append(params, segments[i])
+		case part != segments[i]:
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// Server is an http.Handler serving the API over a Database. Requests are handled concurrently: they read from
+// Snapshots, and change todos with methods that look them up and change them while holding the lock of the Database,
+// so that concurrent requests can't act on what another has just changed. Each request first reloads the Database if
+// another process, e.g. the UI, has changed it.
+type Server struct {
+	db     *db.Database
+	token  string
+	routes []*route
+}
+
+// New returns a Server for the database. If token isn't empty, every request must send it in an
+// "Authorization: Bearer <token>" header.
+func New(database *db.Database, token string) *Server {
+	s := &Server{db: database, token: token}
+
+	s.routes = []*route{
+		{http.MethodGet, []string{"statuses"}, s.listStatuses},
+		{http.MethodGet, []string{"statuses", "*", "todos"}, s.listTodos},
+		{http.MethodPost, []string{"todos"}, s.createTodo},
+		{http.MethodGet, []string{"todos", "*"}, s.getTodo},
+		{http.MethodPatch, []string{"todos", "*"}, s.updateTodo},
+		{http.MethodPut, []string{"todos", "*", "status"}, s.changeStatus},
+		{http.MethodPut, []string{"todos", "*", "rank"}, s.rerank},
+		{http.MethodPut, []string{"todos", "*", "labels", "*"}, s.addLabel},
+		{http.MethodDelete, []string{"todos", "*", "labels", "*"}, s.removeLabel},
+	}
+
+	return s
+}
+
+// ListenAndServe serves the API on the given address, e.g. "127.0.0.1:7070", until the context is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: readHeaderTimeout}
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("error serving on %s: %w", addr, err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down: %w", err)
+	}
+
+	return nil
+}
+
+// ServeHTTP dispatches the request to the route matching its method and path.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		writeError(w, ErrUnauthorized)
+
+		return
+	}
+
+	if _, err := s.db.ReloadIfChanged(req.Context()); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	r, params, err := s.findRoute(w, req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	if err = r.handle(w, req, params); err != nil {
+		writeError(w, err)
+	}
+}
+
+// findRoute returns the route for the request along with the values of its wildcards. If the path matches routes
+// for other methods only, it sets the Allow header and returns ErrMethodNotAllowed.
+func (s *Server) findRoute(w http.ResponseWriter, req *http.Request) (*route, []string, error) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	allowed := []string{}
+
+	for _, r := range s.routes {
+		params, ok := r.match(segments)
+		if !ok {
+			continue
+		}
+
+		if r.method == req.Method {
+			return r, params, nil
+		}
+
+		allowed = append(allowed, r.method)
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+		return nil, nil, fmt.Errorf("%w: %s %s", ErrMethodNotAllowed, req.Method, req.URL.Path)
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+}
+
+// authorized returns whether the request may go ahead: either the Server has no token, or the request has an
+// Authorization header with the "Bearer" scheme and the Server's token.
+func (s *Server) authorized(req *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+
+	// strings.CutPrefix would do, but it needs a newer Go than go.mod asks for
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return false
+	}
+
+	token := header[len(bearerPrefix):]
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// statusCode returns the HTTP status for an error: 4xx for errors caused by the request, 500 for anything else.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, ErrBadRequest), errors.Is(err, db.ErrEmptyTitle):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound), errors.Is(err, db.ErrTodoNotFound), errors.Is(err, db.ErrLabelNotFound),
+		errors.Is(err, db.ErrStatusNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrMaxClosedTodos), errors.Is(err, db.ErrWIPLimitReached),
+		errors.Is(err, db.ErrInvalidTodoMove), errors.Is(err, db.ErrInvalidTodoMoveNoStatusChange),
+		errors.Is(err, db.ErrCantMoveFirstTodoUp), errors.Is(err, db.ErrCantMoveLastTodoDown),
+		errors.Is(err, db.ErrBlocked):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := statusCode(err)
+	if code == http.StatusInternalServerError {
+		log.Error().Err(err).Msg("error handling api request")
+	}
+
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).Msg("error writing api response")
+	}
+}
+
+// readJSON decodes the body of the request into v, rejecting unknown fields so that typos don't go unnoticed.
+func readJSON(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid json: %s", ErrBadRequest, err)
+	}
+
+	return nil
+}
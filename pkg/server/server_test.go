@@ -0,0 +1,400 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matt-steen/todo-tracker/pkg/db"
+	"github.com/matt-steen/todo-tracker/pkg/export"
+	"github.com/matt-steen/todo-tracker/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// getServer returns a server without a token over a new database with two open todos, "first" (#1) and "second"
+// (#2).
+func getServer(assert *assert.Assertions) (*server.Server, *db.Database) {
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_server*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	_, err = database.NewTodo(ctx, "first", "")
+	assert.Nil(err)
+
+	_, err = database.NewTodo(ctx, "second", "")
+	assert.Nil(err)
+
+	return server.New(database, ""), database
+}
+
+// do sends a request with an optional JSON body to the handler and returns the response.
+func do(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+// decodeTodo decodes a response describing a todo.
+func decodeTodo(assert *assert.Assertions, recorder *httptest.ResponseRecorder) export.Todo {
+	var todo export.Todo
+
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &todo), recorder.Body.String())
+
+	return todo
+}
+
+// assertError checks the status code of a response and that its body describes an error.
+func assertError(assert *assert.Assertions, recorder *httptest.ResponseRecorder, code int, message string) {
+	assert.Equal(code, recorder.Code, recorder.Body.String())
+
+	var body map[string]string
+
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Contains(body["error"], message)
+}
+
+func TestListStatuses(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodGet, "/statuses", "")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("application/json", recorder.Header().Get("Content-Type"))
+
+	var list server.StatusList
+
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &list))
+	assert.Equal(len(database.Snapshot().Statuses), len(list.Statuses))
+	assert.Equal(db.StatusOpen, list.Statuses[0].Name)
+	assert.Equal(2, list.Statuses[0].Count)
+	assert.Equal(db.StatusClosed, list.Statuses[1].Name)
+	assert.Equal(database.StatusLimit(database.Snapshot().Statuses[db.StatusClosed]), list.Statuses[1].Limit)
+}
+
+func TestListTodos(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodGet, "/statuses/open/todos", "")
+	assert.Equal(http.StatusOK, recorder.Code)
+
+	var list server.TodoList
+
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &list))
+	assert.Equal(db.StatusOpen, list.Status)
+	assert.Equal(2, len(list.Todos))
+	assert.Equal("first", list.Todos[0].Title)
+	assert.Equal(1, list.Todos[1].Rank)
+
+	recorder = do(srv, http.MethodGet, "/statuses/closed/todos", "")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Contains(recorder.Body.String(), `"todos":[]`)
+
+	assertError(assert, do(srv, http.MethodGet, "/statuses/someday/todos", ""), http.StatusNotFound, "someday")
+}
+
+func TestCreateTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodPost, "/todos",
+		`{"title": "write report", "description": "for the board", "labels": ["onboarding"]}`)
+	assert.Equal(http.StatusCreated, recorder.Code, recorder.Body.String())
+
+	todo := decodeTodo(assert, recorder)
+	assert.Equal("write report", todo.Title)
+	assert.Equal("for the board", todo.Description)
+	assert.Equal(db.StatusOpen, todo.Status)
+	assert.Equal(2, todo.Rank)
+	assert.Equal([]string{"onboarding"}, todo.Labels)
+	assert.Equal("/todos/3", recorder.Header().Get("Location"))
+	assert.Equal(3, len(database.Snapshot().Todos))
+
+	assertError(assert, do(srv, http.MethodPost, "/todos", `{"title": ""}`), http.StatusBadRequest, "empty")
+	assertError(assert, do(srv, http.MethodPost, "/todos", `{"title": "x", "labels": ["nope"]}`),
+		http.StatusNotFound, "nope")
+	recorder = do(srv, http.MethodPost, "/todos", `{"title": "plan offsite", "labels": ["urgent", "task", "urgent"]}`)
+	assert.Equal(http.StatusCreated, recorder.Code, recorder.Body.String())
+	assert.Equal([]string{"urgent", "task"}, decodeTodo(assert, recorder).Labels)
+	assert.Equal(4, len(database.Snapshot().Todos))
+
+	assertError(assert, do(srv, http.MethodPost, "/todos", `{"name": "x"}`), http.StatusBadRequest, "invalid json")
+	assertError(assert, do(srv, http.MethodPost, "/todos", `{`), http.StatusBadRequest, "invalid json")
+	assert.Equal(4, len(database.Snapshot().Todos))
+}
+
+func TestGetTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodGet, "/todos/2", "")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("second", decodeTodo(assert, recorder).Title)
+
+	assertError(assert, do(srv, http.MethodGet, "/todos/42", ""), http.StatusNotFound, "no todo found")
+	assertError(assert, do(srv, http.MethodGet, "/todos/two", ""), http.StatusBadRequest, "invalid todo id")
+}
+
+func TestUpdateTodo(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodPatch, "/todos/1", `{"description": "details"}`)
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+
+	todo := decodeTodo(assert, recorder)
+	assert.Equal("first", todo.Title)
+	assert.Equal("details", todo.Description)
+
+	recorder = do(srv, http.MethodPatch, "/todos/1", `{"title": "renamed"}`)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("renamed", decodeTodo(assert, recorder).Title)
+	assert.Equal("details", database.Snapshot().Statuses[db.StatusOpen].Todos[0].Description)
+
+	assertError(assert, do(srv, http.MethodPatch, "/todos/1", `{"title": ""}`), http.StatusBadRequest, "empty")
+	assertError(assert, do(srv, http.MethodPatch, "/todos/42", `{"title": "x"}`), http.StatusNotFound, "no todo")
+}
+
+func TestChangeStatus(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodPut, "/todos/1/status", `{"status": "closed"}`)
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(db.StatusClosed, decodeTodo(assert, recorder).Status)
+	assert.Equal("first", database.Snapshot().Statuses[db.StatusClosed].Todos[0].Title)
+
+	// open todos have to be closed before they can be done
+	assertError(assert, do(srv, http.MethodPut, "/todos/2/status", `{"status": "done"}`),
+		http.StatusConflict, "cannot move")
+	assertError(assert, do(srv, http.MethodPut, "/todos/1/status", `{"status": "closed"}`),
+		http.StatusConflict, "cannot move")
+
+	assert.Nil(database.SetMaxClosedTodos(context.Background(), 1))
+	assertError(assert, do(srv, http.MethodPut, "/todos/2/status", `{"status": "closed"}`),
+		http.StatusConflict, "the closed list is full")
+
+	assertError(assert, do(srv, http.MethodPut, "/todos/2/status", `{"status": "someday"}`),
+		http.StatusBadRequest, "someday")
+}
+
+func TestRerank(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodPut, "/todos/2/rank", `{"position": "top"}`)
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(0, decodeTodo(assert, recorder).Rank)
+	assert.Equal("second", database.Snapshot().Statuses[db.StatusOpen].Todos[0].Title)
+
+	recorder = do(srv, http.MethodPut, "/todos/2/rank", `{"position": "down"}`)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal(1, decodeTodo(assert, recorder).Rank)
+
+	assertError(assert, do(srv, http.MethodPut, "/todos/2/rank", `{"position": "down"}`),
+		http.StatusConflict, "cannot move down")
+	assertError(assert, do(srv, http.MethodPut, "/todos/2/rank", `{"position": "sideways"}`),
+		http.StatusBadRequest, "sideways")
+}
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	recorder := do(srv, http.MethodPut, "/todos/1/labels/onboarding", "")
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal([]string{"onboarding"}, decodeTodo(assert, recorder).Labels)
+
+	// adding a label twice does nothing
+	recorder = do(srv, http.MethodPut, "/todos/1/labels/onboarding", "")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal([]string{"onboarding"}, decodeTodo(assert, recorder).Labels)
+
+	recorder = do(srv, http.MethodDelete, "/todos/1/labels/onboarding", "")
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Empty(decodeTodo(assert, recorder).Labels)
+
+	assertError(assert, do(srv, http.MethodPut, "/todos/1/labels/nope", ""), http.StatusNotFound, "no label")
+	assertError(assert, do(srv, http.MethodDelete, "/todos/42/labels/onboarding", ""), http.StatusNotFound, "no todo")
+}
+
+func TestReloadsChanges(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tempFile, err := os.CreateTemp("/tmp", "test_server*")
+	assert.Nil(err)
+
+	database, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer database.Close()
+
+	srv := server.New(database, "")
+
+	// e.g. the UI adds a todo while the server is running
+	other, err := db.NewDatabase(ctx, tempFile.Name())
+	assert.Nil(err)
+
+	defer other.Close()
+
+	_, err = other.NewTodo(ctx, "from the ui", "")
+	assert.Nil(err)
+
+	recorder := do(srv, http.MethodPut, "/todos/1/status", `{"status": "closed"}`)
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal("from the ui", decodeTodo(assert, recorder).Title)
+}
+
+// doConcurrently sends the requests at the same time and returns the status codes of the responses in order.
+func doConcurrently(handler http.Handler, method string, paths, bodies []string) []int {
+	codes := make([]int, len(paths))
+
+	var wg sync.WaitGroup
+
+	for i := range paths {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			codes[i] = do(handler, method, paths[i], bodies[i]).Code
+		}(i)
+	}
+
+	wg.Wait()
+
+	return codes
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	// adding the same label from many requests at once adds it once
+	paths := []string{}
+	for i := 0; i < 10; i++ {
+		paths = append(paths, "/todos/1/labels/onboarding")
+	}
+
+	for _, code := range doConcurrently(srv, http.MethodPut, paths, make([]string, len(paths))) {
+		assert.Equal(http.StatusOK, code)
+	}
+
+	assert.Equal([]string{"onboarding"}, decodeTodo(assert, do(srv, http.MethodGet, "/todos/1", "")).Labels)
+
+	// patches of different fields don't undo each other
+	codes := doConcurrently(srv, http.MethodPatch, []string{"/todos/1", "/todos/1"},
+		[]string{`{"title": "renamed"}`, `{"description": "details"}`})
+	assert.Equal([]int{http.StatusOK, http.StatusOK}, codes)
+
+	todo := decodeTodo(assert, do(srv, http.MethodGet, "/todos/1", ""))
+	assert.Equal("renamed", todo.Title)
+	assert.Equal("details", todo.Description)
+
+	// only one of the requests can move the todo; the others find it already closed
+	codes = doConcurrently(srv, http.MethodPut, []string{"/todos/2/status", "/todos/2/status", "/todos/2/status"},
+		[]string{`{"status": "closed"}`, `{"status": "closed"}`, `{"status": "closed"}`})
+	assert.ElementsMatch([]int{http.StatusOK, http.StatusConflict, http.StatusConflict}, codes)
+	assert.Equal(db.StatusClosed, decodeTodo(assert, do(srv, http.MethodGet, "/todos/2", "")).Status)
+}
+
+func TestRouting(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	srv, database := getServer(assert)
+	defer database.Close()
+
+	assertError(assert, do(srv, http.MethodGet, "/nothing/here", ""), http.StatusNotFound, "not found")
+
+	recorder := do(srv, http.MethodDelete, "/todos/1", "")
+	assertError(assert, recorder, http.StatusMethodNotAllowed, "method not allowed")
+	assert.Equal("GET, PATCH", recorder.Header().Get("Allow"))
+}
+
+func TestToken(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	_, database := getServer(assert)
+	defer database.Close()
+
+	ts := httptest.NewServer(server.New(database, "s3cret"))
+	defer ts.Close()
+
+	get := func(authorization ...string) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL+"/todos/1", nil)
+		assert.Nil(err)
+
+		for _, value := range authorization {
+			req.Header.Add("Authorization", value)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	assert.Equal(http.StatusUnauthorized, get().StatusCode)
+	assert.Equal(http.StatusUnauthorized, get("").StatusCode)
+	assert.Equal(http.StatusUnauthorized, get("Bearer ").StatusCode)
+	assert.Equal(http.StatusUnauthorized, get("Bearer guess").StatusCode)
+	assert.Equal(http.StatusUnauthorized, get("s3cret").StatusCode)
+	assert.Equal(http.StatusUnauthorized, get("Basic s3cret").StatusCode)
+	assert.Equal(http.StatusOK, get("Bearer s3cret").StatusCode)
+}